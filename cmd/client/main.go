@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/audit"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/oauth"
+	"github.com/kurochkinivan/auth/internal/usecase/repository/pg"
+	"github.com/kurochkinivan/auth/internal/usecase/tenant"
+	pgclient "github.com/kurochkinivan/pgClient"
+)
+
+// Registers OAuth client and prints its credentials.
+// The secret is shown only once, it is stored hashed.
+// The client signs in users of the tenant it's registered in.
+func main() {
+	var name, tenantSlug, redirectURIs, scopes, grantTypes string
+	var public bool
+
+	flag.StringVar(&name, "name", "", "client name")
+	flag.StringVar(&tenantSlug, "tenant", "", "slug of the tenant of the client, the default tenant if empty")
+	flag.StringVar(&redirectURIs, "redirect_uris", "", "comma-separated redirect uris")
+	flag.StringVar(&scopes, "scopes", "", "comma-separated allowed scopes")
+	flag.StringVar(&grantTypes, "grant_types", "authorization_code,refresh_token", "comma-separated grant types")
+	flag.BoolVar(&public, "public", false, "public client without secret (mobile and single-page apps)")
+
+	cfg := config.MustLoad()
+
+	if name == "" {
+		panic("name is required")
+	}
+
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pool, err := pgclient.NewClient(ctx, cfg.PostgreSQL.Username, cfg.PostgreSQL.Password, cfg.PostgreSQL.Host, cfg.PostgreSQL.Port, cfg.PostgreSQL.DB)
+	if err != nil {
+		panic(err)
+	}
+	defer pool.Close()
+
+	repository := pg.New(pool)
+
+	if tenantSlug != "" {
+		tenantID, err := tenant.New(log, repository).Resolve(ctx, tenantSlug)
+		if err != nil {
+			panic(err)
+		}
+		ctx = tenancy.WithTenant(ctx, tenantID)
+	}
+
+	authService := auth.New(log, repository, repository, nil, audit.New(log, repository), nil, nil, nil, cfg.Secret, cfg.TokenTTL)
+	// registration doesn't sign ID tokens, so no signing key is needed
+	oauthService := oauth.New(log, authService, repository, repository, repository, cfg.Secret, cfg.OIDC.Issuer, nil, cfg.TokenTTL, cfg.OAuth.CodeTTL, cfg.OAuth.RefreshTokenTTL)
+
+	clientID, secret, err := oauthService.RegisterClient(ctx, name, split(redirectURIs), split(scopes), split(grantTypes), public)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("client_id:", clientID)
+	if secret != "" {
+		fmt.Println("client_secret:", secret)
+	}
+}
+
+func split(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(s, ",")
+}
@@ -3,43 +3,372 @@ package app
 import (
 	"context"
 	"log/slog"
+	"net"
 	"time"
 
 	grpcapp "github.com/kurochkinivan/auth/internal/app/grpc"
+	healthapp "github.com/kurochkinivan/auth/internal/app/health"
+	httpapp "github.com/kurochkinivan/auth/internal/app/http"
+	metricsapp "github.com/kurochkinivan/auth/internal/app/metrics"
+	outboxapp "github.com/kurochkinivan/auth/internal/app/outbox"
 	pgapp "github.com/kurochkinivan/auth/internal/app/pg"
+	webhookapp "github.com/kurochkinivan/auth/internal/app/webhook"
 	"github.com/kurochkinivan/auth/internal/config"
+	federationhttp "github.com/kurochkinivan/auth/internal/controller/http/federation"
+	"github.com/kurochkinivan/auth/internal/lib/geoip"
+	"github.com/kurochkinivan/auth/internal/lib/healthcheck"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/ldap"
+	"github.com/kurochkinivan/auth/internal/lib/mail"
+	"github.com/kurochkinivan/auth/internal/lib/oidcclient"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/sms"
+	"github.com/kurochkinivan/auth/internal/lib/tlsutil"
+	"github.com/kurochkinivan/auth/internal/lib/tracing"
+	"github.com/kurochkinivan/auth/internal/usecase/apikey"
+	"github.com/kurochkinivan/auth/internal/usecase/audit"
 	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/federation"
+	"github.com/kurochkinivan/auth/internal/usecase/impersonation"
+	"github.com/kurochkinivan/auth/internal/usecase/invitation"
+	"github.com/kurochkinivan/auth/internal/usecase/loginhistory"
+	"github.com/kurochkinivan/auth/internal/usecase/oauth"
+	"github.com/kurochkinivan/auth/internal/usecase/otp"
 	"github.com/kurochkinivan/auth/internal/usecase/repository/pg"
+	"github.com/kurochkinivan/auth/internal/usecase/risk"
+	"github.com/kurochkinivan/auth/internal/usecase/serviceaccount"
+	"github.com/kurochkinivan/auth/internal/usecase/tenant"
+	"github.com/kurochkinivan/auth/internal/usecase/webhook"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+)
+
+const (
+	serviceName           = "auth"
+	tracerShutdownTimeout = 5 * time.Second
 )
 
 type App struct {
 	log           *slog.Logger
 	GRPCApp       *grpcapp.App
+	HTTPApp       *httpapp.App
+	HealthApp     *healthapp.App
+	MetricsApp    *metricsapp.App
+	OutboxApp     *outboxapp.App
+	WebhookApp    *webhookapp.App
 	PostgreSQLApp *pgapp.App
+	geoIP         *geoip.DB
+	tracer        *sdktrace.TracerProvider
+	gatewayConn   *grpc.ClientConn
 }
 
 func New(ctx context.Context, log *slog.Logger, cfg *config.Config) *App {
+	tracer := mustSetupTracing(ctx, log, cfg.Tracing)
+
 	pgApp := pgapp.New(ctx, log, cfg.PostgreSQL)
 
 	repository := pg.New(pgApp.Pool)
 
-	authService := auth.New(log, repository, repository, cfg.Secret, cfg.TokenTTL)
+	mailSender := mail.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+
+	auditLog := audit.New(log, repository)
+
+	loginHistoryService := loginhistory.New(log, repository, mustLoadNotifier(log, cfg.LoginHistory.Notifier, mailSender))
+
+	geoIP := mustLoadGeoIP(log, cfg.Risk)
+
+	authService := auth.New(log, repository, repository, mustLoadDirectory(log, cfg.LDAP), auditLog, loginHistoryService, riskEngine(log, cfg.Risk, repository, geoIP, auditLog), hardening(cfg.Hardening, mailSender), cfg.Secret, cfg.TokenTTL)
+	otpChannels := map[string]otp.Channel{
+		otp.ChannelEmail: otp.NewEmailChannel(mailSender),
+		otp.ChannelSMS:   otp.NewSMSChannel(sms.NewFileProvider(cfg.OTP.SMSFile)),
+	}
+	otpService := otp.New(log, repository, repository, otpChannels, auditLog, authService, cfg.OTP.CodeTTL, cfg.OTP.MaxAttempts)
+
+	signingKey := mustLoadSigningKey(log, cfg.OIDC.SigningKeyPath)
+	oauthService := oauth.New(log, authService, repository, repository, repository, cfg.Secret, cfg.OIDC.Issuer, signingKey, cfg.TokenTTL, cfg.OAuth.CodeTTL, cfg.OAuth.RefreshTokenTTL)
+
+	tenantService := tenant.New(log, repository)
+
+	invitationService := invitation.New(log, authService, repository, repository, repository, mailSender, cfg.Secret, cfg.Invitation.AcceptURL, cfg.Invitation.TTL)
 
-	gRPCApp := grpcapp.New(log, cfg.GRPC, authService)
+	apiKeyService := apikey.New(log, repository, repository, auditLog, cfg.Secret, cfg.TokenTTL)
+
+	serviceAccountService := serviceaccount.New(log, repository, cfg.Secret, cfg.OIDC.Issuer, cfg.ServiceAccount.TokenTTL)
+
+	impersonationService := impersonation.New(log, repository, auditLog, cfg.Secret, cfg.Impersonation.TokenTTL)
+
+	webhookService := webhook.New(log, repository)
+
+	healthServer := health.NewServer()
+
+	gRPCApp := grpcapp.New(
+		log,
+		cfg.GRPC,
+		cfg.PoW,
+		authService,
+		otpService,
+		tenantService,
+		invitationService,
+		apiKeyService,
+		loginHistoryService,
+		serviceAccountService,
+		impersonationService,
+		auditLog,
+		webhookService,
+		healthServer,
+		cfg.Secret,
+	)
+
+	// the outbox broker and the directory are left out, auth keeps working without them
+	healthApp := healthapp.New(log, cfg.Health, healthServer, gRPCApp.Services(), map[string]healthcheck.Check{
+		"postgresql": pgApp.Pool.Ping,
+	})
+
+	federationService := federation.New(log, repository, repository, authService, otpService, cfg.Secret, cfg.TokenTTL)
+	identityProviders := make([]federationhttp.Provider, 0, len(cfg.Federation.Providers))
+	for _, p := range cfg.Federation.Providers {
+		identityProviders = append(identityProviders, oidcclient.New(oidcclient.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}))
+	}
+
+	gatewayConn := mustDialGateway(cfg.Gateway, cfg.GRPC)
+
+	var gatewayClient authv1.AuthClient
+	if gatewayConn != nil {
+		gatewayClient = authv1.NewAuthClient(gatewayConn)
+	}
+
+	httpApp := httpapp.New(log, cfg.HTTP, cfg.Secret, oauthService, federationService, identityProviders, tenantService, cfg.Gateway, gatewayClient)
+
+	metricsApp := metricsapp.New(log, cfg.Metrics, pgApp.Pool)
+
+	outboxApp := outboxapp.New(log, cfg.Outbox, repository, webhookService)
+	webhookApp := webhookapp.New(log, cfg.Webhook, repository)
 
 	return &App{
 		GRPCApp:       gRPCApp,
+		HTTPApp:       httpApp,
+		HealthApp:     healthApp,
+		MetricsApp:    metricsApp,
+		OutboxApp:     outboxApp,
+		WebhookApp:    webhookApp,
 		PostgreSQLApp: pgApp,
+		geoIP:         geoIP,
+		tracer:        tracer,
+		gatewayConn:   gatewayConn,
 		log:           log,
 	}
 }
 
 func (a *App) Run(ctx context.Context) {
 	go a.PostgreSQLApp.MustRun(ctx, 5, 5*time.Second)
+	a.HealthApp.Run(ctx)
 	go a.GRPCApp.MustRun()
+	go a.HTTPApp.MustRun()
+	go a.MetricsApp.MustRun()
+	a.OutboxApp.Run(ctx)
+	a.WebhookApp.Run(ctx)
 }
 
 func (a *App) Stop() {
+	a.HealthApp.Stop()
+	a.HTTPApp.Stop()
+	if a.gatewayConn != nil {
+		if err := a.gatewayConn.Close(); err != nil {
+			a.log.Error("failed to close gateway connection", sl.Err(err))
+		}
+	}
 	a.GRPCApp.Stop()
+	a.MetricsApp.Stop()
+	a.OutboxApp.Stop()
+	a.WebhookApp.Stop()
 	a.PostgreSQLApp.Stop()
+
+	if a.geoIP != nil {
+		if err := a.geoIP.Close(); err != nil {
+			a.log.Error("failed to close geoip databases", sl.Err(err))
+		}
+	}
+
+	if a.tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+		defer cancel()
+
+		if err := a.tracer.Shutdown(ctx); err != nil {
+			a.log.Error("failed to flush spans", sl.Err(err))
+		}
+	}
+}
+
+func mustLoadSigningKey(log *slog.Logger, path string) *jwk.Key {
+	if path == "" {
+		log.Warn("signing key path is empty, generating ephemeral key")
+
+		key, err := jwk.Generate()
+		if err != nil {
+			panic(err)
+		}
+
+		return key
+	}
+
+	key, err := jwk.Load(path)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// mustLoadDirectory returns nil interface if the directory is disabled,
+// so auth service doesn't receive typed nil.
+func mustLoadDirectory(log *slog.Logger, cfg config.LDAPConfig) auth.Directory {
+	if cfg.URL == "" {
+		return nil
+	}
+
+	directory, err := ldap.New(ldap.Config{
+		URL:                cfg.URL,
+		StartTLS:           cfg.StartTLS,
+		CACertPath:         cfg.CACertPath,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		BindDN:             cfg.BindDN,
+		BindPassword:       cfg.BindPassword,
+		BaseDN:             cfg.BaseDN,
+		UserFilter:         cfg.UserFilter,
+		EmailAttribute:     cfg.EmailAttribute,
+		GroupAttribute:     cfg.GroupAttribute,
+		GroupRoles:         cfg.GroupRoles,
+		Timeout:            cfg.Timeout,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info("ldap directory enabled", slog.String("url", cfg.URL))
+
+	return directory
+}
+
+// mustSetupTracing returns nil if export of spans is off.
+func mustSetupTracing(ctx context.Context, log *slog.Logger, cfg config.TracingConfig) *sdktrace.TracerProvider {
+	provider, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.Exporter,
+		ServiceName: serviceName,
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+		SampleRatio: cfg.SampleRatio,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if provider != nil {
+		log.Info("tracing enabled", slog.String("exporter", cfg.Exporter))
+	}
+
+	return provider
+}
+
+// mustDialGateway returns connection of the HTTP gateway to the gRPC server,
+// or nil if the gateway is disabled.
+func mustDialGateway(cfg config.GatewayConfig, grpcCfg config.GRPCConfig) *grpc.ClientConn {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	creds := insecure.NewCredentials()
+	if grpcCfg.TLS.Enabled {
+		tlsConfig, err := tlsutil.ClientConfig(cfg.TLS.CAPath, cfg.TLS.CertPath, cfg.TLS.KeyPath, cfg.TLS.ServerName)
+		if err != nil {
+			panic(err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(
+		net.JoinHostPort("localhost", grpcCfg.Port),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return conn
+}
+
+// mustLoadGeoIP returns nil if risk scoring is disabled or no GeoIP database is given.
+func mustLoadGeoIP(log *slog.Logger, cfg config.RiskConfig) *geoip.DB {
+	if !cfg.Enabled || (cfg.GeoIPCityPath == "" && cfg.GeoIPASNPath == "") {
+		return nil
+	}
+
+	db, err := geoip.Open(cfg.GeoIPCityPath, cfg.GeoIPASNPath)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info("geoip databases loaded",
+		slog.String("city", cfg.GeoIPCityPath),
+		slog.String("asn", cfg.GeoIPASNPath),
+	)
+
+	return db
+}
+
+// riskEngine returns nil interface if risk scoring is disabled,
+// so auth service doesn't receive typed nil.
+func riskEngine(log *slog.Logger, cfg config.RiskConfig, storage risk.Storage, geoIP *geoip.DB, auditLog risk.AuditLog) auth.Risk {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var geo risk.Geo
+	if geoIP != nil {
+		geo = geoIP
+	}
+
+	return risk.New(log, storage, geo, auditLog, risk.Policy{
+		VelocityWindow: cfg.VelocityWindow,
+		MaxTravelSpeed: cfg.MaxTravelSpeed,
+		MFAScore:       cfg.MFAScore,
+		DenyScore:      cfg.DenyScore,
+	})
+}
+
+// hardening returns nil if hardening is disabled.
+func hardening(cfg config.HardeningConfig, mailSender auth.MailSender) *auth.Hardening {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	return &auth.Hardening{
+		MinDuration: cfg.MinResponseTime,
+		MailSender:  mailSender,
+	}
+}
+
+func mustLoadNotifier(log *slog.Logger, name string, mailSender loginhistory.MailSender) loginhistory.Notifier {
+	switch name {
+	case loginhistory.NotifierEmail:
+		return loginhistory.NewEmailNotifier(mailSender)
+	case loginhistory.NotifierLog:
+		return loginhistory.NewLogNotifier(log)
+	case loginhistory.NotifierNone:
+		return loginhistory.NopNotifier{}
+	default:
+		panic("unknown login notifier: " + name)
+	}
 }
@@ -0,0 +1,45 @@
+package grpcapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// accessLogInterceptor writes a line per call with method, status code, latency and peer.
+// Server errors are logged at error level, the rest at info level.
+func accessLogInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			addr = p.Addr.String()
+		}
+
+		level := slog.LevelInfo
+		switch code {
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+			level = slog.LevelError
+		}
+
+		sl.FromContext(ctx, log).LogAttrs(ctx, level, "grpc call",
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("peer", addr),
+		)
+
+		return resp, err
+	}
+}
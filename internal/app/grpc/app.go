@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/kurochkinivan/auth/internal/config"
+	admingrpc "github.com/kurochkinivan/auth/internal/controller/grpc/admin"
 	authgrpc "github.com/kurochkinivan/auth/internal/controller/grpc/auth"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type App struct {
@@ -19,12 +23,74 @@ type App struct {
 	timeout    time.Duration
 }
 
-func New(log *slog.Logger, cfg config.GRPCConfig, auth authgrpc.Auth) *App {
-	gRPCServer := grpc.NewServer(grpc.ConnectionTimeout(cfg.Timeout))
+type Tenants interface {
+	admingrpc.Tenants
+	TenantResolver
+}
+
+type Invitations interface {
+	admingrpc.Invitations
+	authgrpc.Invitations
+}
+
+type ServiceAccounts interface {
+	admingrpc.ServiceAccounts
+	authgrpc.ServiceAccounts
+}
+
+func New(
+	log *slog.Logger,
+	cfg config.GRPCConfig,
+	powCfg config.PoWConfig,
+	auth authgrpc.Auth,
+	otp authgrpc.OTP,
+	tenants Tenants,
+	invitations Invitations,
+	apiKeys authgrpc.APIKeys,
+	loginHistory authgrpc.LoginHistory,
+	serviceAccounts ServiceAccounts,
+	impersonation admingrpc.Impersonation,
+	auditLog admingrpc.AuditLog,
+	webhooks admingrpc.Webhooks,
+	health healthpb.HealthServer,
+	secret string,
+) *App {
+	interceptors := []grpc.UnaryServerInterceptor{
+		requestIDInterceptor(log),
+		metricsInterceptor(),
+		accessLogInterceptor(log),
+		recoveryInterceptor(log),
+		clientInfoInterceptor(),
+	}
+	// proof-of-work guards unary methods only, streams go through the rest of the chain
+	stream := streamInterceptors(append(slices.Clone(interceptors), tenantInterceptor(tenants))...)
+	if powCfg.Enabled {
+		interceptors = append(interceptors, powInterceptor(powCfg, secret))
+	}
+	interceptors = append(interceptors, tenantInterceptor(tenants))
+
+	opts := []grpc.ServerOption{
+		grpc.ConnectionTimeout(cfg.Timeout),
+		// server spans continue traces of callers passed in metadata
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if cfg.TLS.Enabled {
+		creds, err := transportCredentials(log, cfg.TLS)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	gRPCServer := grpc.NewServer(opts...)
 
 	validate := validator.New(validator.WithRequiredStructEnabled())
 
-	authgrpc.Register(gRPCServer, validate, auth)
+	authgrpc.Register(gRPCServer, validate, auth, otp, invitations, apiKeys, loginHistory, serviceAccounts)
+	admingrpc.Register(gRPCServer, validate, secret, tenants, invitations, serviceAccounts, impersonation, auditLog, webhooks)
+	healthpb.RegisterHealthServer(gRPCServer, health)
 
 	return &App{
 		log:        log,
@@ -34,6 +100,19 @@ func New(log *slog.Logger, cfg config.GRPCConfig, auth authgrpc.Auth) *App {
 	}
 }
 
+// Services returns names of the registered services.
+func (a *App) Services() []string {
+	info := a.gRPCServer.GetServiceInfo()
+
+	services := make([]string, 0, len(info))
+	for name := range info {
+		services = append(services, name)
+	}
+	slices.Sort(services)
+
+	return services
+}
+
 func (a *App) MustRun() {
 	if err := a.Run(); err != nil {
 		panic(err)
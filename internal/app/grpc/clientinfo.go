@@ -0,0 +1,52 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// clientInfoInterceptor adds address and user agent of the client to request context.
+// Requests from loopback addresses, e.g. of the HTTP gateway, may pass the client they are
+// forwarded for in x-forwarded-for and x-forwarded-user-agent metadata.
+// Identity of the client is taken from its verified TLS certificate.
+func clientInfoInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var client clientinfo.Info
+
+		if p, ok := peer.FromContext(ctx); ok {
+			if p.Addr != nil {
+				client.IP = p.Addr.String()
+				if host, _, err := net.SplitHostPort(client.IP); err == nil {
+					client.IP = host
+				}
+			}
+
+			// only certificates verified against the client CA identify the client
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+				client.Identity = clientinfo.IdentityOf(tlsInfo.State.VerifiedChains[0][0])
+			}
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("user-agent"); len(values) > 0 {
+			client.UserAgent = values[0]
+		}
+
+		if ip := net.ParseIP(client.IP); ip != nil && ip.IsLoopback() {
+			if forwarded := first(md.Get(clientinfo.ForwardedForKey)); forwarded != "" {
+				client.IP = forwarded
+				client.UserAgent = first(md.Get(clientinfo.ForwardedUserAgentKey))
+				// certificate of the proxy doesn't identify the client
+				client.Identity = nil
+			}
+		}
+
+		return handler(clientinfo.WithInfo(ctx, client), req)
+	}
+}
@@ -0,0 +1,206 @@
+package grpcapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/requestid"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// headerStream captures headers set by interceptors.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// call passes the request through request id, access log and recovery interceptors
+// and returns headers and lines logged.
+func call(t *testing.T, md metadata.MD, handler grpc.UnaryHandler) (metadata.MD, []map[string]any, error) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	stream := new(headerStream)
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	info := &grpc.UnaryServerInfo{FullMethod: registerMethod}
+	interceptors := []grpc.UnaryServerInterceptor{
+		requestIDInterceptor(log),
+		accessLogInterceptor(log),
+		recoveryInterceptor(log),
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next, interceptor := handler, interceptors[i]
+		handler = func(ctx context.Context, req any) (any, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+
+	_, err := handler(ctx, "request")
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+
+	return stream.header, lines, err
+}
+
+func TestRequestID_Propagated(t *testing.T) {
+	var handlerID string
+	header, lines, err := call(t, metadata.Pairs(requestid.MetadataKey, "abc-123"), func(ctx context.Context, _ any) (any, error) {
+		handlerID = requestid.FromContext(ctx)
+		sl.FromContext(ctx, nil).Info("handled")
+		return "ok", nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc-123", handlerID)
+	assert.Equal(t, []string{"abc-123"}, header.Get(requestid.MetadataKey))
+
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, "abc-123", line["request_id"])
+	}
+}
+
+func TestRequestID_Generated(t *testing.T) {
+	for name, md := range map[string]metadata.MD{
+		"missing":  nil,
+		"invalid":  metadata.Pairs(requestid.MetadataKey, "bad id\n"),
+		"too long": metadata.Pairs(requestid.MetadataKey, strings.Repeat("a", requestid.MaxLength+1)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var handlerID string
+			header, _, err := call(t, md, func(ctx context.Context, _ any) (any, error) {
+				handlerID = requestid.FromContext(ctx)
+				return "ok", nil
+			})
+			require.NoError(t, err)
+
+			assert.True(t, requestid.Valid(handlerID))
+			assert.Equal(t, []string{handlerID}, header.Get(requestid.MetadataKey))
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	_, lines, err := call(t, nil, func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.Len(t, lines, 1)
+	assert.Equal(t, "grpc call", lines[0]["msg"])
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, registerMethod, lines[0]["method"])
+	assert.Equal(t, codes.NotFound.String(), lines[0]["code"])
+	assert.Contains(t, lines[0], "latency")
+	assert.Contains(t, lines[0], "peer")
+}
+
+func TestRecovery(t *testing.T) {
+	_, lines, err := call(t, nil, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	require.Len(t, lines, 2)
+	assert.Equal(t, "panic in grpc handler", lines[0]["msg"])
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.Equal(t, "ERROR", lines[1]["level"], "panics are logged as server errors")
+	assert.Equal(t, codes.Internal.String(), lines[1]["code"])
+}
+
+func TestClientInfo_Identity(t *testing.T) {
+	cert := &x509.Certificate{Raw: []byte("der"), Subject: pkix.Name{CommonName: "billing"}}
+	verified := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}},
+	})
+
+	var client clientinfo.Info
+	handler := func(ctx context.Context, _ any) (any, error) {
+		client = clientinfo.FromContext(ctx)
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: registerMethod}
+
+	_, err := clientInfoInterceptor()(verified, "request", info, handler)
+	require.NoError(t, err)
+	require.NotNil(t, client.Identity)
+	assert.Equal(t, "billing", client.Identity.CommonName)
+	assert.Len(t, client.Identity.Fingerprint, 64)
+
+	forwarded := metadata.NewIncomingContext(verified, metadata.Pairs(clientinfo.ForwardedForKey, "203.0.113.1"))
+	_, err = clientInfoInterceptor()(forwarded, "request", info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.1", client.IP)
+	assert.Nil(t, client.Identity, "certificate of the gateway doesn't identify the client")
+}
+
+// contextStream is a server stream of the context.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), new(headerStream))
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(requestid.MetadataKey, "abc-123"))
+
+	interceptors := streamInterceptors(requestIDInterceptor(log), recoveryInterceptor(log))
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch", IsServerStream: true}
+
+	var handlerID string
+	handler := func(_ any, ss grpc.ServerStream) error {
+		handlerID = requestid.FromContext(ss.Context())
+		panic("boom")
+	}
+
+	// chain the interceptors the way the server does
+	err := interceptors[0](nil, contextStream{ctx: ctx}, info, func(srv any, ss grpc.ServerStream) error {
+		return interceptors[1](srv, ss, info, handler)
+	})
+
+	assert.Equal(t, "abc-123", handlerID, "stream handlers get request context")
+	assert.Equal(t, codes.Internal, status.Code(err), "panics of stream handlers are recovered")
+	assert.Contains(t, buf.String(), `"request_id":"abc-123"`)
+}
@@ -0,0 +1,24 @@
+package grpcapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/lib/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// metricsInterceptor counts calls by method and status code and measures their duration.
+func metricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		metrics.GRPCRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		metrics.GRPCDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	}
+}
@@ -0,0 +1,138 @@
+package grpcapp
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/pow"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of proof-of-work challenges. The challenge is sent in trailers of
+// the rejected request, the client retries with the challenge and its solution.
+const (
+	PoWChallengeKey = "x-pow-challenge"
+	PoWSolutionKey  = "x-pow-solution"
+)
+
+type powGuard struct {
+	cfg    config.PoWConfig
+	issuer *pow.Issuer
+	ips    *pow.Counter
+	emails *pow.Counter
+
+	// spent remembers solved challenges until they expire, so a solution is used once.
+	// It is kept in memory, behind a load balancer a solution is single use per replica.
+	mu    sync.Mutex
+	spent map[string]time.Time
+}
+
+// powInterceptor answers requests to guarded methods from clients over the risk threshold
+// with a proof-of-work challenge, unless they carry a solution of a challenge at least as hard.
+//
+// Counters and spent challenges are local to the replica: with several replicas the thresholds
+// apply per replica and a solution may be replayed once against each of them.
+func powInterceptor(cfg config.PoWConfig, secret string) grpc.UnaryServerInterceptor {
+	g := &powGuard{
+		cfg:    cfg,
+		issuer: pow.NewIssuer(secret, cfg.ChallengeTTL),
+		ips:    pow.NewCounter(cfg.Window),
+		emails: pow.NewCounter(cfg.Window),
+		spent:  make(map[string]time.Time),
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(g.cfg.Methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		// challenges are bound to the client and the method
+		subject := clientinfo.FromContext(ctx).IP + " " + info.FullMethod
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		challenge, solution := first(md.Get(PoWChallengeKey)), first(md.Get(PoWSolutionKey))
+
+		// the request the challenge was issued for has been counted, counting its retry
+		// would raise the difficulty over the solved one
+		if challenge != "" && g.accept(challenge, solution, subject, g.difficulty(ctx, req, false)) {
+			return handler(ctx, req)
+		}
+
+		difficulty := g.difficulty(ctx, req, true)
+		if difficulty == 0 {
+			return handler(ctx, req)
+		}
+
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(PoWChallengeKey, g.issuer.Issue(subject, difficulty)))
+
+		return nil, status.Error(codes.ResourceExhausted, "proof of work required")
+	}
+}
+
+// difficulty returns difficulty of the challenge the client must solve, zero if the client
+// is under the thresholds. The request is counted if count is set. Threshold of zero disables its check.
+func (g *powGuard) difficulty(ctx context.Context, req any, count bool) int {
+	seen := (*pow.Counter).Count
+	if count {
+		seen = (*pow.Counter).Add
+	}
+
+	var risk float64
+
+	if g.cfg.IPThreshold > 0 {
+		risk = float64(seen(g.ips, clientinfo.FromContext(ctx).IP)) / float64(g.cfg.IPThreshold)
+	}
+
+	if r, ok := req.(interface{ GetEmail() string }); ok && r.GetEmail() != "" && g.cfg.EmailThreshold > 0 {
+		email := strings.ToLower(strings.TrimSpace(r.GetEmail()))
+		risk = max(risk, float64(seen(g.emails, email))/float64(g.cfg.EmailThreshold))
+	}
+
+	if risk <= 1 {
+		return 0
+	}
+
+	return min(g.cfg.Difficulty+int(math.Log2(risk)), g.cfg.MaxDifficulty)
+}
+
+// accept verifies the solution and spends the challenge.
+func (g *powGuard) accept(challenge, solution, subject string, difficulty int) bool {
+	solved, err := g.issuer.Verify(challenge, solution, subject)
+	if err != nil || solved < difficulty {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for c, expiresAt := range g.spent {
+		if now.After(expiresAt) {
+			delete(g.spent, c)
+		}
+	}
+
+	if _, ok := g.spent[challenge]; ok {
+		return false
+	}
+	g.spent[challenge] = now.Add(g.cfg.ChallengeTTL)
+
+	return true
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,187 @@
+package grpcapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/pow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const registerMethod = "/auth.Auth/Register"
+
+type registerRequest struct{ email string }
+
+func (r registerRequest) GetEmail() string { return r.email }
+
+// trailerStream captures trailers set by interceptors.
+type trailerStream struct {
+	grpc.ServerTransportStream
+	trailer metadata.MD
+}
+
+func (s *trailerStream) Method() string { return registerMethod }
+
+func (s *trailerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+type powClient struct {
+	interceptor grpc.UnaryServerInterceptor
+	ip          string
+}
+
+// call invokes the interceptor and returns challenge sent in trailers, if any.
+func (c powClient) call(email string, md metadata.MD) (challenge string, err error) {
+	stream := new(trailerStream)
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = clientinfo.WithInfo(ctx, clientinfo.Info{IP: c.ip})
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	info := &grpc.UnaryServerInfo{FullMethod: registerMethod}
+	_, err = c.interceptor(ctx, registerRequest{email}, info, func(context.Context, any) (any, error) {
+		return "ok", nil
+	})
+
+	return first(stream.trailer.Get(PoWChallengeKey)), err
+}
+
+func TestPoWInterceptor(t *testing.T) {
+	interceptor := powInterceptor(config.PoWConfig{
+		Methods:        []string{registerMethod},
+		Window:         time.Minute,
+		IPThreshold:    3,
+		EmailThreshold: 2,
+		Difficulty:     8,
+		MaxDifficulty:  10,
+		ChallengeTTL:   time.Minute,
+	}, "secret")
+
+	client := powClient{interceptor: interceptor, ip: "192.0.2.1"}
+
+	for range 3 {
+		_, err := client.call("", nil)
+		require.NoError(t, err, "clients under the threshold are not challenged")
+	}
+
+	challenge, err := client.call("", nil)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.NotEmpty(t, challenge)
+
+	difficulty, err := pow.Difficulty(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, 8, difficulty)
+
+	solution, err := pow.Solve(context.Background(), challenge)
+	require.NoError(t, err)
+
+	solved := metadata.Pairs(PoWChallengeKey, challenge, PoWSolutionKey, solution)
+
+	_, err = client.call("", solved)
+	require.NoError(t, err, "solved requests pass")
+
+	_, err = client.call("", solved)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "solutions are spent")
+
+	other := powClient{interceptor: interceptor, ip: "198.51.100.1"}
+	_, err = other.call("", solved)
+	require.NoError(t, err, "other clients are under the threshold")
+
+	for range 10 {
+		challenge, err = client.call("", nil)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	}
+
+	difficulty, err = pow.Difficulty(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, 10, difficulty, "difficulty grows with risk up to the maximum")
+}
+
+func TestPoWInterceptor_SolvedRetryIsNotCounted(t *testing.T) {
+	interceptor := powInterceptor(config.PoWConfig{
+		Methods:       []string{registerMethod},
+		Window:        time.Minute,
+		IPThreshold:   1,
+		Difficulty:    4,
+		MaxDifficulty: 12,
+		ChallengeTTL:  time.Minute,
+	}, "secret")
+
+	client := powClient{interceptor: interceptor, ip: "192.0.2.1"}
+
+	var (
+		challenge string
+		err       error
+	)
+	for range 3 {
+		challenge, err = client.call("", nil)
+	}
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	difficulty, err := pow.Difficulty(challenge)
+	require.NoError(t, err)
+	require.Equal(t, 5, difficulty)
+
+	solution, err := pow.Solve(context.Background(), challenge)
+	require.NoError(t, err)
+
+	// counting the retry would make it the fourth request, which needs difficulty of 6
+	_, err = client.call("", metadata.Pairs(PoWChallengeKey, challenge, PoWSolutionKey, solution))
+	require.NoError(t, err)
+
+	challenge, err = client.call("", nil)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	difficulty, err = pow.Difficulty(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, 6, difficulty)
+}
+
+func TestPoWInterceptor_EmailThreshold(t *testing.T) {
+	interceptor := powInterceptor(config.PoWConfig{
+		Methods:        []string{registerMethod},
+		Window:         time.Minute,
+		IPThreshold:    100,
+		EmailThreshold: 2,
+		Difficulty:     4,
+		MaxDifficulty:  4,
+		ChallengeTTL:   time.Minute,
+	}, "secret")
+
+	// a botnet tries the same email from different addresses
+	ips := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}
+	var err error
+	for _, ip := range ips {
+		_, err = powClient{interceptor: interceptor, ip: ip}.call("Victim@Example.com ", nil)
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = powClient{interceptor: interceptor, ip: "192.0.2.4"}.call("other@example.com", nil)
+	assert.NoError(t, err)
+}
+
+func TestPoWInterceptor_UnguardedMethod(t *testing.T) {
+	interceptor := powInterceptor(config.PoWConfig{
+		Methods:      []string{registerMethod},
+		Window:       time.Minute,
+		IPThreshold:  1,
+		ChallengeTTL: time.Minute,
+	}, "secret")
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.Auth/ValidateToken"}
+	for range 5 {
+		_, err := interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+			return "ok", nil
+		})
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,33 @@
+package grpcapp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kurochkinivan/auth/internal/lib/requestid"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDInterceptor takes request id from x-request-id metadata, or generates one
+// if it is missing or invalid, and returns it in x-request-id header.
+// The id is attached to the logger of request context.
+func requestIDInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		id := first(md.Get(requestid.MetadataKey))
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		// the header is informational, the request is served anyway
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+
+		ctx = requestid.WithID(ctx, id)
+		ctx = sl.WithLogger(ctx, log.With(slog.String("request_id", id)))
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,42 @@
+package grpcapp
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// streamInterceptor runs the unary interceptor around a streaming call, so streams,
+// e.g. Health/Watch, get the same request context as unary calls.
+// The interceptor gets no request, streams pass their messages through the stream.
+func streamInterceptor(interceptor grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		unaryInfo := &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}
+
+		_, err := interceptor(ss.Context(), nil, unaryInfo, func(ctx context.Context, _ any) (any, error) {
+			return nil, handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+
+		return err
+	}
+}
+
+// streamInterceptors adapts the unary interceptors to streams.
+func streamInterceptors(interceptors ...grpc.UnaryServerInterceptor) []grpc.StreamServerInterceptor {
+	stream := make([]grpc.StreamServerInterceptor, 0, len(interceptors))
+	for _, interceptor := range interceptors {
+		stream = append(stream, streamInterceptor(interceptor))
+	}
+
+	return stream
+}
+
+// serverStream is a stream with the context of its interceptors.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
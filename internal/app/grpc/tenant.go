@@ -0,0 +1,45 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type TenantResolver interface {
+	Resolve(ctx context.Context, slug string) (tenantID uuid.UUID, err error)
+}
+
+// tenantInterceptor scopes request context to the tenant passed in x-tenant metadata.
+// Requests without the metadata belong to the default tenant.
+func tenantInterceptor(resolver TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get(tenancy.MetadataKey)
+		if len(values) == 0 || values[0] == "" {
+			return handler(ctx, req)
+		}
+
+		tenantID, err := resolver.Resolve(ctx, values[0])
+		if err != nil {
+			switch {
+			case errors.Is(err, tenant.ErrTenantNotFound):
+				return nil, status.Error(codes.NotFound, "tenant not found")
+			case errors.Is(err, tenant.ErrTenantDisabled):
+				return nil, status.Error(codes.PermissionDenied, "tenant is disabled")
+			default:
+				return nil, status.Error(codes.Internal, "internal error")
+			}
+		}
+
+		return handler(tenancy.WithTenant(ctx, tenantID), req)
+	}
+}
@@ -0,0 +1,52 @@
+package grpcapp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/tlsutil"
+	"google.golang.org/grpc/credentials"
+)
+
+var ErrNoClientCA = errors.New("client ca is required to verify client certificates")
+
+// transportCredentials returns TLS credentials of the server, which reload certificates
+// changed on disk.
+func transportCredentials(log *slog.Logger, cfg config.TLSConfig) (credentials.TransportCredentials, error) {
+	const op = "grpcapp.transportCredentials"
+
+	minVersion, err := tlsutil.Version(cfg.MinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cipherSuites, err := tlsutil.CipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	clientAuth, err := tlsutil.ClientAuth(cfg.ClientAuth)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if clientAuth != tls.NoClientCert && cfg.ClientCAPath == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoClientCA)
+	}
+
+	base := &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+		ClientAuth:   clientAuth,
+		NextProtos:   []string{"h2"},
+	}
+
+	reloader, err := tlsutil.NewReloader(log, base, cfg.CertPath, cfg.KeyPath, cfg.ClientCAPath, cfg.ReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return credentials.NewTLS(reloader.Config()), nil
+}
@@ -0,0 +1,54 @@
+package healthapp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/healthcheck"
+	"google.golang.org/grpc/health"
+)
+
+type App struct {
+	log     *slog.Logger
+	server  *health.Server
+	checker *healthcheck.Checker
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// New returns App reporting health of the dependencies through the server,
+// which must be registered in gRPC server serving the services.
+func New(log *slog.Logger, cfg config.HealthConfig, server *health.Server, services []string, checks map[string]healthcheck.Check) *App {
+	return &App{
+		log:     log,
+		server:  server,
+		checker: healthcheck.New(log, server, checks, services, cfg.Interval, cfg.Timeout),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run starts the checker in background, it runs until Stop is called.
+func (a *App) Run(ctx context.Context) {
+	ctx, a.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	go func() {
+		defer close(a.done)
+		a.checker.Run(ctx)
+	}()
+}
+
+// Stop reports NOT_SERVING for the rest of the process lifetime, so clients
+// stop sending requests before the servers shut down, and stops the checker.
+func (a *App) Stop() {
+	const op = "healthapp.Stop"
+
+	a.log.With(slog.String("op", op)).Info("stopping health checker...")
+
+	a.server.Shutdown()
+
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+}
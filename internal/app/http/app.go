@@ -0,0 +1,105 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	federationhttp "github.com/kurochkinivan/auth/internal/controller/http/federation"
+	gatewayhttp "github.com/kurochkinivan/auth/internal/controller/http/gateway"
+	oauthhttp "github.com/kurochkinivan/auth/internal/controller/http/oauth"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       string
+	timeout    time.Duration
+}
+
+// New returns App serving OAuth, federation and, if the gateway client is not nil,
+// the Auth API gateway.
+func New(
+	log *slog.Logger,
+	cfg config.HTTPConfig,
+	secret string,
+	oauth oauthhttp.OAuth,
+	federation federationhttp.Federation,
+	providers []federationhttp.Provider,
+	tenants TenantResolver,
+	gatewayCfg config.GatewayConfig,
+	gateway authv1.AuthClient,
+) *App {
+	mux := http.NewServeMux()
+
+	oauthhttp.Register(mux, log, oauth)
+	federationhttp.Register(mux, log, secret, federation, providers...)
+
+	if gateway != nil {
+		gatewayMux := http.NewServeMux()
+		gatewayhttp.Register(gatewayMux, log, gateway)
+
+		mux.Handle(gatewayhttp.Prefix, corsMiddleware(gatewayCfg.CORS, gatewayMux))
+	}
+
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Handler:           clientInfoMiddleware(tenantMiddleware(tenants, mux)),
+			ReadHeaderTimeout: cfg.Timeout,
+			ReadTimeout:       cfg.Timeout,
+			WriteTimeout:      cfg.Timeout,
+		},
+		port:    cfg.Port,
+		timeout: cfg.Timeout,
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "httpapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("port", a.port),
+	)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%s", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("http server is running", slog.String("addr", l.Addr().String()))
+
+	if err := a.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "httpapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping http server...", slog.String("port", a.port))
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		a.log.With(slog.String("op", op)).
+			Error("failed to stop http server gracefully", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,23 @@
+package httpapp
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+)
+
+// clientInfoMiddleware adds address and user agent of the client to request context.
+func clientInfoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := clientinfo.Info{
+			IP:        r.RemoteAddr,
+			UserAgent: r.UserAgent(),
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			client.IP = host
+		}
+
+		next.ServeHTTP(w, r.WithContext(clientinfo.WithInfo(r.Context(), client)))
+	})
+}
@@ -0,0 +1,78 @@
+package httpapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := corsMiddleware(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"X-Request-Id"},
+		MaxAge:         time.Minute,
+	}, next)
+
+	serve := func(method, origin string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/login", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := serve(http.MethodOptions, "https://app.example.com", http.Header{"Access-Control-Request-Method": {"POST"}})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Authorization, Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "60", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = serve(http.MethodPost, "https://app.example.com", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id", rec.Header().Get("Access-Control-Expose-Headers"))
+
+	rec = serve(http.MethodPost, "https://evil.example.com", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = serve(http.MethodPost, "", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get("Vary"))
+}
+
+func TestCORSMiddleware_AnyOrigin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := corsMiddleware(config.CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	}, next)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/login", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"), "credentials are never shared with any origin")
+}
@@ -0,0 +1,53 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	gatewayhttp "github.com/kurochkinivan/auth/internal/controller/http/gateway"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type TenantResolver interface {
+	Resolve(ctx context.Context, slug string) (tenantID uuid.UUID, err error)
+}
+
+// tenantMiddleware scopes request context to the tenant passed in X-Tenant header
+// or, for browser redirects that can't set headers, in tenant query parameter.
+// Requests without either belong to the default tenant.
+// The tenant is set as X-Tenant header as well, so the gateway forwards it to the gRPC server.
+func tenantMiddleware(resolver TenantResolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slug := r.Header.Get(tenancy.MetadataKey)
+		if slug == "" {
+			slug = r.URL.Query().Get("tenant")
+		}
+		if slug == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tenantID, err := resolver.Resolve(r.Context(), slug)
+		if err != nil {
+			switch {
+			case errors.Is(err, tenant.ErrTenantNotFound):
+				gatewayhttp.WriteError(w, status.New(codes.NotFound, "tenant not found"))
+			case errors.Is(err, tenant.ErrTenantDisabled):
+				gatewayhttp.WriteError(w, status.New(codes.PermissionDenied, "tenant is disabled"))
+			default:
+				gatewayhttp.WriteError(w, status.New(codes.Internal, "internal error"))
+			}
+			return
+		}
+
+		r = r.Clone(tenancy.WithTenant(r.Context(), tenantID))
+		r.Header.Set(tenancy.MetadataKey, slug)
+
+		next.ServeHTTP(w, r)
+	})
+}
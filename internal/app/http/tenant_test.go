@@ -0,0 +1,82 @@
+package httpapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantResolver map[string]uuid.UUID
+
+func (r tenantResolver) Resolve(_ context.Context, slug string) (uuid.UUID, error) {
+	tenantID, ok := r[slug]
+	if !ok {
+		return uuid.Nil, tenant.ErrTenantNotFound
+	}
+
+	return tenantID, nil
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	acme := uuid.New()
+
+	var (
+		tenantID uuid.UUID
+		header   string
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID = tenancy.FromContext(r.Context())
+		header = r.Header.Get(tenancy.MetadataKey)
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := tenantMiddleware(tenantResolver{"acme": acme}, next)
+
+	tests := []struct {
+		name     string
+		target   string
+		header   string
+		code     int
+		tenantID uuid.UUID
+		slug     string
+		// errorCode is code of the gateway error in the body
+		errorCode string
+	}{
+		{"default", "/v1/login", "", http.StatusTeapot, tenancy.Default, "", ""},
+		{"header", "/v1/login", "acme", http.StatusTeapot, acme, "acme", ""},
+		{"query is forwarded as header", "/v1/login?tenant=acme", "", http.StatusTeapot, acme, "acme", ""},
+		{"unknown", "/v1/login?tenant=other", "", http.StatusNotFound, uuid.Nil, "", "NotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenantID, header = uuid.Nil, ""
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(tenancy.MetadataKey, tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, tt.tenantID, tenantID)
+			assert.Equal(t, tt.slug, header)
+
+			if tt.errorCode != "" {
+				var body map[string]any
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+				assert.Equal(t, tt.errorCode, body["code"])
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package metricsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/metrics"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// App serves Prometheus metrics at /metrics.
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	address    string
+}
+
+// New returns metrics App and registers collector of the pool statistics.
+// If the address is empty, the metrics are collected, but not served.
+func New(log *slog.Logger, cfg config.MetricsConfig, pool *pgxpool.Pool) *App {
+	prometheus.MustRegister(metrics.NewPoolCollector(pool))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: shutdownTimeout,
+		},
+		address: cfg.Address,
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "metricsapp.Run"
+
+	if a.address == "" {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", a.address)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.With(slog.String("op", op)).
+		Info("metrics server is running", slog.String("addr", l.Addr().String()))
+
+	if err := a.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "metricsapp.Stop"
+
+	if a.address == "" {
+		return
+	}
+
+	log := a.log.With(slog.String("op", op))
+	log.Info("stopping metrics server...", slog.String("addr", a.address))
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		log.Error("failed to stop metrics server gracefully", sl.Err(err))
+	}
+}
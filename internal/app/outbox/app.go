@@ -0,0 +1,92 @@
+package outboxapp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/broker/kafka"
+	"github.com/kurochkinivan/auth/internal/lib/broker/memory"
+	"github.com/kurochkinivan/auth/internal/lib/broker/nats"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/outbox"
+)
+
+const (
+	BrokerKafka  = "kafka"
+	BrokerNATS   = "nats"
+	BrokerMemory = "memory"
+)
+
+type Broker interface {
+	outbox.Broker
+	Close() error
+}
+
+type App struct {
+	log    *slog.Logger
+	relay  *outbox.Relay
+	broker Broker
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// New returns outbox app publishing events to the configured broker and to webhooks.
+func New(log *slog.Logger, cfg config.OutboxConfig, storage outbox.Storage, webhooks outbox.Broker) *App {
+	broker, err := newBroker(cfg)
+	if err != nil {
+		panic("outboxapp.New: " + err.Error())
+	}
+
+	return &App{
+		log:    log,
+		relay:  outbox.New(log, storage, outbox.Brokers{broker, webhooks}, cfg.BatchSize, cfg.PollInterval, cfg.RetryDelay, cfg.Retention),
+		broker: broker,
+		done:   make(chan struct{}),
+	}
+}
+
+func newBroker(cfg config.OutboxConfig) (Broker, error) {
+	switch cfg.Broker {
+	case BrokerKafka:
+		if len(cfg.Kafka.Brokers) == 0 {
+			return nil, fmt.Errorf("kafka brokers are not set")
+		}
+
+		return kafka.New(cfg.Kafka.Brokers, cfg.Kafka.Topic), nil
+	case BrokerNATS:
+		return nats.New(cfg.NATS.URL, cfg.NATS.SubjectPrefix)
+	case BrokerMemory:
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown broker: %s", cfg.Broker)
+	}
+}
+
+// Run starts the relay in background, it runs until Stop is called.
+func (a *App) Run(ctx context.Context) {
+	a.stop = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+		a.relay.Run(context.WithoutCancel(ctx), a.stop)
+	}()
+}
+
+// Stop waits for the batch in flight, so its events are marked as published, and closes the broker.
+func (a *App) Stop() {
+	const op = "outboxapp.Stop"
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("stopping outbox relay...")
+
+	if a.stop != nil {
+		close(a.stop)
+		<-a.done
+	}
+
+	if err := a.broker.Close(); err != nil {
+		log.Error("failed to close broker", sl.Err(err))
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/url"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/tracing"
 	pgclient "github.com/kurochkinivan/pgClient"
 )
 
@@ -22,7 +25,20 @@ type App struct {
 }
 
 func New(ctx context.Context, log *slog.Logger, cfg config.PostgreSQLConfig) *App {
-	pool, err := pgclient.NewClient(ctx, cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DB)
+	connString := &url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(cfg.Username, cfg.Password),
+		Host:   net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:   cfg.DB,
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(connString.String())
+	if err != nil {
+		panic("pgapp.New: " + err.Error())
+	}
+	poolConfig.ConnConfig.Tracer = tracing.NewPgxTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		panic("pgapp.New: " + err.Error())
 	}
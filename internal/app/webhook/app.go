@@ -0,0 +1,50 @@
+package webhookapp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/ssrf"
+	"github.com/kurochkinivan/auth/internal/usecase/webhook"
+)
+
+type App struct {
+	log        *slog.Logger
+	dispatcher *webhook.Dispatcher
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func New(log *slog.Logger, cfg config.WebhookConfig, storage webhook.Storage) *App {
+	// urls of webhooks are supplied by tenants, they must not reach internal services
+	client := ssrf.NewClient(cfg.Timeout, cfg.AllowPrivateNetworks)
+
+	return &App{
+		log:        log,
+		dispatcher: webhook.NewDispatcher(log, storage, client, cfg.BatchSize, cfg.MaxAttempts, cfg.InitialBackoff, cfg.MaxBackoff, cfg.PollInterval),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run starts the dispatcher in background, it runs until Stop is called.
+func (a *App) Run(ctx context.Context) {
+	ctx, a.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	go func() {
+		defer close(a.done)
+		a.dispatcher.Run(ctx)
+	}()
+}
+
+// Stop cancels requests in flight and waits for their outcome to be saved.
+func (a *App) Stop() {
+	const op = "webhookapp.Stop"
+
+	a.log.With(slog.String("op", op)).Info("stopping webhook dispatcher...")
+
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+}
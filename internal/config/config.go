@@ -3,23 +3,78 @@ package config
 import (
 	"flag"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env        string           `yaml:"env" env-default:"local"`
-	Secret     string           `yaml:"secret" env-required:"true"`
-	TokenTTL   time.Duration    `yaml:"token_ttl" env-required:"true"`
-	GRPC       GRPCConfig       `yaml:"grpc" env-required:"true"`
-	PostgreSQL PostgreSQLConfig `yaml:"postgresql" env-required:"true"`
+	Env            string               `yaml:"env" env-default:"local"`
+	Secret         string               `yaml:"secret" env-required:"true"`
+	TokenTTL       time.Duration        `yaml:"token_ttl" env-required:"true"`
+	GRPC           GRPCConfig           `yaml:"grpc" env-required:"true"`
+	HTTP           HTTPConfig           `yaml:"http" env-required:"true"`
+	PostgreSQL     PostgreSQLConfig     `yaml:"postgresql" env-required:"true"`
+	SMTP           SMTPConfig           `yaml:"smtp" env-required:"true"`
+	OTP            OTPConfig            `yaml:"otp"`
+	OAuth          OAuthConfig          `yaml:"oauth"`
+	OIDC           OIDCConfig           `yaml:"oidc" env-required:"true"`
+	Federation     FederationConfig     `yaml:"federation"`
+	LDAP           LDAPConfig           `yaml:"ldap"`
+	Invitation     InvitationConfig     `yaml:"invitation"`
+	ServiceAccount ServiceAccountConfig `yaml:"service_account"`
+	Impersonation  ImpersonationConfig  `yaml:"impersonation"`
+	LoginHistory   LoginHistoryConfig   `yaml:"login_history"`
+	Outbox         OutboxConfig         `yaml:"outbox"`
+	Webhook        WebhookConfig        `yaml:"webhook"`
+	Hardening      HardeningConfig      `yaml:"hardening"`
+	PoW            PoWConfig            `yaml:"pow"`
+	Risk           RiskConfig           `yaml:"risk"`
+	Metrics        MetricsConfig        `yaml:"metrics"`
+	Tracing        TracingConfig        `yaml:"tracing"`
+	Health         HealthConfig         `yaml:"health"`
+	Gateway        GatewayConfig        `yaml:"gateway"`
 }
 
 type GRPCConfig struct {
 	Host    string        `yaml:"host" env-required:"true"`
 	Port    string        `yaml:"port" env-required:"true"`
 	Timeout time.Duration `yaml:"timeout" env-required:"true"`
+	TLS     TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig describes TLS of a server. Files are reloaded when they change,
+// checked at most once per ReloadInterval.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertPath string `yaml:"cert_path"`
+	KeyPath  string `yaml:"key_path"`
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string `yaml:"min_version" env-default:"1.2"`
+	// CipherSuites of TLS 1.2, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, defaults of Go if empty.
+	// Suites of TLS 1.3 are not configurable.
+	CipherSuites []string `yaml:"cipher_suites"`
+	// ClientAuth is "none", "optional" or "require", the latter two verify client certificates against ClientCAPath.
+	ClientAuth     string        `yaml:"client_auth" env-default:"none"`
+	ClientCAPath   string        `yaml:"client_ca_path"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+// ClientTLSConfig describes TLS of a client, CAPath defaults to system roots,
+// CertPath and KeyPath are a client certificate for servers requiring one.
+type ClientTLSConfig struct {
+	CAPath     string `yaml:"ca_path"`
+	CertPath   string `yaml:"cert_path"`
+	KeyPath    string `yaml:"key_path"`
+	ServerName string `yaml:"server_name"`
+}
+
+type HTTPConfig struct {
+	Host    string        `yaml:"host" env-required:"true"`
+	Port    string        `yaml:"port" env-required:"true"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
 }
 
 type PostgreSQLConfig struct {
@@ -30,6 +85,205 @@ type PostgreSQLConfig struct {
 	DB       string `yaml:"db" env-required:"true"`
 }
 
+type SMTPConfig struct {
+	Host     string `yaml:"host" env-required:"true"`
+	Port     string `yaml:"port" env-required:"true"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from" env-required:"true"`
+}
+
+type OTPConfig struct {
+	CodeTTL     time.Duration `yaml:"code_ttl" env-default:"5m"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+	SMSFile     string        `yaml:"sms_file" env-default:"./sms.log"`
+}
+
+type OAuthConfig struct {
+	CodeTTL         time.Duration `yaml:"code_ttl" env-default:"1m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+}
+
+type OIDCConfig struct {
+	// Issuer is trimmed of the trailing slash on load, ID tokens and
+	// the discovery document must carry exactly the same issuer.
+	Issuer string `yaml:"issuer" env-required:"true"`
+	// SigningKeyPath is a path to PEM encoded RSA private key used to sign ID tokens.
+	// If empty, a key is generated at startup and ID tokens don't survive restarts.
+	SigningKeyPath string `yaml:"signing_key_path"`
+}
+
+type FederationConfig struct {
+	Providers []IdentityProviderConfig `yaml:"providers"`
+}
+
+// IdentityProviderConfig describes upstream OpenID provider users can sign in with.
+// RedirectURL must point to /federation/{name}/callback of this service.
+type IdentityProviderConfig struct {
+	Name         string   `yaml:"name" env-required:"true"`
+	Issuer       string   `yaml:"issuer" env-required:"true"`
+	ClientID     string   `yaml:"client_id" env-required:"true"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url" env-required:"true"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// LDAPConfig describes directory users are authenticated against before local passwords.
+// If URL is empty, the directory is disabled.
+type LDAPConfig struct {
+	URL                string            `yaml:"url"`
+	StartTLS           bool              `yaml:"start_tls"`
+	CACertPath         string            `yaml:"ca_cert_path"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify"`
+	BindDN             string            `yaml:"bind_dn"`
+	BindPassword       string            `yaml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	BaseDN             string            `yaml:"base_dn"`
+	UserFilter         string            `yaml:"user_filter" env-default:"(&(objectClass=person)(mail=%s))"`
+	EmailAttribute     string            `yaml:"email_attribute" env-default:"mail"`
+	GroupAttribute     string            `yaml:"group_attribute" env-default:"memberOf"`
+	GroupRoles         map[string]string `yaml:"group_roles"`
+	Timeout            time.Duration     `yaml:"timeout" env-default:"5s"`
+}
+
+type InvitationConfig struct {
+	TTL time.Duration `yaml:"ttl" env-default:"168h"`
+	// AcceptURL is a link to the page accepting invitations, the token is appended to it.
+	// If empty, the email contains only the token.
+	AcceptURL string `yaml:"accept_url"`
+}
+
+type ServiceAccountConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"15m"`
+}
+
+type ImpersonationConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"15m"`
+}
+
+type LoginHistoryConfig struct {
+	// Notifier tells users about sign-ins from new devices or networks: email, log or none.
+	Notifier string `yaml:"notifier" env-default:"email"`
+}
+
+// OutboxConfig describes relay publishing domain events from the outbox.
+type OutboxConfig struct {
+	// Broker events are published to: kafka, nats or memory.
+	Broker       string        `yaml:"broker" env-default:"memory"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	// RetryDelay is how long a failed event and later events of its user wait before the next attempt.
+	RetryDelay time.Duration `yaml:"retry_delay" env-default:"10s"`
+	// Retention is how long published events are kept, zero keeps them forever.
+	Retention time.Duration     `yaml:"retention" env-default:"168h"`
+	Kafka     OutboxKafkaConfig `yaml:"kafka"`
+	NATS      OutboxNATSConfig  `yaml:"nats"`
+}
+
+type OutboxKafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic" env-default:"auth.events"`
+}
+
+type OutboxNATSConfig struct {
+	URL string `yaml:"url" env-default:"nats://localhost:4222"`
+	// SubjectPrefix is followed by the event type, e.g. auth.events.user.registered.
+	SubjectPrefix string `yaml:"subject_prefix" env-default:"auth.events"`
+}
+
+// WebhookConfig describes delivery of domain events to webhooks of tenants.
+type WebhookConfig struct {
+	// MaxAttempts is how many times a delivery is attempted before it is dead.
+	MaxAttempts    int           `yaml:"max_attempts" env-default:"8"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" env-default:"10s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env-default:"1h"`
+	Timeout        time.Duration `yaml:"timeout" env-default:"10s"`
+	BatchSize      int           `yaml:"batch_size" env-default:"50"`
+	PollInterval   time.Duration `yaml:"poll_interval" env-default:"1s"`
+	// AllowPrivateNetworks lets webhooks receive deliveries on loopback and private addresses.
+	// It is meant for local development, in production webhooks could reach internal services.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// HardeningConfig hides which emails are registered from Login and Register responses.
+// Registration of a taken email succeeds without user id then, and the owner is notified by email.
+type HardeningConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinResponseTime pads Login and Register responses, it should exceed their usual duration.
+	MinResponseTime time.Duration `yaml:"min_response_time" env-default:"300ms"`
+}
+
+// PoWConfig describes proof-of-work challenges answered to clients whose address or email
+// was seen in more than threshold requests to the guarded methods during the window.
+// Difficulty grows by a bit every time the threshold is exceeded twice over, up to MaxDifficulty.
+type PoWConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Methods        []string      `yaml:"methods" env-default:"/auth.Auth/Register,/auth.Auth/Login"`
+	Window         time.Duration `yaml:"window" env-default:"10m"`
+	IPThreshold    int           `yaml:"ip_threshold" env-default:"20"`
+	EmailThreshold int           `yaml:"email_threshold" env-default:"5"`
+	Difficulty     int           `yaml:"difficulty" env-default:"18"`
+	MaxDifficulty  int           `yaml:"max_difficulty" env-default:"24"`
+	ChallengeTTL   time.Duration `yaml:"challenge_ttl" env-default:"2m"`
+}
+
+// RiskConfig describes risk scoring of logins. Logins scoring at least MFAScore
+// have to be completed with a one-time code, at least DenyScore are denied.
+// ASN change and impossible travel are detected only if GeoIP databases are given.
+type RiskConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	GeoIPCityPath  string        `yaml:"geoip_city_path"`
+	GeoIPASNPath   string        `yaml:"geoip_asn_path"`
+	VelocityWindow time.Duration `yaml:"velocity_window" env-default:"15m"`
+	// MaxTravelSpeed in km/h.
+	MaxTravelSpeed float64 `yaml:"max_travel_speed" env-default:"1000"`
+	MFAScore       int     `yaml:"mfa_score" env-default:"40"`
+	DenyScore      int     `yaml:"deny_score" env-default:"80"`
+}
+
+// MetricsConfig describes the HTTP server of Prometheus metrics.
+type MetricsConfig struct {
+	// Address to listen on, e.g. ":9090", metrics are not served if it is empty.
+	Address string `yaml:"address"`
+}
+
+// TracingConfig describes export of OpenTelemetry spans.
+// Exporter is one of "otlp", "stdout" or "none".
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" env-default:"none"`
+	// Endpoint of OTLP gRPC collector.
+	Endpoint string `yaml:"endpoint" env-default:"localhost:4317"`
+	Insecure bool   `yaml:"insecure"`
+	// SampleRatio is the share of traces started by the service that are exported.
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// HealthConfig describes checks of dependencies reported by grpc.health.v1 service.
+type HealthConfig struct {
+	Interval time.Duration `yaml:"interval" env-default:"5s"`
+	// Timeout of a single run of all checks.
+	Timeout time.Duration `yaml:"timeout" env-default:"2s"`
+}
+
+// GatewayConfig describes HTTP/JSON endpoints of the Auth API served by the HTTP server
+// and forwarded to the gRPC server.
+type GatewayConfig struct {
+	Enabled bool       `yaml:"enabled"`
+	CORS    CORSConfig `yaml:"cors"`
+	// TLS of connection to the gRPC server, used if the server has TLS enabled.
+	TLS ClientTLSConfig `yaml:"tls"`
+}
+
+// CORSConfig describes origins allowed to call the gateway from browsers,
+// "*" allows any origin. CORS is disabled if no origin is allowed.
+// Credentials can be allowed only for listed origins, not together with "*".
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env-default:"Authorization,Content-Type,X-Tenant,X-Request-Id,X-Pow-Challenge,X-Pow-Solution"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env-default:"X-Request-Id,X-Pow-Challenge"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" env-default:"10m"`
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 	if path == "" {
@@ -49,6 +303,13 @@ func MustLoadByPath(path string) *Config {
 		panic("failed to read config: " + err.Error())
 	}
 
+	cfg.OIDC.Issuer = strings.TrimSuffix(cfg.OIDC.Issuer, "/")
+
+	// any site could make authenticated requests on behalf of the user
+	if cors := cfg.Gateway.CORS; cors.AllowCredentials && slices.Contains(cors.AllowedOrigins, "*") {
+		panic("gateway.cors.allow_credentials can't be used with allowed origin \"*\", list the origins")
+	}
+
 	return &cfg
 }
 
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// load loads local config with the replacements applied.
+func load(t *testing.T, replacements ...string) *Config {
+	t.Helper()
+
+	data, err := os.ReadFile("../../config/local.yaml")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(strings.NewReplacer(replacements...).Replace(string(data))), 0o600))
+
+	return MustLoadByPath(path)
+}
+
+func TestMustLoadByPath_Issuer(t *testing.T) {
+	cfg := load(t, "issuer: 'http://localhost:8080'", "issuer: 'http://localhost:8080/'")
+
+	assert.Equal(t, "http://localhost:8080", cfg.OIDC.Issuer)
+}
+
+func TestMustLoadByPath_CORS(t *testing.T) {
+	assert.Panics(t, func() {
+		load(t, "allowed_origins: ['http://localhost:3000']", "allowed_origins: ['*']",
+			"allow_credentials: false", "allow_credentials: true")
+	}, "credentials are not allowed for any origin")
+
+	assert.NotPanics(t, func() {
+		load(t, "allowed_origins: ['http://localhost:3000']", "allowed_origins: ['*']")
+	})
+
+	assert.NotPanics(t, func() {
+		load(t, "allow_credentials: false", "allow_credentials: true")
+	})
+}
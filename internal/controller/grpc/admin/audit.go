@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/audit"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type AuditLog interface {
+	Query(ctx context.Context, filter entity.AuditFilter, pageSize int, pageToken string) (events []*entity.AuditEvent, nextPageToken string, err error)
+}
+
+func (s *serverAPI) QueryAuditLog(ctx context.Context, req *authv1.QueryAuditLogRequest) (*authv1.QueryAuditLogResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := auditFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	}
+
+	events, nextPageToken, err := s.auditLog.Query(ctx, filter, int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.QueryAuditLogResponse{
+		Events:        make([]*authv1.AuditEvent, 0, len(events)),
+		NextPageToken: nextPageToken,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, toAuditEvent(event))
+	}
+
+	return resp, nil
+}
+
+func auditFilter(req *authv1.QueryAuditLogRequest) (entity.AuditFilter, error) {
+	filter := entity.AuditFilter{
+		Type: req.GetType(),
+	}
+
+	if req.GetUserId() != "" {
+		userID, err := parseID(req.GetUserId(), "user id")
+		if err != nil {
+			return entity.AuditFilter{}, err
+		}
+		filter.UserID = userID
+	}
+
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return entity.AuditFilter{}, status.Error(codes.InvalidArgument, "until must be after since")
+	}
+
+	return filter, nil
+}
+
+func toAuditEvent(event *entity.AuditEvent) *authv1.AuditEvent {
+	resp := &authv1.AuditEvent{
+		Id:        event.ID,
+		TenantId:  event.TenantID.String(),
+		Type:      event.Type,
+		Outcome:   event.Outcome,
+		Ip:        event.IP,
+		UserAgent: event.UserAgent,
+		Details:   event.Details,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+	if event.UserID != uuid.Nil {
+		resp.UserId = event.UserID.String()
+	}
+	if event.ActorID != uuid.Nil {
+		resp.ActorId = event.ActorID.String()
+	}
+
+	return resp
+}
@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/impersonation"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Impersonation interface {
+	Impersonate(ctx context.Context, actorID, actorTenantID, userID uuid.UUID, reason string) (token string, expiresIn time.Duration, err error)
+}
+
+func (s *serverAPI) Impersonate(ctx context.Context, req *authv1.ImpersonateRequest) (*authv1.ImpersonateResponse, error) {
+	actorID, tenantID, err := s.authorizeRole(ctx, entity.RoleSupport)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := parseID(req.GetUserId(), "user id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate.Var(req.GetReason(), "required,max=512"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+
+	token, expiresIn, err := s.impersonation.Impersonate(ctx, actorID, tenantID, userID, req.GetReason())
+	if err != nil {
+		switch {
+		case errors.Is(err, impersonation.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, impersonation.ErrForbidden):
+			return nil, status.Error(codes.PermissionDenied, "user can't be impersonated")
+		default:
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return &authv1.ImpersonateResponse{
+		Token:     token,
+		ExpiresIn: int64(expiresIn.Seconds()),
+	}, nil
+}
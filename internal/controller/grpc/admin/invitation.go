@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/invitation"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *serverAPI) CreateInvitation(ctx context.Context, req *authv1.CreateInvitationRequest) (*authv1.CreateInvitationResponse, error) {
+	ctx, adminID, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateInvitation(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	inv, err := s.invitations.Create(ctx, adminID, req.GetEmail(), req.GetRole())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.CreateInvitationResponse{
+		Invitation: toInvitation(inv),
+	}, nil
+}
+
+func (s *serverAPI) ListInvitations(ctx context.Context, req *authv1.ListInvitationsRequest) (*authv1.ListInvitationsResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.invitations.Pending(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ListInvitationsResponse{
+		Invitations: make([]*authv1.Invitation, 0, len(invitations)),
+	}
+	for _, inv := range invitations {
+		resp.Invitations = append(resp.Invitations, toInvitation(inv))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeInvitation(ctx context.Context, req *authv1.RevokeInvitationRequest) (*authv1.RevokeInvitationResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitationID, err := parseID(req.GetId(), "invitation id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.invitations.Revoke(ctx, invitationID); err != nil {
+		if errors.Is(err, invitation.ErrInvitationNotFound) {
+			return nil, status.Error(codes.NotFound, "pending invitation not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.RevokeInvitationResponse{}, nil
+}
+
+func toInvitation(inv *entity.Invitation) *authv1.Invitation {
+	return &authv1.Invitation{
+		Id:        inv.ID.String(),
+		Email:     inv.Email,
+		Role:      inv.Role,
+		InvitedBy: inv.InvitedBy.String(),
+		ExpiresAt: timestamppb.New(inv.ExpiresAt),
+		CreatedAt: timestamppb.New(inv.CreatedAt),
+	}
+}
+
+func validateCreateInvitation(req *authv1.CreateInvitationRequest, validate *validator.Validate) error {
+	err := validate.Var(req.GetEmail(), "required,email")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "valid email is required")
+	}
+
+	if !slices.Contains(entity.Roles, req.GetRole()) {
+		return status.Error(codes.InvalidArgument, "role must be one of "+strings.Join(entity.Roles, ", "))
+	}
+
+	return nil
+}
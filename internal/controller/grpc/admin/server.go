@@ -0,0 +1,289 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/controller/grpc/bearer"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/tenant"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Tenants interface {
+	Create(ctx context.Context, slug, name string) (*entity.Tenant, error)
+	Tenant(ctx context.Context, tenantID uuid.UUID) (*entity.Tenant, error)
+	Tenants(ctx context.Context) ([]*entity.Tenant, error)
+	Update(ctx context.Context, tenantID uuid.UUID, name string, disabled bool) (*entity.Tenant, error)
+	Delete(ctx context.Context, tenantID uuid.UUID) error
+}
+
+type Invitations interface {
+	Create(ctx context.Context, invitedBy uuid.UUID, email, role string) (*entity.Invitation, error)
+	Pending(ctx context.Context) ([]*entity.Invitation, error)
+	Revoke(ctx context.Context, invitationID uuid.UUID) error
+}
+
+type serverAPI struct {
+	authv1.UnimplementedAdminServer
+	validate        *validator.Validate
+	secret          string
+	tenants         Tenants
+	invitations     Invitations
+	serviceAccounts ServiceAccounts
+	impersonation   Impersonation
+	auditLog        AuditLog
+	webhooks        Webhooks
+}
+
+func Register(
+	gRPC *grpc.Server,
+	validate *validator.Validate,
+	secret string,
+	tenants Tenants,
+	invitations Invitations,
+	serviceAccounts ServiceAccounts,
+	impersonation Impersonation,
+	auditLog AuditLog,
+	webhooks Webhooks,
+) {
+	authv1.RegisterAdminServer(gRPC, &serverAPI{
+		validate:        validate,
+		secret:          secret,
+		tenants:         tenants,
+		invitations:     invitations,
+		serviceAccounts: serviceAccounts,
+		impersonation:   impersonation,
+		auditLog:        auditLog,
+		webhooks:        webhooks,
+	})
+}
+
+func (s *serverAPI) CreateTenant(ctx context.Context, req *authv1.CreateTenantRequest) (*authv1.CreateTenantResponse, error) {
+	if err := s.authorizeService(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateTenant(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	t, err := s.tenants.Create(ctx, req.GetSlug(), req.GetName())
+	if err != nil {
+		if errors.Is(err, tenant.ErrTenantExists) {
+			return nil, status.Error(codes.AlreadyExists, "tenant already exists")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.CreateTenantResponse{
+		Tenant: toTenant(t),
+	}, nil
+}
+
+func (s *serverAPI) GetTenant(ctx context.Context, req *authv1.GetTenantRequest) (*authv1.GetTenantResponse, error) {
+	if err := s.authorizeService(ctx); err != nil {
+		return nil, err
+	}
+
+	tenantID, err := parseID(req.GetId(), "tenant id")
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.tenants.Tenant(ctx, tenantID)
+	if err != nil {
+		return nil, tenantError(err)
+	}
+
+	return &authv1.GetTenantResponse{
+		Tenant: toTenant(t),
+	}, nil
+}
+
+func (s *serverAPI) ListTenants(ctx context.Context, req *authv1.ListTenantsRequest) (*authv1.ListTenantsResponse, error) {
+	if err := s.authorizeService(ctx); err != nil {
+		return nil, err
+	}
+
+	tenants, err := s.tenants.Tenants(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ListTenantsResponse{
+		Tenants: make([]*authv1.Tenant, 0, len(tenants)),
+	}
+	for _, t := range tenants {
+		resp.Tenants = append(resp.Tenants, toTenant(t))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) UpdateTenant(ctx context.Context, req *authv1.UpdateTenantRequest) (*authv1.UpdateTenantResponse, error) {
+	if err := s.authorizeService(ctx); err != nil {
+		return nil, err
+	}
+
+	tenantID, err := parseID(req.GetId(), "tenant id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate.Var(req.GetName(), "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	t, err := s.tenants.Update(ctx, tenantID, req.GetName(), req.GetDisabled())
+	if err != nil {
+		return nil, tenantError(err)
+	}
+
+	return &authv1.UpdateTenantResponse{
+		Tenant: toTenant(t),
+	}, nil
+}
+
+func (s *serverAPI) DeleteTenant(ctx context.Context, req *authv1.DeleteTenantRequest) (*authv1.DeleteTenantResponse, error) {
+	if err := s.authorizeService(ctx); err != nil {
+		return nil, err
+	}
+
+	tenantID, err := parseID(req.GetId(), "tenant id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tenants.Delete(ctx, tenantID); err != nil {
+		return nil, tenantError(err)
+	}
+
+	return &authv1.DeleteTenantResponse{}, nil
+}
+
+// authorize checks that the caller presented access token of an admin
+// and returns id of the admin and of the tenant the admin manages.
+func (s *serverAPI) authorize(ctx context.Context) (adminID, tenantID uuid.UUID, err error) {
+	return s.authorizeRole(ctx, entity.RoleAdmin)
+}
+
+// authorizeRole checks that the caller presented access token with the role
+// and returns id of the caller and of its tenant. Tokens delegated to OAuth clients
+// or API keys and tokens restricted to scopes are rejected whatever roles they carry.
+func (s *serverAPI) authorizeRole(ctx context.Context, role string) (userID, tenantID uuid.UUID, err error) {
+	token, err := bearer.Token(ctx)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	claims, err := jwt.Parse(token, s.secret)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+
+	for _, claim := range []string{"client_id", "api_key_id", "scope"} {
+		if _, ok := claims[claim]; ok {
+			return uuid.Nil, uuid.Nil, status.Error(codes.PermissionDenied, "delegated tokens can't be used for administration")
+		}
+	}
+
+	roles, _ := claims["roles"].([]any)
+	if !slices.Contains(roles, any(role)) {
+		return uuid.Nil, uuid.Nil, status.Error(codes.PermissionDenied, role+" role is required")
+	}
+
+	subject, _ := claims["sub"].(string)
+	tenantClaim, _ := claims["tenant_id"].(string)
+
+	userID, err = uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+
+	tenantID, err = uuid.Parse(tenantClaim)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+
+	return userID, tenantID, nil
+}
+
+// authorizeTenant checks that the caller is an admin and returns context scoped
+// to the tenant of the admin along with id of the admin.
+func (s *serverAPI) authorizeTenant(ctx context.Context) (context.Context, uuid.UUID, error) {
+	adminID, tenantID, err := s.authorize(ctx)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	return tenancy.WithTenant(ctx, tenantID), adminID, nil
+}
+
+// authorizeService checks that the caller is an admin of the default tenant,
+// who manages the service as a whole.
+func (s *serverAPI) authorizeService(ctx context.Context) error {
+	_, tenantID, err := s.authorize(ctx)
+	if err != nil {
+		return err
+	}
+
+	if tenantID != tenancy.Default {
+		return status.Error(codes.PermissionDenied, "admin of the default tenant is required")
+	}
+
+	return nil
+}
+
+func tenantError(err error) error {
+	switch {
+	case errors.Is(err, tenant.ErrTenantNotFound):
+		return status.Error(codes.NotFound, "tenant not found")
+	case errors.Is(err, tenant.ErrDefaultTenant):
+		return status.Error(codes.FailedPrecondition, "default tenant can't be deleted or disabled")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toTenant(t *entity.Tenant) *authv1.Tenant {
+	return &authv1.Tenant{
+		Id:        t.ID.String(),
+		Slug:      t.Slug,
+		Name:      t.Name,
+		Disabled:  t.Disabled,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+}
+
+func parseID(id, name string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, name+" must be a valid uuid")
+	}
+
+	return parsed, nil
+}
+
+func validateCreateTenant(req *authv1.CreateTenantRequest, validate *validator.Validate) error {
+	err := validate.Var(req.GetSlug(), "required,max=63,lowercase,hostname_rfc1123,excludes=.")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "slug must consist of lowercase letters, digits and hyphens")
+	}
+
+	err = validate.Var(req.GetName(), "required")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	return nil
+}
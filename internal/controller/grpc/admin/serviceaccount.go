@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/serviceaccount"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type ServiceAccounts interface {
+	Create(ctx context.Context, name string, scopes []string, publicKey string) (account *entity.ServiceAccount, secret string, err error)
+	ServiceAccounts(ctx context.Context) ([]*entity.ServiceAccount, error)
+	RotateSecret(ctx context.Context, accountID uuid.UUID) (secret string, err error)
+	Delete(ctx context.Context, accountID uuid.UUID) error
+}
+
+func (s *serverAPI) CreateServiceAccount(ctx context.Context, req *authv1.CreateServiceAccountRequest) (*authv1.CreateServiceAccountResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateServiceAccount(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	account, secret, err := s.serviceAccounts.Create(ctx, req.GetName(), req.GetScopes(), req.GetPublicKey())
+	if err != nil {
+		if errors.Is(err, serviceaccount.ErrInvalidPublicKey) {
+			return nil, status.Error(codes.InvalidArgument, "public key must be a PEM encoded RSA or ECDSA key")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.CreateServiceAccountResponse{
+		ServiceAccount: toServiceAccount(account),
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (s *serverAPI) ListServiceAccounts(ctx context.Context, req *authv1.ListServiceAccountsRequest) (*authv1.ListServiceAccountsResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.serviceAccounts.ServiceAccounts(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ListServiceAccountsResponse{
+		ServiceAccounts: make([]*authv1.ServiceAccount, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, toServiceAccount(account))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) RotateServiceAccountSecret(ctx context.Context, req *authv1.RotateServiceAccountSecretRequest) (*authv1.RotateServiceAccountSecretResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID, err := parseID(req.GetId(), "service account id")
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.serviceAccounts.RotateSecret(ctx, accountID)
+	if err != nil {
+		return nil, serviceAccountError(err)
+	}
+
+	return &authv1.RotateServiceAccountSecretResponse{
+		ClientSecret: secret,
+	}, nil
+}
+
+func (s *serverAPI) DeleteServiceAccount(ctx context.Context, req *authv1.DeleteServiceAccountRequest) (*authv1.DeleteServiceAccountResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID, err := parseID(req.GetId(), "service account id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.serviceAccounts.Delete(ctx, accountID); err != nil {
+		return nil, serviceAccountError(err)
+	}
+
+	return &authv1.DeleteServiceAccountResponse{}, nil
+}
+
+func serviceAccountError(err error) error {
+	if errors.Is(err, serviceaccount.ErrServiceAccountNotFound) {
+		return status.Error(codes.NotFound, "service account not found")
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toServiceAccount(account *entity.ServiceAccount) *authv1.ServiceAccount {
+	return &authv1.ServiceAccount{
+		Id:           account.ID.String(),
+		Name:         account.Name,
+		Scopes:       account.Scopes,
+		HasSecret:    len(account.SecretHash) > 0,
+		HasPublicKey: account.PublicKey != "",
+		CreatedAt:    timestamppb.New(account.CreatedAt),
+	}
+}
+
+func validateCreateServiceAccount(req *authv1.CreateServiceAccountRequest, validate *validator.Validate) error {
+	err := validate.Var(req.GetName(), "required,max=128")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	err = validate.Var(req.GetScopes(), "dive,required,excludesall= ")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "scopes must be non-empty and must not contain spaces")
+	}
+
+	return nil
+}
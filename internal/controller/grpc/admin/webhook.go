@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/webhook"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Webhooks interface {
+	Create(ctx context.Context, url string, eventTypes []string) (*entity.Webhook, error)
+	Webhooks(ctx context.Context) ([]*entity.Webhook, error)
+	Delete(ctx context.Context, webhookID uuid.UUID) error
+	Deliveries(ctx context.Context, filter entity.DeliveryFilter, pageSize int) ([]*entity.WebhookDelivery, error)
+	Replay(ctx context.Context, deliveryID uuid.UUID) error
+}
+
+func (s *serverAPI) CreateWebhook(ctx context.Context, req *authv1.CreateWebhookRequest) (*authv1.CreateWebhookResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate.Var(req.GetUrl(), "required,url"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "valid url is required")
+	}
+
+	if len(req.GetEventTypes()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "event types are required")
+	}
+
+	w, err := s.webhooks.Create(ctx, req.GetUrl(), req.GetEventTypes())
+	if err != nil {
+		return nil, webhookError(err)
+	}
+
+	return &authv1.CreateWebhookResponse{
+		Webhook: toWebhook(w),
+		Secret:  w.Secret,
+	}, nil
+}
+
+func (s *serverAPI) ListWebhooks(ctx context.Context, req *authv1.ListWebhooksRequest) (*authv1.ListWebhooksResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhooks.Webhooks(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ListWebhooksResponse{
+		Webhooks: make([]*authv1.Webhook, 0, len(webhooks)),
+	}
+	for _, w := range webhooks {
+		resp.Webhooks = append(resp.Webhooks, toWebhook(w))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) DeleteWebhook(ctx context.Context, req *authv1.DeleteWebhookRequest) (*authv1.DeleteWebhookResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookID, err := parseID(req.GetId(), "webhook id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.webhooks.Delete(ctx, webhookID); err != nil {
+		return nil, webhookError(err)
+	}
+
+	return &authv1.DeleteWebhookResponse{}, nil
+}
+
+func (s *serverAPI) ListWebhookDeliveries(ctx context.Context, req *authv1.ListWebhookDeliveriesRequest) (*authv1.ListWebhookDeliveriesResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := entity.DeliveryFilter{
+		Status: req.GetStatus(),
+	}
+
+	if req.GetWebhookId() != "" {
+		filter.WebhookID, err = parseID(req.GetWebhookId(), "webhook id")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	}
+
+	deliveries, err := s.webhooks.Deliveries(ctx, filter, int(req.GetPageSize()))
+	if err != nil {
+		return nil, webhookError(err)
+	}
+
+	resp := &authv1.ListWebhookDeliveriesResponse{
+		Deliveries: make([]*authv1.WebhookDelivery, 0, len(deliveries)),
+	}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, toWebhookDelivery(d))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) ReplayWebhookDelivery(ctx context.Context, req *authv1.ReplayWebhookDeliveryRequest) (*authv1.ReplayWebhookDeliveryResponse, error) {
+	ctx, _, err := s.authorizeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryID, err := parseID(req.GetId(), "delivery id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.webhooks.Replay(ctx, deliveryID); err != nil {
+		return nil, webhookError(err)
+	}
+
+	return &authv1.ReplayWebhookDeliveryResponse{}, nil
+}
+
+func webhookError(err error) error {
+	switch {
+	case errors.Is(err, webhook.ErrWebhookNotFound):
+		return status.Error(codes.NotFound, "webhook not found")
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		return status.Error(codes.NotFound, "webhook delivery not found")
+	case errors.Is(err, webhook.ErrInvalidURL):
+		return status.Error(codes.InvalidArgument, "url must be an absolute http or https url")
+	case errors.Is(err, webhook.ErrUnknownEventType):
+		return status.Error(codes.InvalidArgument, "unknown event type")
+	case errors.Is(err, webhook.ErrUnknownStatus):
+		return status.Error(codes.InvalidArgument, "unknown delivery status")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toWebhook(w *entity.Webhook) *authv1.Webhook {
+	return &authv1.Webhook{
+		Id:         w.ID.String(),
+		Url:        w.URL,
+		EventTypes: w.EventTypes,
+		CreatedAt:  timestamppb.New(w.CreatedAt),
+	}
+}
+
+func toWebhookDelivery(d *entity.WebhookDelivery) *authv1.WebhookDelivery {
+	resp := &authv1.WebhookDelivery{
+		Id:             d.ID.String(),
+		WebhookId:      d.WebhookID.String(),
+		EventId:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       int32(d.Attempts),
+		LastStatusCode: int32(d.LastStatusCode),
+		LastError:      d.LastError,
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+	}
+	if d.Status == entity.DeliveryPending {
+		resp.NextAttemptAt = timestamppb.New(d.NextAttemptAt)
+	}
+	if d.DeliveredAt != nil {
+		resp.DeliveredAt = timestamppb.New(*d.DeliveredAt)
+	}
+
+	return resp
+}
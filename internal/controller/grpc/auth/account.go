@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/controller/grpc/bearer"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) ChangeEmail(ctx context.Context, req *authv1.ChangeEmailRequest) (*authv1.ChangeEmailResponse, error) {
+	ctx, userID, err := s.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateEmail(req.GetEmail(), s.validate); err != nil {
+		return nil, err
+	}
+
+	if err := s.validate.Var(req.GetPassword(), "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	err = s.auth.ChangeEmail(ctx, userID, req.GetPassword(), req.GetEmail())
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return &authv1.ChangeEmailResponse{}, nil
+}
+
+func (s *serverAPI) ChangePhone(ctx context.Context, req *authv1.ChangePhoneRequest) (*authv1.ChangePhoneResponse, error) {
+	ctx, userID, err := s.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate.Var(req.GetPhone(), "omitempty,e164"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "phone must be in E.164 format")
+	}
+
+	if err := s.validate.Var(req.GetPassword(), "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	err = s.auth.ChangePhone(ctx, userID, req.GetPassword(), req.GetPhone())
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return &authv1.ChangePhoneResponse{}, nil
+}
+
+func (s *serverAPI) DeleteAccount(ctx context.Context, req *authv1.DeleteAccountRequest) (*authv1.DeleteAccountResponse, error) {
+	ctx, userID, err := s.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate.Var(req.GetPassword(), "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	err = s.auth.DeleteAccount(ctx, userID, req.GetPassword())
+	if err != nil {
+		return nil, accountError(err)
+	}
+
+	return &authv1.DeleteAccountResponse{}, nil
+}
+
+// authenticateUser returns the user signed in with a password.
+// API keys, service accounts and impersonation tokens can't change the account.
+func (s *serverAPI) authenticateUser(ctx context.Context) (context.Context, uuid.UUID, error) {
+	token, err := bearer.Token(ctx)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	info, err := s.apiKeys.Introspect(ctx, token, peerIP(ctx))
+	if err != nil {
+		return nil, uuid.Nil, introspectError(err)
+	}
+
+	if info.APIKeyID != uuid.Nil || info.ActorID != uuid.Nil {
+		return nil, uuid.Nil, status.Error(codes.PermissionDenied, "account can be changed only by its user")
+	}
+
+	return tenancy.WithTenant(ctx, info.TenantID), info.UserID, nil
+}
+
+func accountError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return status.Error(codes.InvalidArgument, "invalid credentials")
+	case errors.Is(err, auth.ErrUserExists):
+		return status.Error(codes.AlreadyExists, "user already exists")
+	case errors.Is(err, auth.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
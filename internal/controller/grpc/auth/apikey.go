@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/controller/grpc/bearer"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/apikey"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type APIKeys interface {
+	Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (key string, apiKey *entity.APIKey, err error)
+	Keys(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error)
+	Revoke(ctx context.Context, userID, keyID uuid.UUID) error
+	Exchange(ctx context.Context, key, ip string) (token string, err error)
+	Introspect(ctx context.Context, token, ip string) (*apikey.TokenInfo, error)
+	Validate(ctx context.Context, token, ip string) (*apikey.TokenInfo, error)
+}
+
+func (s *serverAPI) CreateAPIKey(ctx context.Context, req *authv1.CreateAPIKeyRequest) (*authv1.CreateAPIKeyResponse, error) {
+	ctx, userID, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateAPIKey(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if req.GetExpiresAt() != nil {
+		t := req.GetExpiresAt().AsTime()
+		expiresAt = &t
+	}
+
+	key, apiKey, err := s.apiKeys.Create(ctx, userID, req.GetName(), req.GetScopes(), expiresAt)
+	if err != nil {
+		if errors.Is(err, apikey.ErrExpiresAt) {
+			return nil, status.Error(codes.InvalidArgument, "expiry must be in the future")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.CreateAPIKeyResponse{
+		Key:    key,
+		ApiKey: toAPIKey(apiKey),
+	}, nil
+}
+
+func (s *serverAPI) ListAPIKeys(ctx context.Context, req *authv1.ListAPIKeysRequest) (*authv1.ListAPIKeysResponse, error) {
+	ctx, userID, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.apiKeys.Keys(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ListAPIKeysResponse{
+		ApiKeys: make([]*authv1.APIKey, 0, len(keys)),
+	}
+	for _, key := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, toAPIKey(key))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeAPIKey(ctx context.Context, req *authv1.RevokeAPIKeyRequest) (*authv1.RevokeAPIKeyResponse, error) {
+	ctx, userID, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "api key id must be a valid uuid")
+	}
+
+	if err := s.apiKeys.Revoke(ctx, userID, keyID); err != nil {
+		if errors.Is(err, apikey.ErrKeyNotFound) {
+			return nil, status.Error(codes.NotFound, "api key not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.RevokeAPIKeyResponse{}, nil
+}
+
+func (s *serverAPI) ExchangeAPIKey(ctx context.Context, req *authv1.ExchangeAPIKeyRequest) (*authv1.ExchangeAPIKeyResponse, error) {
+	if err := s.validate.Var(req.GetKey(), "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	token, err := s.apiKeys.Exchange(ctx, req.GetKey(), peerIP(ctx))
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.ExchangeAPIKeyResponse{
+		Token: token,
+	}, nil
+}
+
+func (s *serverAPI) ValidateToken(ctx context.Context, req *authv1.ValidateTokenRequest) (*authv1.ValidateTokenResponse, error) {
+	if err := s.validate.Var(req.GetToken(), "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	info, err := s.apiKeys.Validate(ctx, req.GetToken(), peerIP(ctx))
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ValidateTokenResponse{
+		UserId:   info.UserID.String(),
+		TenantId: info.TenantID.String(),
+		Scopes:   info.Scopes,
+		Roles:    info.Roles,
+	}
+	if !info.ExpiresAt.IsZero() {
+		resp.ExpiresAt = timestamppb.New(info.ExpiresAt)
+	}
+	if info.APIKeyID != uuid.Nil {
+		resp.ApiKeyId = info.APIKeyID.String()
+	}
+	resp.ServiceAccount = info.ServiceAccount
+	if info.ActorID != uuid.Nil {
+		resp.ActorId = info.ActorID.String()
+	}
+
+	return resp, nil
+}
+
+// authenticate returns id of the user who presented an access token
+// along with context scoped to the tenant of the user.
+// API keys and tokens exchanged for them can't manage API keys,
+// so a leaked key can't be used to mint new ones. Service accounts and OAuth clients
+// aren't the user and have no API keys. Impersonators can't mint keys outliving
+// their short-lived token.
+func (s *serverAPI) authenticate(ctx context.Context) (context.Context, uuid.UUID, error) {
+	token, err := bearer.Token(ctx)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	info, err := s.apiKeys.Introspect(ctx, token, peerIP(ctx))
+	if err != nil {
+		return nil, uuid.Nil, introspectError(err)
+	}
+
+	if info.APIKeyID != uuid.Nil {
+		return nil, uuid.Nil, status.Error(codes.PermissionDenied, "api keys can't be managed with an api key")
+	}
+
+	if info.ActorID != uuid.Nil {
+		return nil, uuid.Nil, status.Error(codes.PermissionDenied, "api keys can't be managed while impersonating")
+	}
+
+	return tenancy.WithTenant(ctx, info.TenantID), info.UserID, nil
+}
+
+// introspectError maps errors of APIKeys.Introspect to status errors.
+func introspectError(err error) error {
+	switch {
+	case errors.Is(err, apikey.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "invalid access token")
+	case errors.Is(err, apikey.ErrUserTokenRequired):
+		return status.Error(codes.PermissionDenied, "access token of the user is required")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// peerIP returns IP address of the client, or empty string if it is unknown.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+func toAPIKey(key *entity.APIKey) *authv1.APIKey {
+	resp := &authv1.APIKey{
+		Id:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     apikey.KeyPrefix + key.Prefix,
+		Scopes:     key.Scopes,
+		LastUsedIp: key.LastUsedIP,
+		CreatedAt:  timestamppb.New(key.CreatedAt),
+	}
+	if key.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(*key.ExpiresAt)
+	}
+	if key.LastUsedAt != nil {
+		resp.LastUsedAt = timestamppb.New(*key.LastUsedAt)
+	}
+
+	return resp
+}
+
+func validateCreateAPIKey(req *authv1.CreateAPIKeyRequest, validate *validator.Validate) error {
+	err := validate.Var(req.GetName(), "required,max=128")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	err = validate.Var(req.GetScopes(), "dive,required,excludesall= ")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "scopes must be non-empty and must not contain spaces")
+	}
+
+	return nil
+}
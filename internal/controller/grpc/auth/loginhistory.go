@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/controller/grpc/bearer"
+	"github.com/kurochkinivan/auth/internal/entity"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type LoginHistory interface {
+	Logins(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.Login, error)
+}
+
+func (s *serverAPI) ListLoginHistory(ctx context.Context, req *authv1.ListLoginHistoryRequest) (*authv1.ListLoginHistoryResponse, error) {
+	token, err := bearer.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.apiKeys.Introspect(ctx, token, peerIP(ctx))
+	if err != nil {
+		return nil, introspectError(err)
+	}
+
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	}
+
+	logins, err := s.loginHistory.Logins(ctx, info.UserID, int(req.GetPageSize()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &authv1.ListLoginHistoryResponse{
+		Logins: make([]*authv1.Login, 0, len(logins)),
+	}
+	for _, login := range logins {
+		resp.Logins = append(resp.Logins, &authv1.Login{
+			Ip:                login.IP,
+			UserAgent:         login.UserAgent,
+			DeviceFingerprint: login.DeviceFingerprint,
+			CreatedAt:         timestamppb.New(login.CreatedAt),
+		})
+	}
+
+	return resp, nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/invitation"
+	"github.com/kurochkinivan/auth/internal/usecase/otp"
 	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -15,19 +17,50 @@ import (
 
 type Auth interface {
 	Login(ctx context.Context, email, password string) (token string, err error)
-	RegisterNewUser(ctx context.Context, email, password string) (userID uuid.UUID, err error)
+	Register(ctx context.Context, email, password string) (userID uuid.UUID, err error)
+	ChangeEmail(ctx context.Context, userID uuid.UUID, password, email string) error
+	ChangePhone(ctx context.Context, userID uuid.UUID, password, phone string) error
+	DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error
+}
+
+type OTP interface {
+	SendCode(ctx context.Context, email, channel string) error
+	Verify(ctx context.Context, email, code string) (token string, err error)
+}
+
+type Invitations interface {
+	Accept(ctx context.Context, token, password string) (userID uuid.UUID, err error)
 }
 
 type serverAPI struct {
 	authv1.UnimplementedAuthServer
-	validate *validator.Validate
-	auth     Auth
+	validate        *validator.Validate
+	auth            Auth
+	otp             OTP
+	invitations     Invitations
+	apiKeys         APIKeys
+	loginHistory    LoginHistory
+	serviceAccounts ServiceAccounts
 }
 
-func Register(gRPC *grpc.Server, validate *validator.Validate, auth Auth) {
+func Register(
+	gRPC *grpc.Server,
+	validate *validator.Validate,
+	auth Auth,
+	otp OTP,
+	invitations Invitations,
+	apiKeys APIKeys,
+	loginHistory LoginHistory,
+	serviceAccounts ServiceAccounts,
+) {
 	authv1.RegisterAuthServer(gRPC, &serverAPI{
-		validate: validate,
-		auth:     auth,
+		validate:        validate,
+		auth:            auth,
+		otp:             otp,
+		invitations:     invitations,
+		apiKeys:         apiKeys,
+		loginHistory:    loginHistory,
+		serviceAccounts: serviceAccounts,
 	})
 }
 
@@ -38,8 +71,17 @@ func (s *serverAPI) Login(ctx context.Context, req *authv1.LoginRequest) (*authv
 
 	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword())
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
 			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+		case errors.Is(err, auth.ErrMFARequired):
+			// the password is valid, the login is completed with VerifyOTP
+			if err := s.otp.SendCode(ctx, req.GetEmail(), otp.ChannelEmail); err != nil {
+				return nil, status.Error(codes.Internal, "internal error")
+			}
+			return nil, status.Error(codes.FailedPrecondition, "login requires a one-time code, it was sent to the email, complete the login with VerifyOTP")
+		case errors.Is(err, auth.ErrLoginDenied):
+			return nil, status.Error(codes.PermissionDenied, "login denied")
 		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
@@ -54,7 +96,7 @@ func (s *serverAPI) Register(ctx context.Context, req *authv1.RegisterRequest) (
 		return nil, err
 	}
 
-	userID, err := s.auth.RegisterNewUser(ctx, req.GetEmail(), req.GetPassword())
+	userID, err := s.auth.Register(ctx, req.GetEmail(), req.GetPassword())
 	if err != nil {
 		if errors.Is(err, auth.ErrUserExists) {
 			return nil, status.Error(codes.AlreadyExists, "user already exists")
@@ -62,24 +104,129 @@ func (s *serverAPI) Register(ctx context.Context, req *authv1.RegisterRequest) (
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	return &authv1.RegisterResponse{
+	// hardened registration doesn't reveal the user id
+	resp := &authv1.RegisterResponse{}
+	if userID != uuid.Nil {
+		resp.UserId = userID.String()
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) SendOTP(ctx context.Context, req *authv1.SendOTPRequest) (*authv1.SendOTPResponse, error) {
+	if err := validateSendOTP(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	err := s.otp.SendCode(ctx, req.GetEmail(), req.GetChannel())
+	if err != nil {
+		if errors.Is(err, otp.ErrUnknownChannel) {
+			return nil, status.Error(codes.InvalidArgument, "unknown channel")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.SendOTPResponse{}, nil
+}
+
+func (s *serverAPI) VerifyOTP(ctx context.Context, req *authv1.VerifyOTPRequest) (*authv1.VerifyOTPResponse, error) {
+	if err := validateVerifyOTP(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	token, err := s.otp.Verify(ctx, req.GetEmail(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, otp.ErrInvalidCode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid code")
+		}
+		if errors.Is(err, auth.ErrLoginDenied) {
+			return nil, status.Error(codes.PermissionDenied, "login denied")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.VerifyOTPResponse{
+		Token: token,
+	}, nil
+}
+
+func (s *serverAPI) AcceptInvitation(ctx context.Context, req *authv1.AcceptInvitationRequest) (*authv1.AcceptInvitationResponse, error) {
+	if err := validateAcceptInvitation(req, s.validate); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.invitations.Accept(ctx, req.GetToken(), req.GetPassword())
+	if err != nil {
+		if errors.Is(err, invitation.ErrInvalidInvitation) {
+			return nil, status.Error(codes.InvalidArgument, "invitation is invalid or expired")
+		}
+		if errors.Is(err, invitation.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &authv1.AcceptInvitationResponse{
 		UserId: userID.String(),
 	}, nil
 }
 
 func validateLogin(req *authv1.LoginRequest, validate *validator.Validate) error {
-	err := validate.Var(req.GetEmail(), "required,email")
+	if err := validateEmail(req.GetEmail(), validate); err != nil {
+		return err
+	}
+
+	err := validate.Var(req.GetPassword(), "required")
 	if err != nil {
-		if validationErrs, ok := err.(validator.ValidationErrors); ok {
-			for _, e := range validationErrs {
-				if e.Tag() == "required" {
-					return status.Error(codes.InvalidArgument, "email is required")
-				}
-				if e.Tag() == "email" {
-					return status.Error(codes.InvalidArgument, "invalid email format")
-				}
-			}
-		}
+		return status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	return nil
+}
+
+func validateRegister(req *authv1.RegisterRequest, validate *validator.Validate) error {
+	if err := validateEmail(req.GetEmail(), validate); err != nil {
+		return err
+	}
+
+	err := validate.Var(req.GetPassword(), "required")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	return nil
+}
+
+func validateSendOTP(req *authv1.SendOTPRequest, validate *validator.Validate) error {
+	if err := validateEmail(req.GetEmail(), validate); err != nil {
+		return err
+	}
+
+	err := validate.Var(req.GetChannel(), "required")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "channel is required")
+	}
+
+	return nil
+}
+
+func validateVerifyOTP(req *authv1.VerifyOTPRequest, validate *validator.Validate) error {
+	if err := validateEmail(req.GetEmail(), validate); err != nil {
+		return err
+	}
+
+	err := validate.Var(req.GetCode(), "required,numeric,len=6")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "code must consist of 6 digits")
+	}
+
+	return nil
+}
+
+func validateAcceptInvitation(req *authv1.AcceptInvitationRequest, validate *validator.Validate) error {
+	err := validate.Var(req.GetToken(), "required")
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "token is required")
 	}
 
 	err = validate.Var(req.GetPassword(), "required")
@@ -90,8 +237,8 @@ func validateLogin(req *authv1.LoginRequest, validate *validator.Validate) error
 	return nil
 }
 
-func validateRegister(req *authv1.RegisterRequest, validate *validator.Validate) error {
-	err := validate.Var(req.GetEmail(), "required,email")
+func validateEmail(email string, validate *validator.Validate) error {
+	err := validate.Var(email, "required,email")
 	if err != nil {
 		if validationErrs, ok := err.(validator.ValidationErrors); ok {
 			for _, e := range validationErrs {
@@ -105,10 +252,5 @@ func validateRegister(req *authv1.RegisterRequest, validate *validator.Validate)
 		}
 	}
 
-	err = validate.Var(req.GetPassword(), "required")
-	if err != nil {
-		return status.Error(codes.InvalidArgument, "password is required")
-	}
-
 	return nil
 }
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/usecase/serviceaccount"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ServiceAccounts interface {
+	Token(ctx context.Context, clientID, clientSecret, assertion string, scopes []string) (token string, expiresIn time.Duration, err error)
+}
+
+func (s *serverAPI) ServiceAccountToken(ctx context.Context, req *authv1.ServiceAccountTokenRequest) (*authv1.ServiceAccountTokenResponse, error) {
+	if err := validateServiceAccountToken(req); err != nil {
+		return nil, err
+	}
+
+	token, expiresIn, err := s.serviceAccounts.Token(ctx, req.GetClientId(), req.GetClientSecret(), req.GetAssertion(), req.GetScopes())
+	if err != nil {
+		switch {
+		case errors.Is(err, serviceaccount.ErrInvalidClient):
+			return nil, status.Error(codes.Unauthenticated, "invalid client credentials")
+		case errors.Is(err, serviceaccount.ErrInvalidScope):
+			return nil, status.Error(codes.PermissionDenied, "requested scope is not allowed")
+		default:
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return &authv1.ServiceAccountTokenResponse{
+		Token:     token,
+		ExpiresIn: int64(expiresIn.Seconds()),
+	}, nil
+}
+
+func validateServiceAccountToken(req *authv1.ServiceAccountTokenRequest) error {
+	if req.GetClientId() == "" {
+		return status.Error(codes.InvalidArgument, "client id is required")
+	}
+
+	if (req.GetClientSecret() == "") == (req.GetAssertion() == "") {
+		return status.Error(codes.InvalidArgument, "exactly one of client secret and assertion is required")
+	}
+
+	return nil
+}
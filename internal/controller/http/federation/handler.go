@@ -0,0 +1,201 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/controller/http/gateway"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/oidcclient"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/federation"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	basePath    = "/federation/"
+	stateCookie = "federation_state"
+	stateTTL    = 10 * time.Minute
+)
+
+type Federation interface {
+	Login(ctx context.Context, provider, subject, email string, emailVerified bool) (token string, err error)
+}
+
+type Provider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidcclient.Claims, error)
+}
+
+type handler struct {
+	log        *slog.Logger
+	secret     string
+	federation Federation
+	providers  map[string]Provider
+}
+
+// Register registers login and callback endpoints for every provider:
+// /federation/{provider}/login and /federation/{provider}/callback.
+func Register(mux *http.ServeMux, log *slog.Logger, secret string, federation Federation, providers ...Provider) {
+	h := &handler{
+		log:        log,
+		secret:     secret,
+		federation: federation,
+		providers:  make(map[string]Provider, len(providers)),
+	}
+
+	for _, p := range providers {
+		h.providers[p.Name()] = p
+	}
+
+	mux.HandleFunc("GET "+basePath+"{provider}/login", h.login)
+	mux.HandleFunc("GET "+basePath+"{provider}/callback", h.callback)
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// login redirects the user to the provider. State, nonce and PKCE verifier are kept
+// in a signed cookie, so the callback can be checked without server-side sessions.
+func (h *handler) login(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		gateway.WriteError(w, status.New(codes.NotFound, "unknown provider"))
+		return
+	}
+
+	state, nonce := randomString(), randomString()
+	verifier := oauth2.GenerateVerifier()
+
+	cookie, err := jwt.Sign(map[string]any{
+		"provider": provider.Name(),
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		// the provider redirects back without tenant, so it is restored from the state
+		"tenant_id": tenancy.FromContext(r.Context()).String(),
+	}, h.secret, stateTTL)
+	if err != nil {
+		h.log.Error("failed to sign state", sl.Err(err))
+		gateway.WriteError(w, status.New(codes.Internal, "internal error"))
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		h.log.Error("failed to build authorization url", slog.String("provider", provider.Name()), sl.Err(err))
+		gateway.WriteError(w, status.New(codes.Unavailable, "provider is unavailable"))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    cookie,
+		Path:     basePath,
+		MaxAge:   int(stateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// callback exchanges the authorization code and logs in the user.
+func (h *handler) callback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		gateway.WriteError(w, status.New(codes.NotFound, "unknown provider"))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   stateCookie,
+		Path:   basePath,
+		MaxAge: -1,
+	})
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		gateway.WriteError(w, status.New(codes.Unauthenticated, "login was rejected by provider: "+query.Get("error")))
+		return
+	}
+
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil {
+		gateway.WriteError(w, status.New(codes.InvalidArgument, "login session not found"))
+		return
+	}
+
+	state, err := jwt.Parse(cookie.Value, h.secret)
+	if err != nil || state["provider"] != provider.Name() || state["state"] != query.Get("state") {
+		gateway.WriteError(w, status.New(codes.InvalidArgument, "invalid state"))
+		return
+	}
+
+	verifier, _ := state["verifier"].(string)
+	nonce, _ := state["nonce"].(string)
+
+	tenantClaim, _ := state["tenant_id"].(string)
+	tenantID, err := uuid.Parse(tenantClaim)
+	if err != nil {
+		gateway.WriteError(w, status.New(codes.InvalidArgument, "invalid state"))
+		return
+	}
+	ctx := tenancy.WithTenant(r.Context(), tenantID)
+
+	claims, err := provider.Exchange(ctx, query.Get("code"), verifier, nonce)
+	if err != nil {
+		h.log.Warn("failed to exchange code", slog.String("provider", provider.Name()), sl.Err(err))
+		gateway.WriteError(w, status.New(codes.Unauthenticated, "failed to authenticate with provider"))
+		return
+	}
+
+	token, err := h.federation.Login(ctx, provider.Name(), claims.Subject, claims.Email, claims.EmailVerified)
+	if err != nil {
+		switch {
+		case errors.Is(err, federation.ErrEmailRequired):
+			gateway.WriteError(w, status.New(codes.PermissionDenied, "provider didn't share email"))
+		case errors.Is(err, federation.ErrEmailNotVerified):
+			gateway.WriteError(w, status.New(codes.PermissionDenied, "provider didn't verify the email"))
+		case errors.Is(err, auth.ErrMFARequired):
+			gateway.WriteError(w, status.New(codes.PermissionDenied, "login requires a one-time code, it was sent to the email, complete the login with VerifyOTP"))
+		case errors.Is(err, auth.ErrLoginDenied):
+			gateway.WriteError(w, status.New(codes.PermissionDenied, "login denied"))
+		default:
+			gateway.WriteError(w, status.New(codes.Internal, "internal error"))
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, &tokenResponse{
+		Token: token,
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
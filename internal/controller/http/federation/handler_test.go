@@ -0,0 +1,250 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/oidcclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecret   = "test-secret"
+	testClientID = "test-client"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS and token endpoint
+// with PKCE check. Authorization is skipped, the test sends codes directly.
+type stubIdP struct {
+	*httptest.Server
+	key  *jwk.Key
+	user *entity.User
+
+	mu         sync.Mutex
+	challenges map[string]string
+	nonces     map[string]string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+
+	key, err := jwk.Generate()
+	require.NoError(t, err)
+
+	idp := &stubIdP{
+		key:        key,
+		user:       &entity.User{ID: uuid.New(), Email: "user@example.com"},
+		challenges: make(map[string]string),
+		nonces:     make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(idp.key.Set())
+	})
+	mux.HandleFunc("POST /token", idp.token)
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// authorize emulates the user approving the login at the provider.
+func (idp *stubIdP) authorize(authURL *url.URL, code string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	idp.challenges[code] = authURL.Query().Get("code_challenge")
+	idp.nonces[code] = authURL.Query().Get("nonce")
+}
+
+func (idp *stubIdP) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	code := r.PostForm.Get("code")
+
+	idp.mu.Lock()
+	challenge, nonce := idp.challenges[code], idp.nonces[code]
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if challenge == "" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, _ := jwt.NewIDToken(idp.user, idp.key, idp.URL, testClientID, time.Minute,
+		jwt.WithNonce(nonce),
+		jwt.WithClaims(map[string]any{
+			"email":          idp.user.Email,
+			"email_verified": true,
+		}),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+type federationMock struct {
+	provider, subject, email string
+	emailVerified            bool
+}
+
+func (f *federationMock) Login(ctx context.Context, provider, subject, email string, emailVerified bool) (string, error) {
+	f.provider, f.subject, f.email, f.emailVerified = provider, subject, email, emailVerified
+
+	return "local-token", nil
+}
+
+func setup(t *testing.T) (*stubIdP, *federationMock, *httptest.Server) {
+	t.Helper()
+
+	idp := newStubIdP(t)
+	fed := &federationMock{}
+
+	mux := http.NewServeMux()
+	Register(mux, slog.New(slog.NewTextHandler(io.Discard, nil)), testSecret, fed,
+		oidcclient.New(oidcclient.Config{
+			Name:        "stub",
+			Issuer:      idp.URL,
+			ClientID:    testClientID,
+			RedirectURL: "http://localhost/federation/stub/callback",
+		}),
+	)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return idp, fed, srv
+}
+
+func startLogin(t *testing.T, client *http.Client, srv *httptest.Server) (*url.URL, *http.Cookie) {
+	t.Helper()
+
+	resp, err := client.Get(srv.URL + "/federation/stub/login")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	authURL, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Len(t, resp.Cookies(), 1)
+
+	return authURL, resp.Cookies()[0]
+}
+
+func callback(t *testing.T, client *http.Client, srv *httptest.Server, cookie *http.Cookie, params url.Values) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/federation/stub/callback?"+params.Encode(), nil)
+	require.NoError(t, err)
+	req.AddCookie(cookie)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestFederation_HappyPath(t *testing.T) {
+	idp, fed, srv := setup(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	authURL, cookie := startLogin(t, client, srv)
+	assert.Equal(t, idp.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+
+	idp.authorize(authURL, "code")
+
+	resp := callback(t, client, srv, cookie, url.Values{
+		"code":  {"code"},
+		"state": {authURL.Query().Get("state")},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body tokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "local-token", body.Token)
+
+	assert.Equal(t, "stub", fed.provider)
+	assert.Equal(t, idp.user.ID.String(), fed.subject)
+	assert.Equal(t, idp.user.Email, fed.email)
+	assert.True(t, fed.emailVerified)
+}
+
+func TestFederation_FailCases(t *testing.T) {
+	idp, fed, srv := setup(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	t.Run("state mismatch", func(t *testing.T) {
+		authURL, cookie := startLogin(t, client, srv)
+		idp.authorize(authURL, "code-state")
+
+		resp := callback(t, client, srv, cookie, url.Values{
+			"code":  {"code-state"},
+			"state": {"forged"},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		authURL, cookie := startLogin(t, client, srv)
+
+		query := authURL.Query()
+		query.Set("nonce", "replayed")
+		authURL.RawQuery = query.Encode()
+		idp.authorize(authURL, "code-nonce")
+
+		resp := callback(t, client, srv, cookie, url.Values{
+			"code":  {"code-nonce"},
+			"state": {authURL.Query().Get("state")},
+		})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		resp, err := client.Get(srv.URL + "/federation/unknown/login")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "NotFound", body["code"], "errors have the shape of the gateway errors")
+	})
+
+	assert.Empty(t, fed.subject)
+}
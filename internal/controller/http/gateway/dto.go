@@ -0,0 +1,182 @@
+package gateway
+
+import (
+	"time"
+
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Request and response bodies of the endpoints. Fields tagged with path or query
+// are taken from the URL instead of the body.
+
+type empty struct{}
+
+type credentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type userIDResponse struct {
+	UserID string `json:"user_id"`
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+type sendOTPRequest struct {
+	Email   string `json:"email"`
+	Channel string `json:"channel"`
+}
+
+type verifyOTPRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+type acceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type createAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *apiKey `json:"api_key"`
+}
+
+type apiKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
+
+type listAPIKeysResponse struct {
+	APIKeys []*apiKey `json:"api_keys"`
+}
+
+type revokeAPIKeyRequest struct {
+	ID string `json:"-" path:"id"`
+}
+
+type exchangeAPIKeyRequest struct {
+	Key string `json:"key"`
+}
+
+type validateTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type validateTokenResponse struct {
+	UserID         string     `json:"user_id"`
+	TenantID       string     `json:"tenant_id"`
+	Scopes         []string   `json:"scopes"`
+	Roles          []string   `json:"roles"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	APIKeyID       string     `json:"api_key_id,omitempty"`
+	ServiceAccount bool       `json:"service_account"`
+	ActorID        string     `json:"actor_id,omitempty"`
+}
+
+type serviceAccountTokenRequest struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Assertion    string   `json:"assertion,omitempty"`
+	Scopes       []string `json:"scopes"`
+}
+
+type serviceAccountTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+type listLoginHistoryRequest struct {
+	PageSize int32 `json:"-" query:"page_size"`
+}
+
+type login struct {
+	IP                string     `json:"ip"`
+	UserAgent         string     `json:"user_agent"`
+	DeviceFingerprint string     `json:"device_fingerprint"`
+	CreatedAt         *time.Time `json:"created_at"`
+}
+
+type listLoginHistoryResponse struct {
+	Logins []*login `json:"logins"`
+}
+
+type changeEmailRequest struct {
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type changePhoneRequest struct {
+	Password string `json:"password"`
+	Phone    string `json:"phone"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type errorResponse struct {
+	// Code is name of gRPC status code, e.g. InvalidArgument.
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func toAPIKey(key *authv1.APIKey) *apiKey {
+	if key == nil {
+		return nil
+	}
+
+	return &apiKey{
+		ID:         key.GetId(),
+		Name:       key.GetName(),
+		Prefix:     key.GetPrefix(),
+		Scopes:     key.GetScopes(),
+		ExpiresAt:  toTime(key.GetExpiresAt()),
+		LastUsedAt: toTime(key.GetLastUsedAt()),
+		LastUsedIP: key.GetLastUsedIp(),
+		CreatedAt:  toTime(key.GetCreatedAt()),
+	}
+}
+
+func toLogin(l *authv1.Login) *login {
+	return &login{
+		IP:                l.GetIp(),
+		UserAgent:         l.GetUserAgent(),
+		DeviceFingerprint: l.GetDeviceFingerprint(),
+		CreatedAt:         toTime(l.GetCreatedAt()),
+	}
+}
+
+func toTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+
+	t := ts.AsTime()
+
+	return &t
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,435 @@
+// Package gateway serves the Auth gRPC API as HTTP/JSON endpoints under /v1.
+// Requests are forwarded to the gRPC server, so they pass the same interceptors,
+// validation and error mapping as native gRPC calls.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/requestid"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	Prefix      = "/v1/"
+	openAPIPath = "/v1/openapi.json"
+
+	// maxBodySize limits request bodies, the largest requests carry a few strings.
+	maxBodySize = 1 << 20
+)
+
+type handler struct {
+	log    *slog.Logger
+	client authv1.AuthClient
+}
+
+// route is an endpoint forwarded to an RPC.
+type route struct {
+	method  string
+	path    string
+	summary string
+	// bearer is set if the RPC requires access token in Authorization header.
+	bearer bool
+	// request and response are zero bodies of the endpoint, they describe it in the OpenAPI document.
+	request  any
+	response any
+	serve    func(h *handler, w http.ResponseWriter, r *http.Request)
+}
+
+// endpoint returns route decoding request body and URL parameters into Req,
+// calling the RPC and encoding its result.
+func endpoint[Req, Resp any](
+	method, path, summary string,
+	bearer bool,
+	call func(ctx context.Context, client authv1.AuthClient, req *Req, opts ...grpc.CallOption) (*Resp, error),
+) route {
+	return route{
+		method:   method,
+		path:     path,
+		summary:  summary,
+		bearer:   bearer,
+		request:  new(Req),
+		response: new(Resp),
+		serve: func(h *handler, w http.ResponseWriter, r *http.Request) {
+			req := new(Req)
+			if err := decode(r, req); err != nil {
+				h.writeError(w, r, status.New(codes.InvalidArgument, err.Error()))
+				return
+			}
+
+			var header, trailer metadata.MD
+			resp, err := call(outgoingContext(r), h.client, req, grpc.Header(&header), grpc.Trailer(&trailer))
+
+			forwardMetadata(w, header)
+			forwardMetadata(w, trailer)
+
+			if err != nil {
+				h.writeError(w, r, status.Convert(err))
+				return
+			}
+
+			h.writeJSON(w, http.StatusOK, resp)
+		},
+	}
+}
+
+var routes = []route{
+	endpoint("POST", "/v1/register", "Register new user", false,
+		func(ctx context.Context, c authv1.AuthClient, req *credentialsRequest, opts ...grpc.CallOption) (*userIDResponse, error) {
+			resp, err := c.Register(ctx, &authv1.RegisterRequest{Email: req.Email, Password: req.Password}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &userIDResponse{UserID: resp.GetUserId()}, nil
+		}),
+	endpoint("POST", "/v1/login", "Log in with email and password", false,
+		func(ctx context.Context, c authv1.AuthClient, req *credentialsRequest, opts ...grpc.CallOption) (*tokenResponse, error) {
+			resp, err := c.Login(ctx, &authv1.LoginRequest{Email: req.Email, Password: req.Password}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &tokenResponse{Token: resp.GetToken()}, nil
+		}),
+	endpoint("POST", "/v1/otp/send", "Send one-time code", false,
+		func(ctx context.Context, c authv1.AuthClient, req *sendOTPRequest, opts ...grpc.CallOption) (*empty, error) {
+			_, err := c.SendOTP(ctx, &authv1.SendOTPRequest{Email: req.Email, Channel: req.Channel}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &empty{}, nil
+		}),
+	endpoint("POST", "/v1/otp/verify", "Log in with one-time code", false,
+		func(ctx context.Context, c authv1.AuthClient, req *verifyOTPRequest, opts ...grpc.CallOption) (*tokenResponse, error) {
+			resp, err := c.VerifyOTP(ctx, &authv1.VerifyOTPRequest{Email: req.Email, Code: req.Code}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &tokenResponse{Token: resp.GetToken()}, nil
+		}),
+	endpoint("POST", "/v1/invitations/accept", "Register by invitation", false,
+		func(ctx context.Context, c authv1.AuthClient, req *acceptInvitationRequest, opts ...grpc.CallOption) (*userIDResponse, error) {
+			resp, err := c.AcceptInvitation(ctx, &authv1.AcceptInvitationRequest{Token: req.Token, Password: req.Password}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &userIDResponse{UserID: resp.GetUserId()}, nil
+		}),
+	endpoint("POST", "/v1/api-keys", "Create API key", true,
+		func(ctx context.Context, c authv1.AuthClient, req *createAPIKeyRequest, opts ...grpc.CallOption) (*createAPIKeyResponse, error) {
+			resp, err := c.CreateAPIKey(ctx, &authv1.CreateAPIKeyRequest{
+				Name:      req.Name,
+				Scopes:    req.Scopes,
+				ExpiresAt: toTimestamp(req.ExpiresAt),
+			}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &createAPIKeyResponse{Key: resp.GetKey(), APIKey: toAPIKey(resp.GetApiKey())}, nil
+		}),
+	endpoint("GET", "/v1/api-keys", "List API keys", true,
+		func(ctx context.Context, c authv1.AuthClient, _ *empty, opts ...grpc.CallOption) (*listAPIKeysResponse, error) {
+			resp, err := c.ListAPIKeys(ctx, &authv1.ListAPIKeysRequest{}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			keys := make([]*apiKey, 0, len(resp.GetApiKeys()))
+			for _, key := range resp.GetApiKeys() {
+				keys = append(keys, toAPIKey(key))
+			}
+			return &listAPIKeysResponse{APIKeys: keys}, nil
+		}),
+	endpoint("DELETE", "/v1/api-keys/{id}", "Revoke API key", true,
+		func(ctx context.Context, c authv1.AuthClient, req *revokeAPIKeyRequest, opts ...grpc.CallOption) (*empty, error) {
+			_, err := c.RevokeAPIKey(ctx, &authv1.RevokeAPIKeyRequest{Id: req.ID}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &empty{}, nil
+		}),
+	endpoint("POST", "/v1/api-keys/exchange", "Exchange API key for access token", false,
+		func(ctx context.Context, c authv1.AuthClient, req *exchangeAPIKeyRequest, opts ...grpc.CallOption) (*tokenResponse, error) {
+			resp, err := c.ExchangeAPIKey(ctx, &authv1.ExchangeAPIKeyRequest{Key: req.Key}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &tokenResponse{Token: resp.GetToken()}, nil
+		}),
+	endpoint("POST", "/v1/tokens/validate", "Validate access token", false,
+		func(ctx context.Context, c authv1.AuthClient, req *validateTokenRequest, opts ...grpc.CallOption) (*validateTokenResponse, error) {
+			resp, err := c.ValidateToken(ctx, &authv1.ValidateTokenRequest{Token: req.Token}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &validateTokenResponse{
+				UserID:         resp.GetUserId(),
+				TenantID:       resp.GetTenantId(),
+				Scopes:         resp.GetScopes(),
+				Roles:          resp.GetRoles(),
+				ExpiresAt:      toTime(resp.GetExpiresAt()),
+				APIKeyID:       resp.GetApiKeyId(),
+				ServiceAccount: resp.GetServiceAccount(),
+				ActorID:        resp.GetActorId(),
+			}, nil
+		}),
+	endpoint("POST", "/v1/service-accounts/token", "Issue service account token", false,
+		func(ctx context.Context, c authv1.AuthClient, req *serviceAccountTokenRequest, opts ...grpc.CallOption) (*serviceAccountTokenResponse, error) {
+			resp, err := c.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+				ClientId:     req.ClientID,
+				ClientSecret: req.ClientSecret,
+				Assertion:    req.Assertion,
+				Scopes:       req.Scopes,
+			}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &serviceAccountTokenResponse{Token: resp.GetToken(), ExpiresIn: resp.GetExpiresIn()}, nil
+		}),
+	endpoint("GET", "/v1/login-history", "List latest logins of the user", true,
+		func(ctx context.Context, c authv1.AuthClient, req *listLoginHistoryRequest, opts ...grpc.CallOption) (*listLoginHistoryResponse, error) {
+			resp, err := c.ListLoginHistory(ctx, &authv1.ListLoginHistoryRequest{PageSize: req.PageSize}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			logins := make([]*login, 0, len(resp.GetLogins()))
+			for _, l := range resp.GetLogins() {
+				logins = append(logins, toLogin(l))
+			}
+			return &listLoginHistoryResponse{Logins: logins}, nil
+		}),
+	endpoint("POST", "/v1/account/email", "Change email of the user", true,
+		func(ctx context.Context, c authv1.AuthClient, req *changeEmailRequest, opts ...grpc.CallOption) (*empty, error) {
+			_, err := c.ChangeEmail(ctx, &authv1.ChangeEmailRequest{Password: req.Password, Email: req.Email}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &empty{}, nil
+		}),
+	endpoint("POST", "/v1/account/phone", "Change phone of the user, empty phone removes it", true,
+		func(ctx context.Context, c authv1.AuthClient, req *changePhoneRequest, opts ...grpc.CallOption) (*empty, error) {
+			_, err := c.ChangePhone(ctx, &authv1.ChangePhoneRequest{Password: req.Password, Phone: req.Phone}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &empty{}, nil
+		}),
+	endpoint("DELETE", "/v1/account", "Delete account of the user", true,
+		func(ctx context.Context, c authv1.AuthClient, req *deleteAccountRequest, opts ...grpc.CallOption) (*empty, error) {
+			_, err := c.DeleteAccount(ctx, &authv1.DeleteAccountRequest{Password: req.Password}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &empty{}, nil
+		}),
+}
+
+// Register adds the endpoints and the OpenAPI document describing them to the mux.
+func Register(mux *http.ServeMux, log *slog.Logger, client authv1.AuthClient) {
+	h := &handler{
+		log:    log,
+		client: client,
+	}
+
+	for _, rt := range routes {
+		mux.HandleFunc(rt.method+" "+rt.path, func(w http.ResponseWriter, r *http.Request) {
+			rt.serve(h, w, r)
+		})
+	}
+
+	document, err := json.Marshal(OpenAPI(routes))
+	if err != nil {
+		// the document is built from static definitions
+		panic("gateway.Register: " + err.Error())
+	}
+
+	mux.HandleFunc("GET "+openAPIPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	})
+}
+
+// decode fills fields of the request from JSON body and from path and query parameters.
+func decode(r *http.Request, req any) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return errors.New("failed to read body")
+	}
+
+	if len(body) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(req); err != nil {
+			return errors.New("invalid json body")
+		}
+	}
+
+	v := reflect.ValueOf(req).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+
+		var value string
+		if name, ok := field.Tag.Lookup("path"); ok {
+			value = r.PathValue(name)
+		} else if name, ok := field.Tag.Lookup("query"); ok {
+			value = r.URL.Query().Get(name)
+		} else {
+			continue
+		}
+
+		if err := setParam(v.Field(i), value); err != nil {
+			return errors.New("invalid parameter " + field.Name)
+		}
+	}
+
+	return nil
+}
+
+func setParam(field reflect.Value, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	default:
+		return errors.New("unsupported parameter type")
+	}
+
+	return nil
+}
+
+// forwardedHeaders are passed to the gRPC server as metadata,
+// x- headers are passed as well, except x-forwarded- ones set by the gateway.
+var forwardedHeaders = []string{"authorization"}
+
+// outgoingContext returns request context with metadata made of the headers
+// and of the client the request came from.
+func outgoingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+
+		forwarded := strings.HasPrefix(name, "x-") || slices.Contains(forwardedHeaders, name)
+		if !forwarded || strings.HasPrefix(name, "x-forwarded-") {
+			continue
+		}
+
+		md.Append(name, values...)
+	}
+
+	client := clientinfo.FromContext(r.Context())
+	md.Set(clientinfo.ForwardedForKey, client.IP)
+	md.Set(clientinfo.ForwardedUserAgentKey, client.UserAgent)
+
+	return metadata.NewOutgoingContext(r.Context(), md)
+}
+
+// forwardMetadata sets x- metadata of the response, e.g. request id, as headers.
+func forwardMetadata(w http.ResponseWriter, md metadata.MD) {
+	for name, values := range md {
+		if !strings.HasPrefix(name, "x-") {
+			continue
+		}
+
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+}
+
+func (h *handler) writeError(w http.ResponseWriter, r *http.Request, st *status.Status) {
+	if code := st.Code(); code == codes.Internal || code == codes.Unknown || code == codes.Unavailable {
+		sl.FromContext(r.Context(), h.log).Error("gateway call failed",
+			slog.String("path", r.URL.Path),
+			slog.String("code", code.String()),
+			slog.String("message", st.Message()),
+		)
+	}
+
+	WriteError(w, st)
+}
+
+// WriteError writes the status as JSON error of the gateway with the matching HTTP status code.
+// Other HTTP handlers use it too, so clients of the HTTP API parse one shape of errors.
+func WriteError(w http.ResponseWriter, st *status.Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatus(st.Code()))
+
+	_ = json.NewEncoder(w).Encode(&errorResponse{
+		Code:      st.Code().String(),
+		Message:   st.Message(),
+		RequestID: first(w.Header().Values(requestid.MetadataKey)),
+	})
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.log.Error("failed to write response", sl.Err(err))
+	}
+}
+
+// HTTPStatus returns HTTP status code corresponding to gRPC status code.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// nginx's "client closed request"
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
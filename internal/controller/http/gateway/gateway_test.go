@@ -0,0 +1,205 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubClient answers the RPCs used by the tests and remembers metadata of the last call.
+type stubClient struct {
+	authv1.AuthClient
+	md metadata.MD
+}
+
+func (c *stubClient) Login(ctx context.Context, req *authv1.LoginRequest, opts ...grpc.CallOption) (*authv1.LoginResponse, error) {
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	setHeader(opts, metadata.Pairs("x-request-id", "req-1"))
+
+	if req.GetPassword() != "secret" {
+		return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+	}
+
+	return &authv1.LoginResponse{Token: "token-of-" + req.GetEmail()}, nil
+}
+
+func (c *stubClient) RevokeAPIKey(ctx context.Context, req *authv1.RevokeAPIKeyRequest, _ ...grpc.CallOption) (*authv1.RevokeAPIKeyResponse, error) {
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+
+	if req.GetId() != "key-1" {
+		return nil, status.Error(codes.NotFound, "api key not found")
+	}
+
+	return &authv1.RevokeAPIKeyResponse{}, nil
+}
+
+func (c *stubClient) ListLoginHistory(_ context.Context, req *authv1.ListLoginHistoryRequest, _ ...grpc.CallOption) (*authv1.ListLoginHistoryResponse, error) {
+	logins := make([]*authv1.Login, req.GetPageSize())
+	for i := range logins {
+		logins[i] = &authv1.Login{Ip: "192.0.2.1"}
+	}
+
+	return &authv1.ListLoginHistoryResponse{Logins: logins}, nil
+}
+
+// setHeader fills header requested by grpc.Header call option, as the real client does.
+func setHeader(opts []grpc.CallOption, md metadata.MD) {
+	for _, opt := range opts {
+		if header, ok := opt.(grpc.HeaderCallOption); ok {
+			*header.HeaderAddr = md
+		}
+	}
+}
+
+func newServer(t *testing.T) (*httptest.Server, *stubClient) {
+	t.Helper()
+
+	client := new(stubClient)
+	mux := http.NewServeMux()
+	Register(mux, slog.New(slog.NewTextHandler(io.Discard, nil)), client)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := clientinfo.WithInfo(r.Context(), clientinfo.Info{IP: "198.51.100.7", UserAgent: r.UserAgent()})
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server, client
+}
+
+func do(t *testing.T, req *http.Request) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return resp, body
+}
+
+func TestLogin(t *testing.T) {
+	server, client := newServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/login", strings.NewReader(`{"email":"user@example.com","password":"secret"}`))
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "browser")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Forwarded-For", "203.0.113.66")
+	req.Header.Set("Cookie", "session=1")
+
+	resp, body := do(t, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "token-of-user@example.com", body["token"])
+	assert.Equal(t, "req-1", resp.Header.Get("X-Request-Id"))
+
+	assert.Equal(t, []string{"acme"}, client.md.Get("x-tenant"))
+	assert.Equal(t, []string{"198.51.100.7"}, client.md.Get(clientinfo.ForwardedForKey), "clients can't pass their own address")
+	assert.Equal(t, []string{"browser"}, client.md.Get(clientinfo.ForwardedUserAgentKey))
+	assert.Empty(t, client.md.Get("cookie"))
+}
+
+func TestErrors(t *testing.T) {
+	server, _ := newServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"rpc error", http.MethodPost, "/v1/login", `{"email":"user@example.com","password":"wrong"}`, http.StatusBadRequest, "InvalidArgument"},
+		{"malformed body", http.MethodPost, "/v1/login", `{"email":`, http.StatusBadRequest, "InvalidArgument"},
+		{"unknown field", http.MethodPost, "/v1/login", `{"login":"user"}`, http.StatusBadRequest, "InvalidArgument"},
+		{"not found", http.MethodDelete, "/v1/api-keys/key-2", "", http.StatusNotFound, "NotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+
+			resp, body := do(t, req)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			assert.Equal(t, tt.code, body["code"])
+			assert.NotEmpty(t, body["message"])
+		})
+	}
+}
+
+func TestParameters(t *testing.T) {
+	server, client := newServer(t)
+
+	req, err := http.NewRequest(http.MethodDelete, server.URL+"/v1/api-keys/key-1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+
+	resp, _ := do(t, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Bearer token"}, client.md.Get("authorization"))
+
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/v1/login-history?page_size=3", nil)
+	require.NoError(t, err)
+
+	resp, body := do(t, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, body["logins"], 3)
+
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/v1/login-history?page_size=many", nil)
+	require.NoError(t, err)
+
+	resp, _ = do(t, req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOpenAPI(t *testing.T) {
+	server, _ := newServer(t)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/openapi.json", nil)
+	require.NoError(t, err)
+
+	resp, document := do(t, req)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	paths := document["paths"].(map[string]any)
+	assert.Len(t, paths, 14)
+
+	login := paths["/v1/login"].(map[string]any)["post"].(map[string]any)
+	schema := login["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	assert.Contains(t, schema["properties"], "email")
+	assert.Contains(t, schema["properties"], "password")
+	assert.NotContains(t, login, "security")
+
+	revoke := paths["/v1/api-keys/{id}"].(map[string]any)["delete"].(map[string]any)
+	assert.NotContains(t, revoke, "requestBody")
+	assert.Contains(t, revoke, "security")
+	assert.Equal(t, "id", revoke["parameters"].([]any)[0].(map[string]any)["name"])
+}
+
+func TestHTTPStatus(t *testing.T) {
+	assert.Equal(t, http.StatusUnauthorized, HTTPStatus(codes.Unauthenticated))
+	assert.Equal(t, http.StatusTooManyRequests, HTTPStatus(codes.ResourceExhausted))
+	assert.Equal(t, http.StatusConflict, HTTPStatus(codes.AlreadyExists))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(codes.DataLoss))
+}
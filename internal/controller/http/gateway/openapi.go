@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// OpenAPI returns OpenAPI 3 document describing the routes, schemas are derived from their bodies.
+func OpenAPI(routes []route) map[string]any {
+	paths := map[string]map[string]any{}
+
+	for _, rt := range routes {
+		operation := map[string]any{
+			"summary":     rt.summary,
+			"operationId": operationID(rt),
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     jsonContent(schemaOf(reflect.TypeOf(rt.response))),
+				},
+				"default": map[string]any{
+					"description": "Error, code is name of gRPC status code",
+					"content":     jsonContent(map[string]any{"$ref": "#/components/schemas/Error"}),
+				},
+			},
+		}
+
+		if parameters := parametersOf(reflect.TypeOf(rt.request).Elem()); len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if body := schemaOf(reflect.TypeOf(rt.request)); len(body["properties"].(map[string]any)) > 0 {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(body),
+			}
+		}
+
+		if rt.bearer {
+			operation["security"] = []map[string][]string{{"bearer": {}}}
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]any{}
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Auth API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Error": schemaOf(reflect.TypeOf(errorResponse{})),
+			},
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+			},
+		},
+	}
+}
+
+// operationID returns id of the operation made of its method and path, e.g. "post_v1_api_keys".
+func operationID(rt route) string {
+	id := strings.ToLower(rt.method) + rt.path
+	id = strings.NewReplacer("/", "_", "-", "_", "{", "", "}", "").Replace(id)
+
+	return id
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns JSON schema of values of the type as encoding/json encodes them.
+func schemaOf(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := range t.NumField() {
+			field := t.Field(i)
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = schemaOf(field.Type)
+		}
+
+		return map[string]any{"type": "object", "properties": properties}
+	default:
+		return map[string]any{}
+	}
+}
+
+// parametersOf returns path and query parameters of the request type.
+func parametersOf(t reflect.Type) []map[string]any {
+	var parameters []map[string]any
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if name, ok := field.Tag.Lookup("path"); ok {
+			parameters = append(parameters, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   schemaOf(field.Type),
+			})
+		}
+
+		if name, ok := field.Tag.Lookup("query"); ok {
+			parameters = append(parameters, map[string]any{
+				"name":   name,
+				"in":     "query",
+				"schema": schemaOf(field.Type),
+			})
+		}
+	}
+
+	return parameters
+}
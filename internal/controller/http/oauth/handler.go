@@ -0,0 +1,310 @@
+package oauth
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/oauth"
+)
+
+const (
+	authorizePath = "/oauth/authorize"
+	tokenPath     = "/oauth/token"
+	userInfoPath  = "/oauth/userinfo"
+	jwksPath      = "/.well-known/jwks.json"
+	discoveryPath = "/.well-known/openid-configuration"
+)
+
+//go:embed consent.html
+var consentHTML string
+
+var consentTemplate = template.Must(template.New("consent").Parse(consentHTML))
+
+type OAuth interface {
+	Authorize(ctx context.Context, req *oauth.AuthorizationRequest) (*oauth.Authorization, error)
+	Approve(ctx context.Context, authz *oauth.Authorization, email, password string) (code string, err error)
+	Exchange(ctx context.Context, req *oauth.TokenRequest) (*oauth.Token, error)
+	UserInfo(ctx context.Context, accessToken string) (map[string]any, error)
+	Issuer() string
+	KeySet() jwk.JSONWebKeySet
+}
+
+type handler struct {
+	log   *slog.Logger
+	oauth OAuth
+}
+
+func Register(mux *http.ServeMux, log *slog.Logger, oauth OAuth) {
+	h := &handler{
+		log:   log,
+		oauth: oauth,
+	}
+
+	mux.HandleFunc("GET "+authorizePath, h.authorize)
+	mux.HandleFunc("POST "+authorizePath, h.consent)
+	mux.HandleFunc("POST "+tokenPath, h.token)
+	mux.HandleFunc("GET "+userInfoPath, h.userInfo)
+	mux.HandleFunc("POST "+userInfoPath, h.userInfo)
+	mux.HandleFunc("GET "+jwksPath, h.jwks)
+	mux.HandleFunc("GET "+discoveryPath, h.discovery)
+}
+
+type consentPage struct {
+	ClientName string
+	Scopes     []string
+	Action     string
+	Params     map[string]string
+	Email      string
+	Error      string
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// authorize validates authorization request and renders the consent page.
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request) {
+	req := authorizationRequest(r.URL.Query())
+
+	authz, err := h.oauth.Authorize(r.Context(), req)
+	if err != nil {
+		h.authorizationError(w, r, authz, err)
+		return
+	}
+
+	h.renderConsent(w, http.StatusOK, authz, req, "", "")
+}
+
+// consent handles the consent form: authenticates the user and redirects back to the client.
+func (h *handler) consent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	req := authorizationRequest(r.PostForm)
+
+	authz, err := h.oauth.Authorize(r.Context(), req)
+	if err != nil {
+		h.authorizationError(w, r, authz, err)
+		return
+	}
+
+	if r.PostForm.Get("action") != "approve" {
+		redirect(w, r, authz, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	email := r.PostForm.Get("email")
+
+	code, err := h.oauth.Approve(r.Context(), authz, email, r.PostForm.Get("password"))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			h.renderConsent(w, http.StatusUnauthorized, authz, req, email, "Invalid email or password")
+			return
+		case errors.Is(err, auth.ErrMFARequired):
+			// a login with one-time passcode from this device makes it known to the risk engine
+			h.renderConsent(w, http.StatusForbidden, authz, req, email, "Sign in with a one-time code first, then approve again")
+			return
+		case errors.Is(err, auth.ErrLoginDenied):
+			redirect(w, r, authz, url.Values{"error": {"access_denied"}})
+			return
+		}
+
+		h.log.Error("failed to approve authorization", sl.Err(err))
+		redirect(w, r, authz, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	redirect(w, r, authz, url.Values{"code": {code}})
+}
+
+// token handles the token endpoint, client may authenticate with HTTP Basic or form parameters.
+func (h *handler) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid form")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	token, err := h.oauth.Exchange(r.Context(), &oauth.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+	})
+	if err != nil {
+		code, description := errorCode(err)
+		switch code {
+		case "invalid_client":
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			writeError(w, http.StatusUnauthorized, code, description)
+		case "server_error":
+			h.log.Error("failed to exchange token", sl.Err(err))
+			writeError(w, http.StatusInternalServerError, code, description)
+		default:
+			writeError(w, http.StatusBadRequest, code, description)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, &tokenResponse{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		ExpiresIn:    int64(token.ExpiresIn.Seconds()),
+		RefreshToken: token.RefreshToken,
+		IDToken:      token.IDToken,
+		Scope:        strings.Join(token.Scopes, " "),
+	})
+}
+
+func (h *handler) authorizationError(w http.ResponseWriter, r *http.Request, authz *oauth.Authorization, err error) {
+	if authz == nil {
+		if errors.Is(err, oauth.ErrInvalidClient) || errors.Is(err, oauth.ErrInvalidRedirectURI) {
+			http.Error(w, "invalid client or redirect uri", http.StatusBadRequest)
+			return
+		}
+
+		h.log.Error("failed to authorize", sl.Err(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	code, description := errorCode(err)
+	redirect(w, r, authz, url.Values{
+		"error":             {code},
+		"error_description": {description},
+	})
+}
+
+func (h *handler) renderConsent(w http.ResponseWriter, status int, authz *oauth.Authorization, req *oauth.AuthorizationRequest, email, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	err := consentTemplate.Execute(w, &consentPage{
+		ClientName: authz.Client.Name,
+		Scopes:     authz.Scopes,
+		Action:     authorizePath,
+		Params: map[string]string{
+			"response_type":         req.ResponseType,
+			"client_id":             req.ClientID,
+			"redirect_uri":          req.RedirectURI,
+			"scope":                 req.Scope,
+			"state":                 req.State,
+			"code_challenge":        req.CodeChallenge,
+			"code_challenge_method": req.CodeChallengeMethod,
+			"nonce":                 req.Nonce,
+		},
+		Email: email,
+		Error: errMsg,
+	})
+	if err != nil {
+		h.log.Error("failed to render consent page", sl.Err(err))
+	}
+}
+
+func authorizationRequest(params url.Values) *oauth.AuthorizationRequest {
+	return &oauth.AuthorizationRequest{
+		ResponseType:        params.Get("response_type"),
+		ClientID:            params.Get("client_id"),
+		RedirectURI:         params.Get("redirect_uri"),
+		Scope:               params.Get("scope"),
+		State:               params.Get("state"),
+		CodeChallenge:       params.Get("code_challenge"),
+		CodeChallengeMethod: params.Get("code_challenge_method"),
+		Nonce:               params.Get("nonce"),
+	}
+}
+
+// redirect sends the user back to the validated redirect URI of the client.
+func redirect(w http.ResponseWriter, r *http.Request, authz *oauth.Authorization, params url.Values) {
+	u, err := url.Parse(authz.RedirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	if authz.State != "" {
+		params.Set("state", authz.State)
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// errorCode maps service error to the error code defined in RFC 6749.
+func errorCode(err error) (code, description string) {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidRequest):
+		return "invalid_request", "request is missing or has invalid parameter"
+	case errors.Is(err, oauth.ErrInvalidClient):
+		return "invalid_client", "client authentication failed"
+	case errors.Is(err, oauth.ErrInvalidGrant):
+		return "invalid_grant", "grant is invalid, expired or revoked"
+	case errors.Is(err, oauth.ErrInvalidScope):
+		return "invalid_scope", "requested scope is not allowed"
+	case errors.Is(err, oauth.ErrUnauthorizedClient):
+		return "unauthorized_client", "client is not allowed to use this grant"
+	case errors.Is(err, oauth.ErrUnsupportedGrantType):
+		return "unsupported_grant_type", "grant type is not supported"
+	case errors.Is(err, oauth.ErrUnsupportedResponseType):
+		return "unsupported_response_type", "response type is not supported"
+	default:
+		return "server_error", "internal error"
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, &errorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,94 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/oauth"
+)
+
+// providerMetadata is OpenID Provider metadata (OpenID Connect Discovery 1.0, section 3).
+type providerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func (h *handler) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := h.oauth.Issuer()
+
+	writeJSON(w, http.StatusOK, &providerMetadata{
+		Issuer:                issuer,
+		AuthorizationEndpoint: issuer + authorizePath,
+		TokenEndpoint:         issuer + tokenPath,
+		UserInfoEndpoint:      issuer + userInfoPath,
+		JWKSURI:               issuer + jwksPath,
+		ScopesSupported: []string{
+			oauth.ScopeOpenID,
+			oauth.ScopeEmail,
+			oauth.ScopeProfile,
+			oauth.ScopeOfflineAccess,
+		},
+		ResponseTypesSupported: []string{oauth.ResponseTypeCode},
+		GrantTypesSupported: []string{
+			entity.GrantTypeAuthorizationCode,
+			entity.GrantTypeRefreshToken,
+			entity.GrantTypeClientCredentials,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oauth.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash",
+			"email", "email_verified", "updated_at",
+		},
+	})
+}
+
+func (h *handler) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, http.StatusOK, h.oauth.KeySet())
+}
+
+// userInfo returns claims about the user, access token is accepted in the Authorization header only.
+func (h *handler) userInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || accessToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	info, err := h.oauth.UserInfo(r.Context(), accessToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, oauth.ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			h.log.Error("failed to get user info", sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "server_error", "internal error")
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, info)
+}
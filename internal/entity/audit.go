@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of audit events.
+// Lockout is recorded once attempts of a one-time passcode are exhausted, passwords have no attempt limit.
+const (
+	AuditRegister        = "register"
+	AuditLogin           = "login"
+	AuditLockout         = "lockout"
+	AuditTokenRevocation = "token_revocation"
+	AuditImpersonation   = "impersonation"
+	AuditEmailChange     = "email_change"
+	AuditPhoneChange     = "phone_change"
+	AuditAccountDeletion = "account_deletion"
+	AuditRiskDecision    = "risk_decision"
+)
+
+// Outcomes of audited actions.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// AuditEvent records a security-relevant action.
+type AuditEvent struct {
+	ID       int64
+	TenantID uuid.UUID
+	Type     string
+	// UserID is the user the action was performed on or by,
+	// it is nil if the user is unknown, e.g. login with unregistered email.
+	UserID uuid.UUID
+	// ActorID is set if someone else acted on behalf of the user.
+	ActorID   uuid.UUID
+	Outcome   string
+	IP        string
+	UserAgent string
+	Details   map[string]string
+	CreatedAt time.Time
+}
+
+// AuditFilter narrows audit log queries, zero fields match any event.
+type AuditFilter struct {
+	UserID  uuid.UUID
+	Type    string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	// AfterID returns events older than the event with the id, for pagination.
+	AfterID int64
+}
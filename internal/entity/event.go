@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of domain events.
+const (
+	EventUserRegistered   = "user.registered"
+	EventUserEmailChanged = "user.email_changed"
+	EventUserDeleted      = "user.deleted"
+)
+
+// Event is a domain event waiting in the outbox to be published.
+type Event struct {
+	ID int64
+	// AggregateID is id of the user the event is about.
+	// Events of one user are published in the order they happened.
+	AggregateID uuid.UUID
+	Type        string
+	// Payload is JSON encoded body of the event.
+	Payload   []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Login is a successful sign-in of the user.
+type Login struct {
+	ID                int64
+	UserID            uuid.UUID
+	IP                string
+	Network           string
+	UserAgent         string
+	DeviceFingerprint string
+	CreatedAt         time.Time
+}
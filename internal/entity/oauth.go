@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// Client is an application registered to request tokens from the service.
+// Public clients (e.g. mobile and single-page apps) have no secret.
+// Clients belong to a tenant and get tokens of its users only.
+type Client struct {
+	ID           uuid.UUID
+	TenantID     uuid.UUID
+	SecretHash   []byte
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	GrantTypes   []string
+	CreatedAt    time.Time
+}
+
+func (c *Client) Public() bool {
+	return len(c.SecretHash) == 0
+}
+
+type AuthorizationCode struct {
+	CodeHash []byte
+	ClientID uuid.UUID
+	UserID   uuid.UUID
+	// RedirectURI is the one named by the authorization request, empty if the request omitted it.
+	RedirectURI         string
+	Scopes              []string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	AuthTime            time.Time
+	ExpiresAt           time.Time
+	CreatedAt           time.Time
+}
+
+type RefreshToken struct {
+	TokenHash []byte
+	ClientID  uuid.UUID
+	UserID    uuid.UUID
+	Scopes    []string
+	AuthTime  time.Time
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
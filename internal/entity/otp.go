@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OTP struct {
+	UserID    uuid.UUID
+	CodeHash  []byte
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,35 @@
+package entity
+
+// Decisions of risk-based authentication.
+const (
+	RiskAllow = "allow"
+	// RiskMFA requires the user to sign in with a one-time code instead.
+	RiskMFA  = "mfa"
+	RiskDeny = "deny"
+)
+
+// RiskSignals are inputs of a risk assessment of a sign-in.
+type RiskSignals struct {
+	// FailedAttempts is number of failed sign-ins of the user during the velocity window.
+	FailedAttempts int
+	// FirstLogin is set for users never signed in before, they have no device or location to compare with.
+	FirstLogin bool
+	NewDevice  bool
+	NewNetwork bool
+	// ASNChanged is set if the address belongs to another autonomous system than the previous sign-in.
+	ASNChanged bool
+	// ImpossibleTravel is set if getting from the location of the previous sign-in
+	// would take faster travel than possible.
+	ImpossibleTravel bool
+	Country          string
+	ASN              uint
+	TravelKm         float64
+	TravelSpeedKmh   float64
+}
+
+// RiskAssessment is a scored sign-in and the decision taken on it.
+type RiskAssessment struct {
+	Signals  RiskSignals
+	Score    int
+	Decision string
+}
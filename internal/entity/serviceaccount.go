@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAccount is a non-human identity of a backend service.
+// It authenticates with a secret, a JWT assertion signed with its private key, or both.
+type ServiceAccount struct {
+	ID         uuid.UUID
+	TenantID   uuid.UUID
+	Name       string
+	SecretHash []byte
+	// PublicKey is PEM encoded key verifying JWT assertions of the account.
+	PublicKey string
+	Scopes    []string
+	CreatedAt time.Time
+}
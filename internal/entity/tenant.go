@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tenant is an organization with its own namespace of users.
+type Tenant struct {
+	ID        uuid.UUID
+	Slug      string
+	Name      string
+	Disabled  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
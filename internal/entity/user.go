@@ -6,10 +6,24 @@ import (
 	"github.com/google/uuid"
 )
 
+// RoleAdmin is a role of the user allowed to manage its tenant.
+// Admins of the default tenant manage the whole service.
+const RoleAdmin = "admin"
+
+// RoleSupport is a role of the support engineer allowed to impersonate users of its tenant.
+// Support engineers of the default tenant impersonate users of any tenant.
+const RoleSupport = "support"
+
+// Roles are all roles users can be granted.
+var Roles = []string{RoleAdmin, RoleSupport}
+
 type User struct {
 	ID        uuid.UUID
+	TenantID  uuid.UUID
 	Email     string
+	Phone     string
 	PassHash  []byte
+	Roles     []string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
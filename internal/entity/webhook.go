@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is a delivery that failed every attempt, it is sent again only when replayed.
+	DeliveryDead = "dead"
+)
+
+// EventTypes lists types of domain events webhooks subscribe to.
+var EventTypes = []string{
+	EventUserRegistered,
+	EventUserEmailChanged,
+	EventUserDeleted,
+}
+
+// Webhook is an URL of a tenant receiving domain events of the given types.
+type Webhook struct {
+	ID         uuid.UUID
+	TenantID   uuid.UUID
+	URL        string
+	EventTypes []string
+	// Secret signs payloads, so the receiver can verify them.
+	Secret    string
+	CreatedAt time.Time
+}
+
+// WebhookDelivery is an event being sent to a webhook.
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	WebhookID      uuid.UUID
+	TenantID       uuid.UUID
+	EventID        int64
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
+
+// DeliveryFilter narrows delivery queries, zero fields match any delivery.
+type DeliveryFilter struct {
+	WebhookID uuid.UUID
+	Status    string
+}
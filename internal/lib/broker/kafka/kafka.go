@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher publishes events to a Kafka topic.
+// Events are keyed by the user, so events of a user land in one partition and keep their order.
+type Publisher struct {
+	writer messageWriter
+}
+
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+func New(brokers []string, topic string) *Publisher {
+	return &Publisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// events are published one by one, waiting for a batch only adds latency
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event *entity.Event) error {
+	const op = "kafka.Publisher.Publish"
+
+	err := p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.AggregateID.String()),
+		Value: event.Payload,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(strconv.FormatInt(event.ID, 10))},
+			{Key: "event-type", Value: []byte(event.Type)},
+		},
+		Time: event.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWriter struct {
+	messages []kafka.Message
+	err      error
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	p := New([]string{"localhost:9092"}, "auth.events")
+
+	writer, ok := p.writer.(*kafka.Writer)
+	require.True(t, ok)
+	assert.Equal(t, "auth.events", writer.Topic)
+	assert.IsType(t, &kafka.Hash{}, writer.Balancer, "events of a user land in one partition")
+	assert.Equal(t, kafka.RequireAll, writer.RequiredAcks)
+}
+
+func TestPublish(t *testing.T) {
+	writer := &fakeWriter{}
+	p := &Publisher{writer: writer}
+
+	event := &entity.Event{
+		ID:          42,
+		AggregateID: uuid.New(),
+		Type:        entity.EventUserRegistered,
+		Payload:     []byte(`{"type":"user.registered"}`),
+		CreatedAt:   time.Now(),
+	}
+
+	require.NoError(t, p.Publish(context.Background(), event))
+	require.Len(t, writer.messages, 1)
+
+	msg := writer.messages[0]
+	assert.Equal(t, event.AggregateID.String(), string(msg.Key))
+	assert.Equal(t, event.Payload, msg.Value)
+	assert.Equal(t, event.CreatedAt, msg.Time)
+	assert.Equal(t, []kafka.Header{
+		{Key: "event-id", Value: []byte("42")},
+		{Key: "event-type", Value: []byte(entity.EventUserRegistered)},
+	}, msg.Headers)
+}
+
+func TestPublish_Error(t *testing.T) {
+	errUnavailable := errors.New("broker is unavailable")
+	p := &Publisher{writer: &fakeWriter{err: errUnavailable}}
+
+	err := p.Publish(context.Background(), &entity.Event{ID: 1, AggregateID: uuid.New()})
+	assert.ErrorIs(t, err, errUnavailable)
+}
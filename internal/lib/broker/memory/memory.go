@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+)
+
+// Broker keeps published events in memory, e.g. for tests and local use.
+type Broker struct {
+	mu     sync.Mutex
+	events []*entity.Event
+}
+
+func New() *Broker {
+	return &Broker{}
+}
+
+func (b *Broker) Publish(_ context.Context, event *entity.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = append(b.events, event)
+
+	return nil
+}
+
+// Events returns events published so far in order of publishing.
+func (b *Broker) Events() []*entity.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make([]*entity.Event, len(b.events))
+	copy(events, b.events)
+
+	return events
+}
+
+func (b *Broker) Close() error {
+	return nil
+}
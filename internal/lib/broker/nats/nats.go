@@ -0,0 +1,60 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Publisher publishes events to NATS JetStream, subject is the prefix followed by the event type,
+// e.g. auth.events.user.registered. A stream must capture the subjects.
+// Event id is sent as Nats-Msg-Id, so JetStream drops events the relay sends again.
+type Publisher struct {
+	conn          *nats.Conn
+	js            jetstream.JetStream
+	subjectPrefix string
+}
+
+func New(url, subjectPrefix string) (*Publisher, error) {
+	const op = "nats.New"
+
+	conn, err := nats.Connect(url, nats.Name("auth"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Publisher{
+		conn:          conn,
+		js:            js,
+		subjectPrefix: subjectPrefix,
+	}, nil
+}
+
+func (p *Publisher) Publish(ctx context.Context, event *entity.Event) error {
+	const op = "nats.Publisher.Publish"
+
+	msg := nats.NewMsg(p.subjectPrefix + "." + event.Type)
+	msg.Data = event.Payload
+	msg.Header.Set(jetstream.MsgIDHeader, strconv.FormatInt(event.ID, 10))
+	msg.Header.Set("Aggregate-Id", event.AggregateID.String())
+
+	if _, err := p.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Publisher) Close() error {
+	return p.conn.Drain()
+}
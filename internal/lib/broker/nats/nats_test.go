@@ -0,0 +1,58 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJetStream records published messages, other methods of JetStream are not used.
+type fakeJetStream struct {
+	jetstream.JetStream
+	messages []*nats.Msg
+	err      error
+}
+
+func (js *fakeJetStream) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.messages = append(js.messages, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestPublish(t *testing.T) {
+	js := &fakeJetStream{}
+	p := &Publisher{js: js, subjectPrefix: "auth.events"}
+
+	event := &entity.Event{
+		ID:          42,
+		AggregateID: uuid.New(),
+		Type:        entity.EventUserRegistered,
+		Payload:     []byte(`{"type":"user.registered"}`),
+	}
+
+	require.NoError(t, p.Publish(context.Background(), event))
+	require.Len(t, js.messages, 1)
+
+	msg := js.messages[0]
+	assert.Equal(t, "auth.events."+entity.EventUserRegistered, msg.Subject)
+	assert.Equal(t, event.Payload, msg.Data)
+	assert.Equal(t, "42", msg.Header.Get(jetstream.MsgIDHeader), "JetStream drops events sent again")
+	assert.Equal(t, event.AggregateID.String(), msg.Header.Get("Aggregate-Id"))
+}
+
+func TestPublish_Error(t *testing.T) {
+	errUnavailable := errors.New("no responders")
+	p := &Publisher{js: &fakeJetStream{err: errUnavailable}, subjectPrefix: "auth.events"}
+
+	err := p.Publish(context.Background(), &entity.Event{ID: 1, AggregateID: uuid.New()})
+	assert.ErrorIs(t, err, errUnavailable)
+}
@@ -0,0 +1,63 @@
+package clientinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+)
+
+// Metadata keys carrying address and user agent of the client a proxy, e.g. the HTTP gateway,
+// forwards the request of.
+const (
+	ForwardedForKey       = "x-forwarded-for"
+	ForwardedUserAgentKey = "x-forwarded-user-agent"
+)
+
+// Info describes the client a request came from.
+type Info struct {
+	IP        string
+	UserAgent string
+	// Identity is set if the client presented a certificate verified by the server.
+	Identity *Identity
+}
+
+// Identity is the subject of a verified client certificate.
+type Identity struct {
+	CommonName string
+	DNSNames   []string
+	// URIs hold e.g. SPIFFE ids of workloads.
+	URIs []string
+	// Fingerprint is hex encoded SHA-256 of the certificate.
+	Fingerprint string
+}
+
+// IdentityOf returns identity of the certificate.
+func IdentityOf(cert *x509.Certificate) *Identity {
+	sum := sha256.Sum256(cert.Raw)
+
+	identity := &Identity{
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+
+	return identity
+}
+
+type ctxKey struct{}
+
+// WithInfo returns copy of the context carrying the client info.
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// FromContext returns info of the client the request came from, or zero Info if it is unknown.
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(ctxKey{}).(Info)
+
+	return info
+}
@@ -0,0 +1,41 @@
+package device
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"strings"
+)
+
+// Fingerprint returns stable identifier of the device the user agent belongs to.
+// Whitespace and case differences don't change it, other changes, e.g. a browser update, do.
+func Fingerprint(userAgent string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(userAgent), " "))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// Network returns network of the ip address: /24 for IPv4 and /48 for IPv6,
+// so a device moving within its home or office network keeps the same network.
+// If the address is not valid, returns it unchanged.
+func Network(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+
+	return prefix.String()
+}
@@ -0,0 +1,29 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	const ua = "Mozilla/5.0 (X11; Linux x86_64) Firefox/131.0"
+
+	assert.Equal(t, Fingerprint(ua), Fingerprint("  mozilla/5.0  (X11; Linux x86_64) Firefox/131.0 "))
+	assert.NotEqual(t, Fingerprint(ua), Fingerprint("Mozilla/5.0 (X11; Linux x86_64) Firefox/132.0"))
+	assert.Len(t, Fingerprint(""), 32)
+}
+
+func TestNetwork(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.15":        "192.0.2.0/24",
+		"::ffff:192.0.2.15": "192.0.2.0/24",
+		"2001:db8:1:2::1":   "2001:db8:1::/48",
+		"not an ip":         "not an ip",
+		"":                  "",
+	}
+
+	for ip, want := range tests {
+		assert.Equal(t, want, Network(ip), ip)
+	}
+}
@@ -0,0 +1,129 @@
+// Package geoip locates IP addresses using local MaxMind databases,
+// e.g. GeoLite2-City and GeoLite2-ASN.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var ErrInvalidIP = errors.New("invalid ip address")
+
+// Location is what is known about an address, fields of databases not loaded are zero.
+type Location struct {
+	Country   string
+	City      string
+	Latitude  float64
+	Longitude float64
+	// ASN is number of the autonomous system announcing the address.
+	ASN          uint
+	Organization string
+}
+
+// HasCoordinates reports whether the location is known to the city database.
+func (l *Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+type DB struct {
+	city *maxminddb.Reader
+	asn  *maxminddb.Reader
+}
+
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Open opens city and ASN databases, either path may be empty.
+func Open(cityPath, asnPath string) (*DB, error) {
+	const op = "geoip.Open"
+
+	db := new(DB)
+
+	if cityPath != "" {
+		reader, err := maxminddb.Open(cityPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		db.city = reader
+	}
+
+	if asnPath != "" {
+		reader, err := maxminddb.Open(asnPath)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		db.asn = reader
+	}
+
+	return db, nil
+}
+
+// Lookup returns location of the address, addresses missing from the databases have zero location.
+//
+// If the address is not valid, returns ErrInvalidIP.
+func (db *DB) Lookup(ip string) (*Location, error) {
+	const op = "geoip.Lookup"
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidIP)
+	}
+
+	location := new(Location)
+
+	if db.city != nil {
+		var record cityRecord
+		if err := db.city.Lookup(addr, &record); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		location.Country = record.Country.ISOCode
+		location.City = record.City.Names["en"]
+		location.Latitude = record.Location.Latitude
+		location.Longitude = record.Location.Longitude
+	}
+
+	if db.asn != nil {
+		var record asnRecord
+		if err := db.asn.Lookup(addr, &record); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		location.ASN = record.Number
+		location.Organization = record.Organization
+	}
+
+	return location, nil
+}
+
+func (db *DB) Close() error {
+	var errs []error
+
+	if db.city != nil {
+		errs = append(errs, db.city.Close())
+	}
+	if db.asn != nil {
+		errs = append(errs, db.asn.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+// Package healthcheck periodically checks dependencies of the service
+// and reports the outcome as serving status of the gRPC health service.
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check returns error if the dependency is not usable.
+type Check func(ctx context.Context) error
+
+// StatusSetter is a health server, e.g. health.Server.
+type StatusSetter interface {
+	SetServingStatus(service string, status healthpb.HealthCheckResponse_ServingStatus)
+}
+
+type Checker struct {
+	log      *slog.Logger
+	server   StatusSetter
+	checks   map[string]Check
+	services []string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// New returns Checker reporting status of the server as a whole and of the services.
+// The status is NOT_SERVING until the first run of checks succeeds.
+func New(log *slog.Logger, server StatusSetter, checks map[string]Check, services []string, interval, timeout time.Duration) *Checker {
+	c := &Checker{
+		log:      log,
+		server:   server,
+		checks:   checks,
+		services: append([]string{""}, services...),
+		interval: interval,
+		timeout:  timeout,
+	}
+
+	c.set(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return c
+}
+
+// Run checks dependencies every interval until the context is canceled.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	healthy := false
+	for {
+		if ok := c.Check(ctx); ok != healthy {
+			healthy = ok
+
+			status := healthpb.HealthCheckResponse_NOT_SERVING
+			if healthy {
+				status = healthpb.HealthCheckResponse_SERVING
+			}
+
+			c.log.Info("serving status changed", slog.String("status", status.String()))
+			c.set(status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check runs all checks concurrently and reports whether they all succeeded.
+func (c *Checker) Check(ctx context.Context) bool {
+	const op = "healthcheck.Check"
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result, len(c.checks))
+	for name, check := range c.checks {
+		go func() {
+			results <- result{name: name, err: check(ctx)}
+		}()
+	}
+
+	healthy := true
+	for range c.checks {
+		r := <-results
+		if r.err != nil {
+			c.log.Warn("dependency is unhealthy",
+				slog.String("op", op),
+				slog.String("dependency", r.name),
+				sl.Err(r.err),
+			)
+
+			healthy = false
+		}
+	}
+
+	return healthy
+}
+
+func (c *Checker) set(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,79 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func status(t *testing.T, server *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+
+	return resp.GetStatus()
+}
+
+func TestChecker(t *testing.T) {
+	server := health.NewServer()
+
+	var down atomic.Bool
+	down.Store(true)
+	checks := map[string]Check{
+		"postgresql": func(context.Context) error {
+			if down.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+		"cache": func(context.Context) error { return nil },
+	}
+
+	checker := New(slog.New(slog.NewTextHandler(io.Discard, nil)), server, checks, []string{"auth.Auth"}, 10*time.Millisecond, time.Second)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, ""), "not serving before dependencies are checked")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checker.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, server, ""))
+
+	down.Store(false)
+	assert.Eventually(t, func() bool {
+		return status(t, server, "") == healthpb.HealthCheckResponse_SERVING &&
+			status(t, server, "auth.Auth") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	down.Store(true)
+	assert.Eventually(t, func() bool {
+		return status(t, server, "auth.Auth") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestCheck_Timeout(t *testing.T) {
+	checker := New(slog.New(slog.NewTextHandler(io.Discard, nil)), health.NewServer(), map[string]Check{
+		"postgresql": func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, nil, time.Second, 10*time.Millisecond)
+
+	assert.False(t, checker.Check(context.Background()))
+}
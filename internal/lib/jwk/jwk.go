@@ -0,0 +1,138 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+const keyBits = 2048
+
+// Key is RSA key used to sign tokens with RS256.
+type Key struct {
+	Private *rsa.PrivateKey
+	ID      string
+}
+
+// JSONWebKey is a public key in the JWK format (RFC 7517).
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Load reads PEM encoded RSA private key (PKCS #1 or PKCS #8) from the file.
+func Load(path string) (*Key, error) {
+	const op = "jwk.Load"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", op)
+	}
+
+	private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		var ok bool
+		if private, ok = key.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("%s: %w", op, errors.New("key is not RSA"))
+		}
+	}
+
+	return newKey(private), nil
+}
+
+// ParsePublicKey parses PEM encoded RSA or ECDSA public key in PKIX format.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	const op = "jwk.ParsePublicKey"
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", op)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%s: %w", op, errors.New("key is neither RSA nor ECDSA"))
+	}
+}
+
+// Generate returns new random key. Tokens signed with it become invalid after restart.
+func Generate() (*Key, error) {
+	const op = "jwk.Generate"
+
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newKey(private), nil
+}
+
+// Public returns public part of the key in the JWK format.
+func (k *Key) Public() JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     k.ID,
+		N:         encode(k.Private.N),
+		E:         encode(big.NewInt(int64(k.Private.E))),
+	}
+}
+
+// Set returns key set with public part of the key.
+func (k *Key) Set() JSONWebKeySet {
+	return JSONWebKeySet{
+		Keys: []JSONWebKey{k.Public()},
+	}
+}
+
+// newKey uses JWK thumbprint (RFC 7638) as key id.
+func newKey(private *rsa.PrivateKey) *Key {
+	k := &Key{Private: private}
+	thumbprint := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+		encode(big.NewInt(int64(private.E))),
+		encode(private.N),
+	)
+	sum := sha256.Sum256([]byte(thumbprint))
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return k
+}
+
+func encode(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
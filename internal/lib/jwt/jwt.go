@@ -1,20 +1,150 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
 )
 
-// TODO: покрыть тестами
-func NewToken(user *entity.User, secret string, ttl time.Duration) (string, error) {
+var ErrInvalidToken = errors.New("invalid token")
+
+// Option adds optional claims to the token.
+type Option func(claims jwt.MapClaims)
+
+// WithScopes adds space-delimited scope claim.
+func WithScopes(scopes []string) Option {
+	return func(claims jwt.MapClaims) {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+}
+
+// WithClientID adds id of the OAuth client the token was issued to.
+func WithClientID(clientID string) Option {
+	return func(claims jwt.MapClaims) {
+		claims["client_id"] = clientID
+	}
+}
+
+// WithoutRoles leaves roles of the user out of the token. Tokens delegated
+// to clients or API keys must not act with the roles of the user.
+func WithoutRoles() Option {
+	return func(claims jwt.MapClaims) {
+		delete(claims, "roles")
+	}
+}
+
+// WithNonce adds nonce sent by the client to the authorization endpoint.
+func WithNonce(nonce string) Option {
+	return func(claims jwt.MapClaims) {
+		if nonce != "" {
+			claims["nonce"] = nonce
+		}
+	}
+}
+
+// WithAuthTime adds time when the user authenticated.
+func WithAuthTime(authTime time.Time) Option {
+	return func(claims jwt.MapClaims) {
+		if !authTime.IsZero() {
+			claims["auth_time"] = authTime.Unix()
+		}
+	}
+}
+
+// WithAccessTokenHash adds at_hash claim: left half of SHA-256 hash of the access token.
+func WithAccessTokenHash(accessToken string) Option {
+	return func(claims jwt.MapClaims) {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims["at_hash"] = base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+	}
+}
+
+// WithActor adds act claim (RFC 8693) identifying who acts on behalf of the subject.
+func WithActor(actorID uuid.UUID) Option {
+	return func(claims jwt.MapClaims) {
+		claims["act"] = map[string]any{"sub": actorID.String()}
+	}
+}
+
+// WithClaims adds arbitrary claims.
+func WithClaims(extra map[string]any) Option {
+	return func(claims jwt.MapClaims) {
+		for k, v := range extra {
+			claims[k] = v
+		}
+	}
+}
+
+// NewToken returns access token of the user signed with the secret.
+func NewToken(user *entity.User, secret string, ttl time.Duration, opts ...Option) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["uid"] = user.ID
+	claims["sub"] = user.ID.String()
+	claims["tenant_id"] = user.TenantID.String()
 	claims["email"] = user.Email
 	claims["exp"] = time.Now().Add(ttl).Unix()
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+	}
+
+	for _, opt := range opts {
+		opt(claims)
+	}
+
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// NewClientToken returns token issued to the OAuth client itself rather than to a user.
+func NewClientToken(clientID string, secret string, ttl time.Duration, opts ...Option) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["sub"] = clientID
+	claims["client_id"] = clientID
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	for _, opt := range opts {
+		opt(claims)
+	}
+
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// NewServiceAccountToken returns access token of the service account signed with the secret.
+// The service_account claim tells it apart from tokens of users.
+func NewServiceAccountToken(account *entity.ServiceAccount, secret string, ttl time.Duration, opts ...Option) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["sub"] = account.ID.String()
+	claims["tenant_id"] = account.TenantID.String()
+	claims["service_account"] = true
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	for _, opt := range opts {
+		opt(claims)
+	}
 
 	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
@@ -23,3 +153,92 @@ func NewToken(user *entity.User, secret string, ttl time.Duration) (string, erro
 
 	return tokenString, nil
 }
+
+// NewIDToken returns OpenID Connect ID token signed with RS256, so clients can verify it with the published JWKS.
+func NewIDToken(user *entity.User, key *jwk.Key, issuer, audience string, ttl time.Duration, opts ...Option) (string, error) {
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = key.ID
+
+	now := time.Now()
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["iss"] = issuer
+	claims["sub"] = user.ID.String()
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+
+	for _, opt := range opts {
+		opt(claims)
+	}
+
+	tokenString, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// Sign returns token with arbitrary claims signed with the secret,
+// it is used for short-lived values that pass through the client, e.g. OAuth state.
+func Sign(claims map[string]any, secret string, ttl time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	mapClaims := token.Claims.(jwt.MapClaims)
+	for k, v := range claims {
+		mapClaims[k] = v
+	}
+	mapClaims["exp"] = time.Now().Add(ttl).Unix()
+
+	return token.SignedString([]byte(secret))
+}
+
+// ParseAssertion verifies JWT assertion (RFC 7523) the client signed with its private key
+// and returns its id and expiration time.
+// Issuer and subject must be the client id and audience must be this service.
+// Assertions must be short-lived and have an id, the caller accepts every id once until it expires.
+func ParseAssertion(assertion string, key crypto.PublicKey, clientID, audience string, maxLifetime time.Duration) (jti string, expiresAt time.Time, err error) {
+	token, err := jwt.Parse(assertion, func(t *jwt.Token) (any, error) {
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(clientID),
+		jwt.WithSubject(clientID),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", time.Time{}, errors.Join(ErrInvalidToken, err)
+	}
+
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || time.Until(exp.Time) > maxLifetime {
+		return "", time.Time{}, fmt.Errorf("%w: assertion lifetime exceeds %s", ErrInvalidToken, maxLifetime)
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	jti, _ = claims["jti"].(string)
+	if jti == "" {
+		return "", time.Time{}, fmt.Errorf("%w: assertion has no jti", ErrInvalidToken)
+	}
+
+	return jti, exp.Time, nil
+}
+
+// Parse verifies token signed with the secret and returns its claims.
+func Parse(tokenString, secret string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, errors.Join(ErrInvalidToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,195 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "test-secret"
+
+func TestNewToken(t *testing.T) {
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+
+	token, err := NewToken(user, secret, time.Hour, WithScopes([]string{"openid", "email"}))
+	require.NoError(t, err)
+
+	claims, err := Parse(token, secret)
+	require.NoError(t, err)
+
+	assert.Equal(t, user.ID.String(), claims["uid"])
+	assert.Equal(t, user.ID.String(), claims["sub"])
+	assert.Equal(t, user.Email, claims["email"])
+	assert.Equal(t, "openid email", claims["scope"])
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), claims["exp"], 1)
+}
+
+func TestNewToken_Actor(t *testing.T) {
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+	actorID := uuid.New()
+
+	token, err := NewToken(user, secret, time.Hour, WithActor(actorID))
+	require.NoError(t, err)
+
+	claims, err := Parse(token, secret)
+	require.NoError(t, err)
+
+	assert.Equal(t, user.ID.String(), claims["sub"])
+	assert.Equal(t, map[string]any{"sub": actorID.String()}, claims["act"])
+}
+
+func TestNewToken_WithoutRoles(t *testing.T) {
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", Roles: []string{entity.RoleAdmin}}
+
+	token, err := NewToken(user, secret, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := Parse(token, secret)
+	require.NoError(t, err)
+	assert.Equal(t, []any{entity.RoleAdmin}, claims["roles"])
+
+	token, err = NewToken(user, secret, time.Hour, WithClientID("client"), WithoutRoles())
+	require.NoError(t, err)
+
+	claims, err = Parse(token, secret)
+	require.NoError(t, err)
+	assert.NotContains(t, claims, "roles")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+
+	expired, err := NewToken(user, secret, -time.Minute)
+	require.NoError(t, err)
+
+	_, err = Parse(expired, secret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	valid, err := NewToken(user, secret, time.Hour)
+	require.NoError(t, err)
+
+	_, err = Parse(valid, "another-secret")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewIDToken(t *testing.T) {
+	key, err := jwk.Generate()
+	require.NoError(t, err)
+
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+	const accessToken = "access-token"
+
+	token, err := NewIDToken(user, key, "https://auth.example.com", "client", time.Hour,
+		WithNonce("nonce"),
+		WithAccessTokenHash(accessToken),
+	)
+	require.NoError(t, err)
+
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		return &key.Private.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, key.ID, parsed.Header["kid"])
+
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, "https://auth.example.com", claims["iss"])
+	assert.Equal(t, user.ID.String(), claims["sub"])
+	assert.Equal(t, "client", claims["aud"])
+	assert.Equal(t, "nonce", claims["nonce"])
+
+	sum := sha256.Sum256([]byte(accessToken))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:16]), claims["at_hash"])
+}
+
+func TestNewServiceAccountToken(t *testing.T) {
+	account := &entity.ServiceAccount{ID: uuid.New(), TenantID: uuid.New()}
+
+	token, err := NewServiceAccountToken(account, secret, time.Minute, WithScopes([]string{"read"}))
+	require.NoError(t, err)
+
+	claims, err := Parse(token, secret)
+	require.NoError(t, err)
+
+	assert.Equal(t, account.ID.String(), claims["sub"])
+	assert.Equal(t, account.TenantID.String(), claims["tenant_id"])
+	assert.Equal(t, true, claims["service_account"])
+	assert.Equal(t, "read", claims["scope"])
+	assert.NotContains(t, claims, "email")
+}
+
+func TestParseAssertion(t *testing.T) {
+	const (
+		clientID = "client"
+		audience = "https://auth.example.com"
+	)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": clientID,
+			"sub": clientID,
+			"aud": audience,
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"jti": "assertion",
+		}
+	}
+
+	jti, expiresAt, err := ParseAssertion(sign(valid()), &key.PublicKey, clientID, audience, 5*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "assertion", jti)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+
+	tests := map[string]func(jwt.MapClaims){
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "another" },
+		"wrong subject":  func(c jwt.MapClaims) { c["sub"] = "another" },
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "https://evil.example.com" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+		"too long-lived": func(c jwt.MapClaims) { c["exp"] = time.Now().Add(time.Hour).Unix() },
+		"no id":          func(c jwt.MapClaims) { delete(c, "jti") },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := valid()
+			modify(claims)
+
+			_, _, err := ParseAssertion(sign(claims), &key.PublicKey, clientID, audience, 5*time.Minute)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	t.Run("another key", func(t *testing.T) {
+		another, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		_, _, err = ParseAssertion(sign(valid()), &another.PublicKey, clientID, audience, 5*time.Minute)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("hmac", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte(secret))
+		require.NoError(t, err)
+
+		_, _, err = ParseAssertion(token, &key.PublicKey, clientID, audience, 5*time.Minute)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
@@ -0,0 +1,240 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found in directory")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+const defaultTimeout = 5 * time.Second
+
+type Config struct {
+	// URL of the directory, e.g. ldaps://ldap.example.org:636 or ldap://ldap.example.org:389.
+	URL string
+	// StartTLS upgrades plain ldap:// connection to TLS.
+	StartTLS bool
+	// CACertPath is a path to PEM encoded CA certificates of the directory.
+	// If empty, system roots are used.
+	CACertPath         string
+	InsecureSkipVerify bool
+
+	// BindDN and BindPassword are credentials of the service account used to search users.
+	// If BindPassword is empty, anonymous bind is used.
+	BindDN       string
+	BindPassword string
+
+	BaseDN string
+	// UserFilter is a search filter with a single %s verb replaced by the escaped email,
+	// e.g. (&(objectClass=person)(mail=%s)).
+	UserFilter string
+	// EmailAttribute is the attribute holding email of the user, mail by default.
+	EmailAttribute string
+	// GroupAttribute is the attribute holding DNs of user groups, memberOf by default.
+	GroupAttribute string
+	// GroupRoles maps group DNs to local roles. Groups without mapping are ignored.
+	GroupRoles map[string]string
+
+	Timeout time.Duration
+}
+
+// User is a user found in the directory.
+type User struct {
+	DN    string
+	Email string
+	Roles []string
+}
+
+// Client authenticates users against LDAP or Active Directory.
+// Every call opens a new connection, so the client is safe for concurrent use.
+type Client struct {
+	cfg       Config
+	tlsConfig *tls.Config
+}
+
+func New(cfg Config) (*Client, error) {
+	const op = "ldap.New"
+
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if strings.Count(cfg.UserFilter, "%s") != 1 {
+		return nil, fmt.Errorf("%s: user filter must contain exactly one %%s", op)
+	}
+
+	// DNs are case-insensitive
+	groupRoles := make(map[string]string, len(cfg.GroupRoles))
+	for group, role := range cfg.GroupRoles {
+		groupRoles[strings.ToLower(group)] = role
+	}
+	cfg.GroupRoles = groupRoles
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CACertPath != "" {
+		pem, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found in %s", op, cfg.CACertPath)
+		}
+	}
+
+	return &Client{
+		cfg:       cfg,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// Authenticate finds the user by email with the service account
+// and verifies the password by binding as the user.
+//
+// If the user is not found, returns ErrUserNotFound.
+// If the password is incorrect, returns ErrInvalidCredentials.
+// Other errors mean the directory is unavailable or misconfigured.
+func (c *Client) Authenticate(ctx context.Context, email, password string) (*User, error) {
+	const op = "ldap.Authenticate"
+
+	// most servers treat bind with empty password as anonymous and report success
+	if password == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if c.cfg.BindPassword == "" {
+		err = conn.UnauthenticatedBind(c.cfg.BindDN)
+	} else {
+		err = conn.Bind(c.cfg.BindDN, c.cfg.BindPassword)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: service bind: %w", op, err)
+	}
+
+	entry, err := c.search(conn, email)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		return nil, fmt.Errorf("%s: user bind: %w", op, err)
+	}
+
+	return &User{
+		DN:    entry.DN,
+		Email: entry.GetAttributeValue(c.cfg.EmailAttribute),
+		Roles: c.roles(entry.GetAttributeValues(c.cfg.GroupAttribute)),
+	}, nil
+}
+
+func (c *Client) connect(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.cfg.Timeout}
+
+	conn, err := ldap.DialURL(c.cfg.URL,
+		ldap.DialWithDialer(dialer),
+		ldap.DialWithTLSConfig(c.tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.cfg.Timeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	conn.SetTimeout(timeout)
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(c.tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) search(conn *ldap.Conn, email string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		c.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // one is enough, second one reveals ambiguous filter
+		int(c.cfg.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(c.cfg.UserFilter, ldap.EscapeFilter(email)),
+		[]string{c.cfg.EmailAttribute, c.cfg.GroupAttribute},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		switch {
+		case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+			return nil, ErrUserNotFound
+		case ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded):
+			return nil, errors.New("user filter matched more than one entry")
+		}
+		return nil, err
+	}
+
+	switch len(res.Entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+		return res.Entries[0], nil
+	default:
+		return nil, fmt.Errorf("user filter matched %d entries", len(res.Entries))
+	}
+}
+
+// roles maps groups of the user to local roles, deduplicated and in stable order.
+func (c *Client) roles(groups []string) []string {
+	roles := make([]string, 0, len(groups))
+	seen := make(map[string]struct{}, len(groups))
+
+	for _, group := range groups {
+		role, ok := c.cfg.GroupRoles[strings.ToLower(group)]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[role]; ok {
+			continue
+		}
+
+		seen[role] = struct{}{}
+		roles = append(roles, role)
+	}
+
+	return roles
+}
@@ -0,0 +1,150 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	usersDN  = "ou=people,dc=example,dc=org"
+	groupsDN = "ou=groups,dc=example,dc=org"
+	password = "secret"
+)
+
+// newUser returns entry of the test directory. The test directory matches
+// filters against DNs only, so the email is a part of the DN.
+func newUser(email string, groups ...string) *gldap.Entry {
+	memberOf := make([]string, 0, len(groups))
+	for _, g := range groups {
+		memberOf = append(memberOf, fmt.Sprintf("cn=%s,%s", g, groupsDN))
+	}
+
+	return gldap.NewEntry(fmt.Sprintf("mail=%s,%s", email, usersDN), map[string][]string{
+		"mail":     {email},
+		"password": {password},
+		"memberOf": memberOf,
+	})
+}
+
+func setup(t *testing.T) *Client {
+	t.Helper()
+
+	td := testdirectory.Start(t,
+		testdirectory.WithDefaults(t, &testdirectory.Defaults{
+			AllowAnonymousBind: true,
+			UserDN:             usersDN,
+			GroupDN:            groupsDN,
+			Users: []*gldap.Entry{
+				newUser("alice@example.com", "admins", "developers"),
+				newUser("bob@example.com", "users"),
+			},
+		}),
+	)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caPath, []byte(td.Cert()), 0o600))
+
+	cfg := Config{
+		URL:        fmt.Sprintf("ldaps://%s:%d", td.Host(), td.Port()),
+		CACertPath: caPath,
+		BaseDN:     usersDN,
+		UserFilter: "(&(objectClass=person)(mail=%s))",
+		GroupRoles: map[string]string{
+			"CN=Admins," + groupsDN:     "admin",
+			"cn=developers," + groupsDN: "developer",
+		},
+	}
+
+	client, err := New(cfg)
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestAuthenticate_HappyPath(t *testing.T) {
+	client := setup(t)
+
+	user, err := client.Authenticate(context.Background(), "alice@example.com", password)
+	require.NoError(t, err)
+
+	assert.Equal(t, "mail=alice@example.com,"+usersDN, user.DN)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Equal(t, []string{"admin", "developer"}, user.Roles)
+
+	user, err = client.Authenticate(context.Background(), "bob@example.com", password)
+	require.NoError(t, err)
+
+	assert.Empty(t, user.Roles)
+}
+
+func TestAuthenticate_FailCases(t *testing.T) {
+	client := setup(t)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		expected error
+	}{
+		{
+			name:     "wrong password",
+			email:    "alice@example.com",
+			password: "wrong",
+			expected: ErrInvalidCredentials,
+		},
+		{
+			name:     "empty password",
+			email:    "alice@example.com",
+			password: "",
+			expected: ErrInvalidCredentials,
+		},
+		{
+			name:     "unknown user",
+			email:    "carol@example.com",
+			password: password,
+			expected: ErrUserNotFound,
+		},
+		{
+			name:     "filter injection",
+			email:    "*",
+			password: password,
+			expected: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Authenticate(context.Background(), tt.email, tt.password)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestAuthenticate_Unavailable(t *testing.T) {
+	client, err := New(Config{
+		URL:        "ldap://127.0.0.1:1",
+		BaseDN:     usersDN,
+		UserFilter: "(mail=%s)",
+		Timeout:    time.Second,
+	})
+	require.NoError(t, err)
+
+	_, err = client.Authenticate(context.Background(), "alice@example.com", password)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUserNotFound)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestNew_InvalidFilter(t *testing.T) {
+	_, err := New(Config{UserFilter: "(mail=*)"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+// Package metrics holds Prometheus collectors of the service.
+// They are registered in the default registry, which is served by Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "auth"
+
+var (
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of handled gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of gRPC requests by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Number of registered users.",
+	})
+
+	LoginSuccesses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_successes_total",
+		Help:      "Number of successful logins.",
+	})
+
+	LoginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Number of failed logins by reason.",
+	}, []string{"reason"})
+
+	Lockouts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lockouts_total",
+		Help:      "Number of users locked out after too many failed attempts.",
+	})
+
+	BcryptDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "bcrypt",
+		Name:      "duration_seconds",
+		Help:      "Duration of bcrypt operations: hash or compare.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 10),
+	}, []string{"operation"})
+)
+
+// Handler serves metrics of the default registry in Prometheus format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveAuditEvent counts registrations, failed credential checks and lockouts the event reports.
+// Valid credentials don't make a login yet, logins admitted or refused after the check are counted by ObserveLogin.
+func ObserveAuditEvent(event *entity.AuditEvent) {
+	switch event.Type {
+	case entity.AuditRegister:
+		if event.Outcome == entity.OutcomeSuccess {
+			Registrations.Inc()
+		}
+	case entity.AuditLogin:
+		if event.Outcome == entity.OutcomeSuccess {
+			return
+		}
+
+		reason := event.Details["reason"]
+		if reason == "" {
+			reason = "unknown"
+		}
+		LoginFailures.WithLabelValues(reason).Inc()
+	case entity.AuditLockout:
+		Lockouts.Inc()
+	}
+}
+
+// ObserveLogin counts login of the user with checked credentials: successful if reason is empty,
+// failed for the reason otherwise, e.g. refused by the risk engine.
+func ObserveLogin(reason string) {
+	if reason == "" {
+		LoginSuccesses.Inc()
+		return
+	}
+
+	LoginFailures.WithLabelValues(reason).Inc()
+}
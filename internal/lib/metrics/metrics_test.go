@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveAuditEvent(t *testing.T) {
+	events := []*entity.AuditEvent{
+		{Type: entity.AuditRegister, Outcome: entity.OutcomeSuccess},
+		{Type: entity.AuditRegister, Outcome: entity.OutcomeFailure},
+		{Type: entity.AuditLogin, Outcome: entity.OutcomeSuccess},
+		{Type: entity.AuditLogin, Outcome: entity.OutcomeFailure, Details: map[string]string{"reason": "invalid_password"}},
+		{Type: entity.AuditLogin, Outcome: entity.OutcomeFailure, Details: map[string]string{"reason": "invalid_password"}},
+		{Type: entity.AuditLogin, Outcome: entity.OutcomeFailure},
+		{Type: entity.AuditRiskDecision, Outcome: entity.OutcomeSuccess, Details: map[string]string{"decision": entity.RiskAllow}},
+		{Type: entity.AuditRiskDecision, Outcome: entity.OutcomeFailure, Details: map[string]string{"decision": entity.RiskDeny}},
+		{Type: entity.AuditLockout, Outcome: entity.OutcomeFailure},
+		{Type: entity.AuditEmailChange, Outcome: entity.OutcomeSuccess},
+	}
+	for _, event := range events {
+		ObserveAuditEvent(event)
+	}
+
+	assert.InDelta(t, 1, testutil.ToFloat64(Registrations), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(LoginSuccesses), 0, "valid credentials are not a login yet")
+	assert.InDelta(t, 2, testutil.ToFloat64(LoginFailures.WithLabelValues("invalid_password")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(LoginFailures.WithLabelValues("unknown")), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(LoginFailures.WithLabelValues("risk_deny")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(Lockouts), 0)
+
+	ObserveLogin("")
+	ObserveLogin("risk_deny")
+
+	assert.InDelta(t, 1, testutil.ToFloat64(LoginSuccesses), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(LoginFailures.WithLabelValues("risk_deny")), 0)
+}
@@ -0,0 +1,27 @@
+// Package passhash hashes passwords and secrets with bcrypt and measures how long it takes.
+package passhash
+
+import (
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/lib/metrics"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash returns bcrypt hash of the password with default cost.
+func Hash(password []byte) ([]byte, error) {
+	defer observe("hash", time.Now())
+
+	return bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+}
+
+// Compare returns nil if the hash is a bcrypt hash of the password.
+func Compare(hash, password []byte) error {
+	defer observe("compare", time.Now())
+
+	return bcrypt.CompareHashAndPassword(hash, password)
+}
+
+func observe(operation string, start time.Time) {
+	metrics.BcryptDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
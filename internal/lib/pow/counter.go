@@ -0,0 +1,70 @@
+package pow
+
+import (
+	"sync"
+	"time"
+)
+
+// Counter estimates how many times each key was seen during the last window.
+// It keeps counts of the current and the previous window and weighs the previous one
+// by its overlap with the sliding window, so memory doesn't grow with traffic of a key.
+type Counter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	started time.Time
+	current map[string]int
+	prev    map[string]int
+}
+
+func NewCounter(window time.Duration) *Counter {
+	return &Counter{
+		window:  window,
+		started: time.Now(),
+		current: make(map[string]int),
+		prev:    make(map[string]int),
+	}
+}
+
+// Add counts the key and returns its estimated count during the last window, including this one.
+func (c *Counter) Add(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.rotate(now)
+
+	c.current[key]++
+
+	return c.estimate(key, now)
+}
+
+// Count returns estimated count of the key during the last window without counting it.
+func (c *Counter) Count(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.rotate(now)
+
+	return c.estimate(key, now)
+}
+
+// rotate starts new window once the current one is over.
+func (c *Counter) rotate(now time.Time) {
+	switch elapsed := now.Sub(c.started); {
+	case elapsed >= 2*c.window:
+		c.prev = make(map[string]int)
+		c.current = make(map[string]int)
+		c.started = now
+	case elapsed >= c.window:
+		c.prev = c.current
+		c.current = make(map[string]int)
+		c.started = c.started.Add(c.window)
+	}
+}
+
+func (c *Counter) estimate(key string, now time.Time) int {
+	overlap := 1 - float64(now.Sub(c.started))/float64(c.window)
+
+	return c.current[key] + int(float64(c.prev[key])*overlap)
+}
@@ -0,0 +1,179 @@
+// Package pow implements stateless hashcash-style proof-of-work challenges.
+//
+// A challenge is "v1.{expires}.{difficulty}.{nonce}.{mac}", where mac is HMAC-SHA256
+// of the other parts and of the subject the challenge is issued to, e.g. the client address.
+// The subject is not sent, so a challenge is accepted only from the subject it was issued to.
+// A solution is any string such that SHA-256 of "{challenge}:{solution}" starts with
+// difficulty zero bits, finding one takes about 2^difficulty hashes.
+package pow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidChallenge = errors.New("invalid challenge")
+	ErrExpiredChallenge = errors.New("challenge expired")
+	ErrInvalidSolution  = errors.New("invalid solution")
+)
+
+const (
+	version = "v1"
+	// MaxDifficulty keeps challenges solvable.
+	MaxDifficulty = 32
+)
+
+// Issuer issues and verifies challenges signed with its secret.
+type Issuer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewIssuer returns issuer of challenges valid for ttl.
+func NewIssuer(secret string, ttl time.Duration) *Issuer {
+	// challenges are signed with a key of their own, not with the secret shared with tokens
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("pow"))
+
+	return &Issuer{
+		key: mac.Sum(nil),
+		ttl: ttl,
+	}
+}
+
+// Issue returns challenge of the difficulty for the subject.
+func (i *Issuer) Issue(subject string, difficulty int) string {
+	difficulty = min(max(difficulty, 0), MaxDifficulty)
+
+	nonce := make([]byte, 12)
+	_, _ = rand.Read(nonce)
+
+	body := strings.Join([]string{
+		version,
+		strconv.FormatInt(time.Now().Add(i.ttl).Unix(), 10),
+		strconv.Itoa(difficulty),
+		base64.RawURLEncoding.EncodeToString(nonce),
+	}, ".")
+
+	return body + "." + i.sign(body, subject)
+}
+
+// Verify checks that the challenge was issued to the subject, has not expired and is solved.
+// It returns difficulty of the challenge.
+//
+// If the challenge is malformed or issued to another subject, returns ErrInvalidChallenge.
+// If the challenge has expired, returns ErrExpiredChallenge.
+// If the solution is wrong, returns ErrInvalidSolution.
+func (i *Issuer) Verify(challenge, solution, subject string) (difficulty int, err error) {
+	body, mac, ok := cutLast(challenge, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(i.sign(body, subject))) {
+		return 0, ErrInvalidChallenge
+	}
+
+	expiresAt, difficulty, err := parse(challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	if time.Now().After(expiresAt) {
+		return 0, ErrExpiredChallenge
+	}
+
+	if !solves(challenge, solution, difficulty) {
+		return 0, ErrInvalidSolution
+	}
+
+	return difficulty, nil
+}
+
+func (i *Issuer) sign(body, subject string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(body))
+	mac.Write([]byte{0})
+	mac.Write([]byte(subject))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Difficulty returns difficulty of the challenge without verifying it.
+//
+// If the challenge is malformed, returns ErrInvalidChallenge.
+func Difficulty(challenge string) (int, error) {
+	_, difficulty, err := parse(challenge)
+
+	return difficulty, err
+}
+
+// Solve finds solution of the challenge, it gives up when the context is done.
+//
+// If the challenge is malformed, returns ErrInvalidChallenge.
+func Solve(ctx context.Context, challenge string) (string, error) {
+	difficulty, err := Difficulty(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	for n := uint64(0); ; n++ {
+		if n%(1<<14) == 0 && ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		solution := strconv.FormatUint(n, 36)
+		if solves(challenge, solution, difficulty) {
+			return solution, nil
+		}
+	}
+}
+
+func parse(challenge string) (expiresAt time.Time, difficulty int, err error) {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 5 || parts[0] != version {
+		return time.Time{}, 0, ErrInvalidChallenge
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidChallenge
+	}
+
+	difficulty, err = strconv.Atoi(parts[2])
+	if err != nil || difficulty < 0 || difficulty > MaxDifficulty {
+		return time.Time{}, 0, ErrInvalidChallenge
+	}
+
+	return time.Unix(expires, 0), difficulty, nil
+}
+
+// solves reports whether hash of the solution starts with difficulty zero bits.
+func solves(challenge, solution string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+
+	return zeros >= difficulty
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
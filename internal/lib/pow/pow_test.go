@@ -0,0 +1,85 @@
+package pow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueSolveVerify(t *testing.T) {
+	issuer := NewIssuer("secret", time.Minute)
+	challenge := issuer.Issue("192.0.2.1", 12)
+
+	difficulty, err := Difficulty(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, 12, difficulty)
+
+	solution, err := Solve(context.Background(), challenge)
+	require.NoError(t, err)
+
+	difficulty, err = issuer.Verify(challenge, solution, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, 12, difficulty)
+
+	tests := []struct {
+		name      string
+		issuer    *Issuer
+		challenge string
+		solution  string
+		subject   string
+		err       error
+	}{
+		{"another subject", issuer, challenge, solution, "192.0.2.2", ErrInvalidChallenge},
+		{"another secret", NewIssuer("other", time.Minute), challenge, solution, "192.0.2.1", ErrInvalidChallenge},
+		{"wrong solution", issuer, challenge, solution + "x", "192.0.2.1", ErrInvalidSolution},
+		{"lowered difficulty", issuer, strings.Replace(challenge, ".12.", ".1.", 1), solution, "192.0.2.1", ErrInvalidChallenge},
+		{"malformed", issuer, "v1.garbage", solution, "192.0.2.1", ErrInvalidChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.issuer.Verify(tt.challenge, tt.solution, tt.subject)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestVerify_Expired(t *testing.T) {
+	issuer := NewIssuer("secret", -time.Second)
+	challenge := issuer.Issue("192.0.2.1", 0)
+
+	_, err := issuer.Verify(challenge, "", "192.0.2.1")
+	assert.ErrorIs(t, err, ErrExpiredChallenge)
+}
+
+func TestSolve_Canceled(t *testing.T) {
+	challenge := NewIssuer("secret", time.Minute).Issue("192.0.2.1", MaxDifficulty)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := Solve(ctx, challenge)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCounter(t *testing.T) {
+	counter := NewCounter(time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, i, counter.Add("a"))
+	}
+	assert.Equal(t, 1, counter.Add("b"))
+	assert.Equal(t, 3, counter.Count("a"), "reading the count doesn't count")
+	assert.Equal(t, 0, counter.Count("c"))
+
+	// the previous window is weighed by its overlap with the sliding window
+	counter.started = counter.started.Add(-90 * time.Minute)
+	assert.InDelta(t, 1+3/2, counter.Add("a"), 1)
+
+	counter.started = counter.started.Add(-3 * time.Hour)
+	assert.Equal(t, 1, counter.Add("a"))
+}
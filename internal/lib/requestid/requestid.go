@@ -0,0 +1,48 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// MetadataKey is a gRPC metadata key and HTTP header carrying request id.
+const MetadataKey = "x-request-id"
+
+// MaxLength limits ids accepted from clients, so they can't flood logs.
+const MaxLength = 128
+
+type ctxKey struct{}
+
+// New returns new random request id.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether id passed by a client can be used as request id:
+// it is not empty, not longer than MaxLength and consists of printable ASCII characters.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithID returns copy of the context carrying the request id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns id of the request, or empty string if it is unknown.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+
+	return id
+}
@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Provider sends text messages to phone numbers.
+type Provider interface {
+	SendSMS(ctx context.Context, phone, text string) error
+}
+
+// FileProvider is a stand-in Provider for local use,
+// it appends every message to a file instead of sending it.
+type FileProvider struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{
+		path: path,
+	}
+}
+
+func (p *FileProvider) SendSMS(ctx context.Context, phone, text string) error {
+	const op = "sms.FileProvider.SendSMS"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phone, text)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
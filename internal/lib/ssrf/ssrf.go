@@ -0,0 +1,69 @@
+// Package ssrf keeps requests to URLs supplied by users off internal networks.
+package ssrf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), not covered by netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Control rejects connections to addresses which are not public: loopback, link-local,
+// private, unspecified and multicast ones. Dialers call it with the resolved address,
+// so host names resolving to internal addresses are rejected as well.
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !Public(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
+
+// Public reports whether the address is routable on the internet.
+func Public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// NewClient returns HTTP client for URLs supplied by users. It connects only to public
+// addresses, ignores proxies of the environment, which would connect on its behalf,
+// and doesn't follow redirects: the redirect response is returned as is.
+//
+// allowPrivate lifts the address check for local development, it must not be set in production.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+	if !allowPrivate {
+		dialer.Control = Control
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package tenancy
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Default is id of the tenant requests belong to when no tenant is specified.
+// It is created by migrations and can't be deleted.
+var Default = uuid.Nil
+
+// MetadataKey is a gRPC metadata key and HTTP header carrying tenant slug.
+const MetadataKey = "x-tenant"
+
+type ctxKey struct{}
+
+// WithTenant returns copy of the context scoped to the tenant.
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenantID)
+}
+
+// FromContext returns id of the tenant the context is scoped to, or Default.
+func FromContext(ctx context.Context) uuid.UUID {
+	tenantID, ok := ctx.Value(ctxKey{}).(uuid.UUID)
+	if !ok {
+		return Default
+	}
+
+	return tenantID
+}
@@ -0,0 +1,147 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+)
+
+// Reloader serves TLS configuration with certificate, key and client CA read from files,
+// so renewed certificates are picked up without a restart. Files are checked for
+// changes on handshakes, at most once per interval.
+type Reloader struct {
+	log      *slog.Logger
+	base     *tls.Config
+	certPath string
+	keyPath  string
+	caPath   string
+	interval time.Duration
+
+	mu       sync.Mutex
+	checked  time.Time
+	modTimes []time.Time
+	config   *tls.Config
+}
+
+// NewReloader returns Reloader of the base configuration, which sets e.g. versions
+// and client auth, and loads the files. Client CA path may be empty.
+//
+// If the files can't be loaded, returns error.
+func NewReloader(log *slog.Logger, base *tls.Config, certPath, keyPath, caPath string, interval time.Duration) (*Reloader, error) {
+	const op = "tlsutil.NewReloader"
+
+	r := &Reloader{
+		log:      log,
+		base:     base,
+		certPath: certPath,
+		keyPath:  keyPath,
+		caPath:   caPath,
+		interval: interval,
+	}
+
+	modTimes, err := r.modTimesOf()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.load(modTimes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	r.checked = time.Now()
+
+	return r, nil
+}
+
+// Config returns server configuration resolving to the latest loaded files on every handshake.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.base.MinVersion,
+		NextProtos: r.base.NextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// current returns the latest configuration, reloading files changed since the last check.
+// Files failing to load, e.g. written partially, are retried after the interval,
+// the previous configuration is served meanwhile.
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < r.interval {
+		return r.config
+	}
+	r.checked = time.Now()
+
+	modTimes, err := r.modTimesOf()
+	if err != nil {
+		r.log.Error("failed to check tls files", sl.Err(err))
+		return r.config
+	}
+
+	changed := false
+	for i := range modTimes {
+		changed = changed || !modTimes[i].Equal(r.modTimes[i])
+	}
+	if !changed {
+		return r.config
+	}
+
+	if err := r.load(modTimes); err != nil {
+		r.log.Error("failed to reload tls files", sl.Err(err))
+		return r.config
+	}
+
+	r.log.Info("tls certificates reloaded", slog.String("cert", r.certPath))
+
+	return r.config
+}
+
+func (r *Reloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return err
+	}
+
+	config := r.base.Clone()
+	config.Certificates = []tls.Certificate{cert}
+
+	if r.caPath != "" {
+		pool, err := LoadCertPool(r.caPath)
+		if err != nil {
+			return err
+		}
+		config.ClientCAs = pool
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+
+	return nil
+}
+
+func (r *Reloader) modTimesOf() ([]time.Time, error) {
+	paths := []string{r.certPath, r.keyPath}
+	if r.caPath != "" {
+		paths = append(paths, r.caPath)
+	}
+
+	modTimes := make([]time.Time, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
@@ -0,0 +1,130 @@
+// Package tlsutil builds TLS configurations of servers and clients from files on disk.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Client authentication policies.
+const (
+	ClientAuthNone = "none"
+	// ClientAuthOptional verifies certificates of clients presenting one.
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+var (
+	ErrUnknownVersion     = errors.New("unknown tls version")
+	ErrUnknownCipherSuite = errors.New("unknown or insecure cipher suite")
+	ErrUnknownClientAuth  = errors.New("unknown client auth policy")
+	ErrNoCertificates     = errors.New("no certificates found")
+)
+
+// Version returns TLS version by its name, "1.2" or "1.3".
+//
+// If the version is unknown, returns ErrUnknownVersion.
+func Version(name string) (uint16, error) {
+	switch name {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownVersion, name)
+	}
+}
+
+// CipherSuites returns ids of the cipher suites by their names, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256.
+// Only suites considered secure by crypto/tls are accepted. Empty list keeps defaults of crypto/tls.
+//
+// If a suite is unknown or insecure, returns ErrUnknownCipherSuite.
+func CipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	secure := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCipherSuite, name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// ClientAuth returns client authentication type of the policy.
+//
+// If the policy is unknown, returns ErrUnknownClientAuth.
+func ClientAuth(policy string) (tls.ClientAuthType, error) {
+	switch policy {
+	case ClientAuthNone, "":
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownClientAuth, policy)
+	}
+}
+
+// LoadCertPool returns pool of PEM encoded certificates of the file.
+//
+// If the file has no certificates, returns ErrNoCertificates.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	const op = "tlsutil.LoadCertPool"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrNoCertificates, path)
+	}
+
+	return pool, nil
+}
+
+// ClientConfig returns configuration of a client trusting servers signed by the CA,
+// or by system roots if caPath is empty, and presenting the certificate if its paths are given.
+func ClientConfig(caPath, certPath, keyPath, serverName string) (*tls.Config, error) {
+	const op = "tlsutil.ClientConfig"
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caPath != "" {
+		pool, err := LoadCertPool(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		config.RootCAs = pool
+	}
+
+	if certPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
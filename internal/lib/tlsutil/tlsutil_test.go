@@ -0,0 +1,247 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &authority{cert: cert, key: key}
+}
+
+// issue writes certificate of the common name signed by the authority and its key
+// to the directory, returns their paths.
+func (a *authority) issue(t *testing.T, dir, commonName string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, commonName+".crt")
+	keyPath := filepath.Join(dir, commonName+".key")
+	writePEM(t, certPath, "CERTIFICATE", der)
+	writePEM(t, keyPath, "PRIVATE KEY", keyDER)
+
+	return certPath, keyPath
+}
+
+func (a *authority) write(t *testing.T, path string) {
+	t.Helper()
+	writePEM(t, path, "CERTIFICATE", a.cert.Raw)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+// handshake connects the client to the server over loopback and returns state of the server side.
+// Errors of the server are returned first, as clients of TLS 1.3 finish before
+// their certificate is verified.
+func handshake(t *testing.T, server, client *tls.Config) (tls.ConnectionState, error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		conn, err := tls.Dial("tcp", l.Addr().String(), client)
+		if err == nil {
+			defer conn.Close()
+		}
+		clientErr <- err
+	}()
+
+	raw, err := l.Accept()
+	require.NoError(t, err)
+	defer raw.Close()
+
+	conn := tls.Server(raw, server)
+	err = conn.Handshake()
+	raw.Close()
+
+	if cerr := <-clientErr; err == nil {
+		err = cerr
+	}
+
+	return conn.ConnectionState(), err
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	caPath := filepath.Join(dir, "ca.crt")
+	ca.write(t, caPath)
+
+	serverCert, serverKey := ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "billing", x509.ExtKeyUsageClientAuth)
+
+	base := &tls.Config{MinVersion: tls.VersionTLS12, ClientAuth: tls.RequireAndVerifyClientCert}
+	reloader, err := NewReloader(discard, base, serverCert, serverKey, caPath, time.Minute)
+	require.NoError(t, err)
+
+	client, err := ClientConfig(caPath, clientCert, clientKey, "localhost")
+	require.NoError(t, err)
+
+	state, err := handshake(t, reloader.Config(), client)
+	require.NoError(t, err)
+	require.NotEmpty(t, state.VerifiedChains)
+	assert.Equal(t, "billing", state.VerifiedChains[0][0].Subject.CommonName)
+
+	t.Run("without client certificate", func(t *testing.T) {
+		client, err := ClientConfig(caPath, "", "", "localhost")
+		require.NoError(t, err)
+
+		_, err = handshake(t, reloader.Config(), client)
+		assert.Error(t, err)
+	})
+
+	t.Run("certificate of other authority", func(t *testing.T) {
+		otherCert, otherKey := newAuthority(t).issue(t, t.TempDir(), "intruder", x509.ExtKeyUsageClientAuth)
+
+		client, err := ClientConfig(caPath, otherCert, otherKey, "localhost")
+		require.NoError(t, err)
+
+		_, err = handshake(t, reloader.Config(), client)
+		assert.Error(t, err)
+	})
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	oldCA, newCA := newAuthority(t), newAuthority(t)
+
+	oldCAPath, newCAPath := filepath.Join(dir, "old-ca.crt"), filepath.Join(dir, "new-ca.crt")
+	oldCA.write(t, oldCAPath)
+	newCA.write(t, newCAPath)
+
+	certPath, keyPath := oldCA.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+
+	reloader, err := NewReloader(discard, &tls.Config{MinVersion: tls.VersionTLS12}, certPath, keyPath, "", 0)
+	require.NoError(t, err)
+
+	trustsNew, err := ClientConfig(newCAPath, "", "", "localhost")
+	require.NoError(t, err)
+
+	_, err = handshake(t, reloader.Config(), trustsNew)
+	require.Error(t, err, "certificate is signed by the old authority")
+
+	// renewal writes the files in place
+	newDir := t.TempDir()
+	renewedCert, renewedKey := newCA.issue(t, newDir, "localhost", x509.ExtKeyUsageServerAuth)
+	for from, to := range map[string]string{renewedCert: certPath, renewedKey: keyPath} {
+		data, err := os.ReadFile(from)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(to, data, 0o600))
+
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(to, later, later))
+	}
+
+	_, err = handshake(t, reloader.Config(), trustsNew)
+	require.NoError(t, err)
+
+	t.Run("broken files keep the previous certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(keyPath, []byte("garbage"), 0o600))
+
+		later := time.Now().Add(2 * time.Minute)
+		require.NoError(t, os.Chtimes(keyPath, later, later))
+
+		_, err = handshake(t, reloader.Config(), trustsNew)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCipherSuites(t *testing.T) {
+	ids, err := CipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, ids)
+
+	_, err = CipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	assert.ErrorIs(t, err, ErrUnknownCipherSuite, "insecure suites are rejected")
+
+	ids, err = CipherSuites(nil)
+	require.NoError(t, err)
+	assert.Nil(t, ids)
+}
+
+func TestPolicies(t *testing.T) {
+	version, err := Version("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), version)
+
+	_, err = Version("1.0")
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+
+	clientAuth, err := ClientAuth(ClientAuthOptional)
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, clientAuth)
+
+	_, err = ClientAuth("always")
+	assert.ErrorIs(t, err, ErrUnknownClientAuth)
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxTracer starts a client span for every query executed by pgx.
+// Query arguments are not recorded, they may hold secrets, e.g. password hashes.
+type PgxTracer struct {
+	tracer trace.Tracer
+}
+
+// NewPgxTracer returns tracer to be set in pgx.ConnConfig.
+func NewPgxTracer() *PgxTracer {
+	return &PgxTracer{
+		tracer: otel.Tracer("github.com/kurochkinivan/auth/internal/lib/tracing"),
+	}
+}
+
+func (t *PgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemPostgreSQL,
+		semconv.DBQueryText(data.SQL),
+	}
+	if conn != nil {
+		attrs = append(attrs, semconv.DBNamespace(conn.Config().Database))
+	}
+
+	ctx, _ = t.tracer.Start(ctx, "postgresql.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx
+}
+
+func (t *PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	provider, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	_, err = Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+}
+
+func TestPgxTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	tracer := NewPgxTracer()
+	ctx := context.Background()
+
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1", Args: []any{"secret"}})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT broken"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("syntax error")})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "postgresql.query", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.query.text", "SELECT 1"))
+	assert.Contains(t, spans[0].Attributes(), attribute.Int64("db.rows_affected", 1))
+	for _, attr := range spans[0].Attributes() {
+		assert.NotEqual(t, "secret", attr.Value.Emit(), "arguments are not recorded")
+	}
+
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
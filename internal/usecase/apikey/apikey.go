@@ -0,0 +1,410 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrInvalidKey        = errors.New("invalid api key")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrKeyNotFound       = errors.New("api key not found")
+	ErrExpiresAt         = errors.New("expiry must be in the future")
+	ErrUserTokenRequired = errors.New("user token is required")
+)
+
+// KeyPrefix starts every API key, so keys are recognizable in configs and by secret scanners.
+const KeyPrefix = "ak_"
+
+type Keys struct {
+	log          *slog.Logger
+	secret       string
+	userProvider UserProvider
+	keyStorage   KeyStorage
+	auditLog     AuditLog
+	tokenTTL     time.Duration
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+}
+
+type KeyStorage interface {
+	SaveAPIKey(ctx context.Context, key *entity.APIKey) (keyID uuid.UUID, err error)
+	APIKeyByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error)
+	APIKeys(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, keyID uuid.UUID) error
+	TouchAPIKey(ctx context.Context, keyID uuid.UUID, ip string) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, event *entity.AuditEvent) error
+}
+
+// TokenInfo describes a valid access token or API key.
+type TokenInfo struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+	// Scopes are empty if the token is not restricted.
+	Scopes    []string
+	Roles     []string
+	ExpiresAt time.Time
+	// APIKeyID is set if the token is an API key or was exchanged for one.
+	APIKeyID uuid.UUID
+	// ServiceAccount is set if the token was issued to a service account,
+	// UserID is id of the account then.
+	ServiceAccount bool
+	// ActorID is set if the token was issued to someone impersonating the user.
+	ActorID uuid.UUID
+	// ClientID is set if the token was issued to an OAuth client, for itself or for the user.
+	ClientID string
+
+	// hasTenant is false for tokens not bound to a tenant, e.g. tokens of OAuth clients themselves.
+	hasTenant bool
+}
+
+// New returns new instance of Keys service
+func New(log *slog.Logger, userProvider UserProvider, keyStorage KeyStorage, auditLog AuditLog, secret string, tokenTTL time.Duration) *Keys {
+	return &Keys{
+		log:          log,
+		secret:       secret,
+		userProvider: userProvider,
+		keyStorage:   keyStorage,
+		auditLog:     auditLog,
+		tokenTTL:     tokenTTL,
+	}
+}
+
+// Create creates API key of the user and returns the key itself along with its metadata.
+// The key is not stored and can't be shown again.
+//
+// If expiresAt is not in the future, returns ErrExpiresAt.
+func (k *Keys) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (string, *entity.APIKey, error) {
+	const op = "apikey.Create"
+	log := k.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, fmt.Errorf("%s: %w", op, ErrExpiresAt)
+	}
+
+	prefix, secret, err := generate()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	key := &entity.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hash(secret),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	key.ID, err = k.keyStorage.SaveAPIKey(ctx, key)
+	if err != nil {
+		log.Error("failed to save api key", sl.Err(err))
+
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("api key created", slog.String("prefix", prefix))
+
+	return KeyPrefix + prefix + "_" + secret, key, nil
+}
+
+// Keys returns API keys of the user without the keys themselves.
+func (k *Keys) Keys(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error) {
+	const op = "apikey.Keys"
+
+	keys, err := k.keyStorage.APIKeys(ctx, userID)
+	if err != nil {
+		k.log.Error("failed to get api keys", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// Revoke deletes API key of the user and records the revocation in the audit log.
+//
+// If the user has no such key, returns ErrKeyNotFound.
+func (k *Keys) Revoke(ctx context.Context, userID, keyID uuid.UUID) error {
+	const op = "apikey.Revoke"
+	log := k.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+		slog.String("key_id", keyID.String()),
+	)
+
+	if err := k.keyStorage.DeleteAPIKey(ctx, userID, keyID); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+		}
+
+		log.Error("failed to delete api key", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("api key revoked")
+
+	err := k.auditLog.Record(ctx, &entity.AuditEvent{
+		TenantID: tenancy.FromContext(ctx),
+		Type:     entity.AuditTokenRevocation,
+		UserID:   userID,
+		Outcome:  entity.OutcomeSuccess,
+		Details:  map[string]string{"api_key_id": keyID.String()},
+	})
+	if err != nil {
+		log.Error("failed to record audit event", sl.Err(err))
+	}
+
+	return nil
+}
+
+// Exchange returns access token of the owner of the API key restricted to scopes of the key.
+// The token doesn't outlive the key and doesn't carry roles of the owner.
+//
+// If the key is unknown, malformed or expired, returns ErrInvalidKey.
+func (k *Keys) Exchange(ctx context.Context, rawKey, ip string) (token string, err error) {
+	const op = "apikey.Exchange"
+	log := k.log.With(
+		slog.String("op", op),
+	)
+
+	key, user, err := k.authenticate(ctx, log, rawKey, ip)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	ttl := k.tokenTTL
+	if key.ExpiresAt != nil && time.Until(*key.ExpiresAt) < ttl {
+		ttl = time.Until(*key.ExpiresAt)
+	}
+
+	opts := []jwt.Option{
+		jwt.WithClaims(map[string]any{"api_key_id": key.ID.String()}),
+		jwt.WithoutRoles(),
+	}
+	if len(key.Scopes) > 0 {
+		opts = append(opts, jwt.WithScopes(key.Scopes))
+	}
+
+	token, err = jwt.NewToken(user, k.secret, ttl, opts...)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// Introspect validates access token or API key the caller acts as the user with.
+// Tokens of service accounts and tokens issued to OAuth clients are refused.
+//
+// If the token is invalid or expired, returns ErrInvalidToken.
+// If the token doesn't act as the user, returns ErrUserTokenRequired.
+func (k *Keys) Introspect(ctx context.Context, token, ip string) (*TokenInfo, error) {
+	const op = "apikey.Introspect"
+
+	info, err := k.Validate(ctx, token, ip)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !info.hasTenant || info.ClientID != "" || info.ServiceAccount {
+		return nil, fmt.Errorf("%s: %w", op, ErrUserTokenRequired)
+	}
+
+	return info, nil
+}
+
+// Validate validates access token or API key of any kind and returns what it grants.
+// API keys and tokens exchanged for them carry no roles.
+//
+// If the token is invalid or expired, returns ErrInvalidToken.
+func (k *Keys) Validate(ctx context.Context, token, ip string) (*TokenInfo, error) {
+	const op = "apikey.Validate"
+	log := k.log.With(
+		slog.String("op", op),
+	)
+
+	if strings.HasPrefix(token, KeyPrefix) {
+		key, user, err := k.authenticate(ctx, log, token, ip)
+		if err != nil {
+			if errors.Is(err, ErrInvalidKey) {
+				return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		info := &TokenInfo{
+			UserID:    user.ID,
+			TenantID:  user.TenantID,
+			Scopes:    key.Scopes,
+			APIKeyID:  key.ID,
+			hasTenant: true,
+		}
+		if key.ExpiresAt != nil {
+			info.ExpiresAt = *key.ExpiresAt
+		}
+
+		return info, nil
+	}
+
+	claims, err := jwt.Parse(token, k.secret)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	info := new(TokenInfo)
+
+	subject, _ := claims["sub"].(string)
+	if info.UserID, err = uuid.Parse(subject); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	tenantID, ok := claims["tenant_id"].(string)
+	if ok {
+		if info.TenantID, err = uuid.Parse(tenantID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		info.hasTenant = true
+	}
+
+	if scope, _ := claims["scope"].(string); scope != "" {
+		info.Scopes = strings.Fields(scope)
+	}
+
+	roles, _ := claims["roles"].([]any)
+	for _, role := range roles {
+		if role, ok := role.(string); ok {
+			info.Roles = append(info.Roles, role)
+		}
+	}
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Time
+	}
+
+	keyID, _ := claims["api_key_id"].(string)
+	info.APIKeyID, _ = uuid.Parse(keyID)
+
+	info.ServiceAccount, _ = claims["service_account"].(bool)
+	info.ClientID, _ = claims["client_id"].(string)
+
+	if act, ok := claims["act"].(map[string]any); ok {
+		actorID, _ := act["sub"].(string)
+		info.ActorID, _ = uuid.Parse(actorID)
+	}
+
+	return info, nil
+}
+
+// authenticate finds the API key, checks it and records its use.
+func (k *Keys) authenticate(ctx context.Context, log *slog.Logger, rawKey, ip string) (*entity.APIKey, *entity.User, error) {
+	prefix, secret, ok := parse(rawKey)
+	if !ok {
+		return nil, nil, ErrInvalidKey
+	}
+
+	key, err := k.keyStorage.APIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			log.Warn("api key not found")
+
+			return nil, nil, ErrInvalidKey
+		}
+
+		log.Error("failed to get api key", sl.Err(err))
+
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare(key.KeyHash, hash(secret)) != 1 {
+		log.Warn("api key secret mismatch", slog.String("prefix", prefix))
+
+		return nil, nil, ErrInvalidKey
+	}
+
+	if key.Expired(time.Now()) {
+		log.Warn("api key expired", slog.String("prefix", prefix))
+
+		return nil, nil, ErrInvalidKey
+	}
+
+	if err := k.keyStorage.TouchAPIKey(ctx, key.ID, ip); err != nil {
+		// usage tracking must not lock users out
+		log.Error("failed to record api key use", sl.Err(err))
+	}
+
+	user, err := k.userProvider.UserByID(ctx, key.UserID)
+	if err != nil {
+		log.Error("failed to get owner of api key", sl.Err(err))
+
+		return nil, nil, err
+	}
+
+	return key, user, nil
+}
+
+// generate returns random lookup prefix and secret of a new key.
+func generate() (prefix, secret string, err error) {
+	b := make([]byte, 6+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(b[:6]), base64.RawURLEncoding.EncodeToString(b[6:]), nil
+}
+
+// parse splits key of form ak_<prefix>_<secret>. The prefix is hex, so the first
+// underscore after it separates the secret, which may contain underscores itself.
+func parse(key string) (prefix, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(key, KeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+
+	prefix, secret, ok = strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", "", false
+	}
+
+	return prefix, secret, true
+}
+
+// hash returns SHA-256 of the secret. Unlike passwords, secrets have 256 bits
+// of entropy, so a fast hash is enough and keeps validation cheap.
+func hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+
+	return sum[:]
+}
@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_GeneratedKeys(t *testing.T) {
+	// secrets are base64url and often contain underscores, parse must split on the first one
+	for range 100 {
+		prefix, secret, err := generate()
+		require.NoError(t, err)
+
+		gotPrefix, gotSecret, ok := parse(KeyPrefix + prefix + "_" + secret)
+		require.True(t, ok)
+		assert.Equal(t, prefix, gotPrefix)
+		assert.Equal(t, secret, gotSecret)
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []string{
+		"",
+		"ak_",
+		"ak_prefix",
+		"ak_prefix_",
+		"ak__secret",
+		"sk_prefix_secret",
+		strings.Repeat("a", 64),
+	}
+
+	for _, key := range tests {
+		_, _, ok := parse(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	const secret = "secret"
+
+	keys := New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil, secret, time.Hour)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", Roles: []string{entity.RoleAdmin}}
+
+	token := func(token string, err error) string {
+		require.NoError(t, err)
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"user", token(jwt.NewToken(user, secret, time.Hour)), nil},
+		{"delegated to client", token(jwt.NewToken(user, secret, time.Hour, jwt.WithClientID("client"), jwt.WithoutRoles())), ErrUserTokenRequired},
+		{"client", token(jwt.NewClientToken(uuid.NewString(), secret, time.Hour)), ErrUserTokenRequired},
+		{"service account", token(jwt.NewServiceAccountToken(&entity.ServiceAccount{ID: uuid.New()}, secret, time.Hour)), ErrUserTokenRequired},
+		{"forged", "forged", ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := keys.Introspect(context.Background(), tt.token, "")
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, user.ID, info.UserID)
+			}
+
+			// any valid token passes validation
+			_, err = keys.Validate(context.Background(), tt.token, "")
+			if tt.err == ErrInvalidToken {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/metrics"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+type Log struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+type Storage interface {
+	SaveAuditEvent(ctx context.Context, event *entity.AuditEvent) (eventID int64, err error)
+	AuditEvents(ctx context.Context, filter entity.AuditFilter, limit int) ([]*entity.AuditEvent, error)
+}
+
+// New returns new instance of audit Log.
+func New(log *slog.Logger, storage Storage) *Log {
+	return &Log{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Record appends the event to the audit log and counts it in metrics.
+// Address and user agent of the client are taken from the context unless set.
+func (l *Log) Record(ctx context.Context, event *entity.AuditEvent) error {
+	const op = "audit.Record"
+
+	client := clientinfo.FromContext(ctx)
+	if event.IP == "" {
+		event.IP = client.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	log := l.log.With(
+		slog.String("op", op),
+		slog.String("type", event.Type),
+		slog.String("tenant_id", event.TenantID.String()),
+		slog.String("user_id", event.UserID.String()),
+		slog.String("outcome", event.Outcome),
+		slog.String("ip", event.IP),
+	)
+	if event.ActorID != uuid.Nil {
+		log = log.With(slog.String("actor_id", event.ActorID.String()))
+	}
+
+	metrics.ObserveAuditEvent(event)
+
+	eventID, err := l.storage.SaveAuditEvent(ctx, event)
+	if err != nil {
+		log.Error("failed to save audit event", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	event.ID = eventID
+
+	log.Info("audit event recorded", slog.Int64("event_id", eventID))
+
+	return nil
+}
+
+// Query returns page of events of the context tenant matching the filter, newest first,
+// and token of the next page, which is empty on the last page.
+// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+//
+// If the page token is malformed, returns ErrInvalidPageToken.
+func (l *Log) Query(ctx context.Context, filter entity.AuditFilter, pageSize int, pageToken string) ([]*entity.AuditEvent, string, error) {
+	const op = "audit.Query"
+
+	if pageToken != "" {
+		afterID, err := strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || afterID <= 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+
+		filter.AfterID = afterID
+	}
+
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	pageSize = min(pageSize, MaxPageSize)
+
+	// one extra event tells whether there is a next page
+	events, err := l.storage.AuditEvents(ctx, filter, pageSize+1)
+	if err != nil {
+		l.log.Error("failed to get audit events", slog.String("op", op), sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(events) <= pageSize {
+		return events, "", nil
+	}
+
+	events = events[:pageSize]
+
+	return events, strconv.FormatInt(events[pageSize-1].ID, 10), nil
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage keeps events in insertion order, ids start at 1.
+type memoryStorage struct {
+	events []*entity.AuditEvent
+}
+
+func (m *memoryStorage) SaveAuditEvent(_ context.Context, event *entity.AuditEvent) (int64, error) {
+	m.events = append(m.events, event)
+	return int64(len(m.events)), nil
+}
+
+func (m *memoryStorage) AuditEvents(_ context.Context, filter entity.AuditFilter, limit int) ([]*entity.AuditEvent, error) {
+	var events []*entity.AuditEvent
+	for i := len(m.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if filter.AfterID != 0 && m.events[i].ID >= filter.AfterID {
+			continue
+		}
+		events = append(events, m.events[i])
+	}
+	return events, nil
+}
+
+func TestRecord_ClientInfo(t *testing.T) {
+	storage := new(memoryStorage)
+	log := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage)
+
+	ctx := clientinfo.WithInfo(context.Background(), clientinfo.Info{IP: "192.0.2.1", UserAgent: "grpc-go"})
+
+	event := &entity.AuditEvent{Type: entity.AuditLogin, Outcome: entity.OutcomeSuccess}
+	require.NoError(t, log.Record(ctx, event))
+
+	assert.Equal(t, int64(1), event.ID)
+	assert.Equal(t, "192.0.2.1", event.IP)
+	assert.Equal(t, "grpc-go", event.UserAgent)
+	assert.False(t, event.CreatedAt.IsZero())
+}
+
+func TestQuery_Pagination(t *testing.T) {
+	storage := new(memoryStorage)
+	log := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage)
+
+	ctx := context.Background()
+	for range 5 {
+		require.NoError(t, log.Record(ctx, &entity.AuditEvent{Type: entity.AuditLogin}))
+	}
+
+	var ids []int64
+	var pages int
+	pageToken := ""
+	for {
+		events, next, err := log.Query(ctx, entity.AuditFilter{}, 2, pageToken)
+		require.NoError(t, err)
+
+		pages++
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, ids)
+
+	for _, token := range []string{"abc", "-1", "0"} {
+		_, _, err := log.Query(ctx, entity.AuditFilter{}, 2, token)
+		assert.ErrorIs(t, err, ErrInvalidPageToken, token)
+	}
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+// ChangeEmail changes email of the user after checking the current password.
+//
+// If the password is incorrect, returns ErrInvalidCredentials.
+// If the email is taken by another user, returns ErrUserExists.
+// If the user is not found, returns ErrUserNotFound.
+func (a *Auth) ChangeEmail(ctx context.Context, userID uuid.UUID, password, email string) error {
+	const op = "auth.ChangeEmail"
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	user, err := a.checkPassword(ctx, log, userID, password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.userSaver.UpdateUserEmail(ctx, userID, email); err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			log.Warn("email is taken", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to update email", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email changed")
+
+	a.record(ctx, log, user.TenantID, user.ID, entity.AuditEmailChange, entity.OutcomeSuccess, map[string]string{
+		"old_email": user.Email,
+		"email":     email,
+	})
+
+	return nil
+}
+
+// ChangePhone changes phone of the user after checking the current password,
+// empty phone removes it. The phone receives one-time passcodes of the SMS channel.
+//
+// If the password is incorrect, returns ErrInvalidCredentials.
+// If the phone is taken by another user, returns ErrUserExists.
+// If the user is not found, returns ErrUserNotFound.
+func (a *Auth) ChangePhone(ctx context.Context, userID uuid.UUID, password, phone string) error {
+	const op = "auth.ChangePhone"
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	user, err := a.checkPassword(ctx, log, userID, password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.userSaver.UpdateUserPhone(ctx, userID, phone); err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			log.Warn("phone is taken", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to update phone", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("phone changed")
+
+	a.record(ctx, log, user.TenantID, user.ID, entity.AuditPhoneChange, entity.OutcomeSuccess, map[string]string{
+		"removed": strconv.FormatBool(phone == ""),
+	})
+
+	return nil
+}
+
+// DeleteAccount deletes the user after checking the password.
+//
+// If the password is incorrect, returns ErrInvalidCredentials.
+// If the user is not found, returns ErrUserNotFound.
+func (a *Auth) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error {
+	const op = "auth.DeleteAccount"
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	user, err := a.checkPassword(ctx, log, userID, password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.userSaver.DeleteUser(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to delete user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("account deleted")
+
+	a.record(ctx, log, user.TenantID, user.ID, entity.AuditAccountDeletion, entity.OutcomeSuccess, map[string]string{
+		"email": user.Email,
+	})
+
+	return nil
+}
+
+// checkPassword returns the user if the password is correct.
+func (a *Auth) checkPassword(ctx context.Context, log *slog.Logger, userID uuid.UUID, password string) (*entity.User, error) {
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, err
+	}
+
+	if err := comparePassword(ctx, user.PassHash, []byte(password)); err != nil {
+		log.Warn("invalid password", sl.Err(err))
+
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
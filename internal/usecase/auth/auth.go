@@ -2,24 +2,42 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kurochkinivan/auth/internal/entity"
 	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/ldap"
+	"github.com/kurochkinivan/auth/internal/lib/metrics"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
 	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
 	"github.com/kurochkinivan/auth/internal/usecase/repository"
-	"golang.org/x/crypto/bcrypt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
+var tracer = otel.Tracer("github.com/kurochkinivan/auth/internal/usecase/auth")
+
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidAppID       = errors.New("invalid app id")
 	ErrUserExists         = errors.New("user exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrMFARequired        = errors.New("mfa required")
+	ErrLoginDenied        = errors.New("login denied")
+)
+
+// Login methods, see Admit.
+const (
+	MethodPassword   = "password"
+	MethodOTP        = "otp"
+	MethodFederation = "federation"
 )
 
 type Auth struct {
@@ -27,82 +45,329 @@ type Auth struct {
 	secret       string
 	userSaver    UserSaver
 	userProvider UserProvider
+	directory    Directory
+	auditLog     AuditLog
+	loginHistory LoginHistory
+	risk         Risk
+	hardening    *Hardening
+	dummyHash    []byte
 	tokentTTL    time.Duration
 }
 
 type UserSaver interface {
 	SaveUser(ctx context.Context, email string, passHash []byte) (userID uuid.UUID, err error)
+	SaveDirectoryUser(ctx context.Context, email string, passHash []byte, roles []string) (userID uuid.UUID, err error)
+	UpdateUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error
+	UpdateUserEmail(ctx context.Context, userID uuid.UUID, email string) error
+	UpdateUserPhone(ctx context.Context, userID uuid.UUID, phone string) error
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (*entity.User, error)
+	UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+}
+
+// Directory is an external user directory, e.g. LDAP or Active Directory.
+type Directory interface {
+	Authenticate(ctx context.Context, email, password string) (*ldap.User, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, event *entity.AuditEvent) error
 }
 
-// New returns new instance of Auth service
-func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, secret string, tokenTTL time.Duration) *Auth {
-	return &Auth{
+type LoginHistory interface {
+	Record(ctx context.Context, user *entity.User) error
+}
+
+// Risk scores sign-ins and decides whether to allow them.
+type Risk interface {
+	Assess(ctx context.Context, user *entity.User) (*entity.RiskAssessment, error)
+}
+
+// New returns new instance of Auth service.
+// Directory is optional, if nil, users are authenticated only by local password.
+// LoginHistory is optional, if nil, logins are not recorded.
+// Risk is optional, if nil, every login with valid credentials is allowed.
+// Hardening is optional, if nil, Login and Register reveal which emails are registered.
+func New(
+	log *slog.Logger,
+	userSaver UserSaver,
+	userProvider UserProvider,
+	directory Directory,
+	auditLog AuditLog,
+	loginHistory LoginHistory,
+	risk Risk,
+	hardening *Hardening,
+	secret string,
+	tokenTTL time.Duration,
+) *Auth {
+	a := &Auth{
 		log:          log,
 		secret:       secret,
 		userSaver:    userSaver,
 		userProvider: userProvider,
+		directory:    directory,
+		auditLog:     auditLog,
+		loginHistory: loginHistory,
+		risk:         risk,
+		hardening:    hardening,
 		tokentTTL:    tokenTTL,
 	}
+
+	if hardening != nil {
+		a.dummyHash = newDummyHash()
+	}
+
+	return a
 }
 
 // Login checks if user with given credentials exists in the system
 //
 // If exists, but password is incorrect, returns error.
 // If user doesn't exist, returns error
+// If the login is too risky, returns ErrMFARequired or ErrLoginDenied.
 func (a *Auth) Login(ctx context.Context, email, password string) (token string, err error) {
 	const op = "auth.Login"
-	log := a.log.With(
+	log := a.logger(ctx).With(
 		slog.String("op", op),
 	)
 
+	defer a.pad(ctx, time.Now())
+
 	log.Info("attempting to login user")
 
+	user, err := a.Authenticate(ctx, email, password)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.Admit(ctx, user, MethodPassword); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in successfully")
+
+	token, err = a.NewToken(ctx, user)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// Admit decides whether the user authenticated with the method may log in and records
+// the login in the history. Every login path calls it once credentials of the user are checked.
+//
+// If the login is too risky, returns ErrMFARequired or ErrLoginDenied. One-time passcode
+// is the second factor the risk engine asks for, so logins with MethodOTP can only be denied.
+func (a *Auth) Admit(ctx context.Context, user *entity.User, method string) error {
+	const op = "auth.Admit"
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+		slog.String("method", method),
+	)
+
+	if err := a.assess(ctx, log, user); err != nil {
+		if !errors.Is(err, ErrMFARequired) || method != MethodOTP {
+			reason := "risk_" + entity.RiskDeny
+			if errors.Is(err, ErrMFARequired) {
+				reason = "risk_" + entity.RiskMFA
+			}
+			metrics.ObserveLogin(reason)
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("one-time passcode satisfies mfa")
+	}
+
+	metrics.ObserveLogin("")
+
+	if a.loginHistory != nil {
+		if err := a.loginHistory.Record(ctx, user); err != nil {
+			// history must not lock users out
+			log.Error("failed to record login", sl.Err(err))
+		}
+	}
+
+	return nil
+}
+
+// assess returns ErrMFARequired or ErrLoginDenied if the risk engine doesn't allow the login.
+func (a *Auth) assess(ctx context.Context, log *slog.Logger, user *entity.User) error {
+	if a.risk == nil {
+		return nil
+	}
+
+	assessment, err := a.risk.Assess(ctx, user)
+	if err != nil {
+		// risk engine must not lock users out
+		log.Error("failed to assess login risk", sl.Err(err))
+
+		return nil
+	}
+
+	switch assessment.Decision {
+	case entity.RiskMFA:
+		log.Warn("login requires mfa", slog.Int("risk_score", assessment.Score))
+
+		return ErrMFARequired
+	case entity.RiskDeny:
+		log.Warn("login denied", slog.Int("risk_score", assessment.Score))
+
+		return ErrLoginDenied
+	default:
+		return nil
+	}
+}
+
+// Authenticate checks credentials of the user and returns the user on success.
+//
+// If the directory is configured, it is asked first. Users unknown to the directory
+// are checked against local password, as well as all users while the directory is unavailable.
+// Every attempt is recorded in the audit log.
+// If user doesn't exist or password is incorrect, returns ErrInvalidCredentials.
+func (a *Auth) Authenticate(ctx context.Context, email, password string) (*entity.User, error) {
+	const op = "auth.Authenticate"
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+	)
+
+	if a.directory != nil {
+		dirUser, err := a.directory.Authenticate(ctx, email, password)
+		switch {
+		case err == nil:
+			user, err := a.syncDirectoryUser(ctx, log, email, dirUser.Roles)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			a.record(ctx, log, user.TenantID, user.ID, entity.AuditLogin, entity.OutcomeSuccess, map[string]string{
+				"method": "directory",
+			})
+
+			return user, nil
+		case errors.Is(err, ldap.ErrInvalidCredentials):
+			log.Warn("invalid directory credentials", sl.Err(err))
+
+			a.record(ctx, log, tenancy.FromContext(ctx), uuid.Nil, entity.AuditLogin, entity.OutcomeFailure, map[string]string{
+				"method": "directory",
+				"email":  email,
+				"reason": "invalid_credentials",
+			})
+
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		case errors.Is(err, ldap.ErrUserNotFound):
+			// local user
+		default:
+			log.Error("directory is unavailable, falling back to local password", sl.Err(err))
+		}
+	}
+
 	user, err := a.userProvider.User(ctx, email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			log.Warn("user not found", sl.Err(err))
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			a.compareDummyHash(ctx, password)
+
+			a.record(ctx, log, tenancy.FromContext(ctx), uuid.Nil, entity.AuditLogin, entity.OutcomeFailure, map[string]string{
+				"method": "password",
+				"email":  email,
+				"reason": "user_not_found",
+			})
+
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
 
 		log.Error("failed to get user", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+	if err := comparePassword(ctx, user.PassHash, []byte(password)); err != nil {
 		log.Warn("invalid credentials", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		a.record(ctx, log, user.TenantID, user.ID, entity.AuditLogin, entity.OutcomeFailure, map[string]string{
+			"method": "password",
+			"reason": "invalid_password",
+		})
+
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
-	log.Info("user logged in successfully")
+	a.record(ctx, log, user.TenantID, user.ID, entity.AuditLogin, entity.OutcomeSuccess, map[string]string{
+		"method": "password",
+	})
+
+	return user, nil
+}
+
+// syncDirectoryUser returns local user authenticated by the directory with roles taken from the directory.
+// Unknown users are provisioned with a random password, so they can't log in while
+// the directory is unavailable.
+func (a *Auth) syncDirectoryUser(ctx context.Context, log *slog.Logger, email string, roles []string) (*entity.User, error) {
+	user, err := a.userProvider.User(ctx, email)
+	switch {
+	case err == nil:
+		if slices.Equal(user.Roles, roles) {
+			return user, nil
+		}
 
-	token, err = jwt.NewToken(user, a.secret, a.tokentTTL)
+		if err := a.userSaver.UpdateUserRoles(ctx, user.ID, roles); err != nil {
+			log.Error("failed to update user roles", sl.Err(err))
+
+			return nil, err
+		}
+
+		user.Roles = roles
+
+		return user, nil
+	case errors.Is(err, repository.ErrUserNotFound):
+	default:
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, err
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+
+	passHash, err := hashPassword(ctx, password)
 	if err != nil {
-		log.Error("failed to generate token", sl.Err(err))
+		log.Error("failed to generate password hash", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
-	return token, nil
+	if _, err := a.userSaver.SaveDirectoryUser(ctx, email, passHash, roles); err != nil {
+		log.Error("failed to provision directory user", sl.Err(err))
+
+		return nil, err
+	}
+
+	log.Info("directory user provisioned")
+
+	return a.userProvider.User(ctx, email)
 }
 
 // RegisterNewUser registers new user in the system and returns user id.
 // If a user with given email already exists, returns error
 func (a *Auth) RegisterNewUser(ctx context.Context, email, password string) (userID uuid.UUID, err error) {
 	const op = "auth.RegisterNewUser"
-	log := a.log.With(
+	log := a.logger(ctx).With(
 		slog.String("op", op),
 	)
 
 	log.Info("registering user")
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passHash, err := hashPassword(ctx, []byte(password))
 	if err != nil {
 		log.Error("failed to generate password hash", sl.Err(err))
 
@@ -114,6 +379,11 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email, password string) (use
 		if errors.Is(err, repository.ErrUserExists) {
 			log.Warn("user exists", sl.Err(err))
 
+			a.record(ctx, log, tenancy.FromContext(ctx), uuid.Nil, entity.AuditRegister, entity.OutcomeFailure, map[string]string{
+				"email":  email,
+				"reason": "user_exists",
+			})
+
 			return uuid.Nil, ErrUserExists
 		}
 
@@ -124,5 +394,60 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email, password string) (use
 
 	log.Info("user registered")
 
+	a.record(ctx, log, tenancy.FromContext(ctx), userID, entity.AuditRegister, entity.OutcomeSuccess, map[string]string{
+		"email": email,
+	})
+
 	return userID, nil
 }
+
+// hashPassword returns hash of the password, hashing is traced as it dominates latency of the callers.
+func hashPassword(ctx context.Context, password []byte) ([]byte, error) {
+	_, span := tracer.Start(ctx, "auth.hashPassword")
+	defer span.End()
+
+	return passhash.Hash(password)
+}
+
+// comparePassword returns nil if the hash is hash of the password, comparison is traced.
+func comparePassword(ctx context.Context, hash, password []byte) error {
+	_, span := tracer.Start(ctx, "auth.comparePassword")
+	defer span.End()
+
+	return passhash.Compare(hash, password)
+}
+
+// NewToken returns signed access token of the user, signing is traced.
+// Other login methods issue their tokens with it, so all of them share claims and TTL.
+func (a *Auth) NewToken(ctx context.Context, user *entity.User) (string, error) {
+	_, span := tracer.Start(ctx, "auth.NewToken")
+	defer span.End()
+
+	token, err := jwt.NewToken(user, a.secret, a.tokentTTL)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return token, err
+}
+
+// logger returns logger of the request context, e.g. with request id, or logger of the service.
+func (a *Auth) logger(ctx context.Context) *slog.Logger {
+	return sl.FromContext(ctx, a.log)
+}
+
+// record writes event to the audit log.
+// Failures are only logged, so the audit log being unavailable doesn't lock users out.
+func (a *Auth) record(ctx context.Context, log *slog.Logger, tenantID, userID uuid.UUID, eventType, outcome string, details map[string]string) {
+	err := a.auditLog.Record(ctx, &entity.AuditEvent{
+		TenantID: tenantID,
+		Type:     eventType,
+		UserID:   userID,
+		Outcome:  outcome,
+		Details:  details,
+	})
+	if err != nil {
+		log.Error("failed to record audit event", sl.Err(err))
+	}
+}
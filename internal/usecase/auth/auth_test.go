@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedRisk string
+
+func (r fixedRisk) Assess(context.Context, *entity.User) (*entity.RiskAssessment, error) {
+	return &entity.RiskAssessment{Decision: string(r)}, nil
+}
+
+type countingHistory int
+
+func (h *countingHistory) Record(context.Context, *entity.User) error {
+	*h++
+	return nil
+}
+
+func TestAdmit(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision string
+		method   string
+		err      error
+	}{
+		{"allow password", entity.RiskAllow, MethodPassword, nil},
+		{"mfa password", entity.RiskMFA, MethodPassword, ErrMFARequired},
+		{"mfa federation", entity.RiskMFA, MethodFederation, ErrMFARequired},
+		{"mfa otp", entity.RiskMFA, MethodOTP, nil},
+		{"deny otp", entity.RiskDeny, MethodOTP, ErrLoginDenied},
+		{"deny federation", entity.RiskDeny, MethodFederation, ErrLoginDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newMemoryStorage()
+			history := new(countingHistory)
+			a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, storage, nil, storage, history, fixedRisk(tt.decision), nil, "secret", time.Hour)
+
+			err := a.Admit(context.Background(), &entity.User{ID: uuid.New()}, tt.method)
+			assert.ErrorIs(t, err, tt.err)
+
+			if tt.err == nil {
+				assert.Equal(t, 1, int(*history), "admitted login is recorded")
+			} else {
+				assert.Zero(t, int(*history))
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+)
+
+// Hardening hides which emails are registered from responses of Login and Register:
+// unknown emails cost the same password check as known ones, responses are padded
+// toward a constant duration and registration of a taken email looks like a success.
+type Hardening struct {
+	// MinDuration is how long Login and Register take at least, it should exceed their usual duration.
+	// Zero disables padding.
+	MinDuration time.Duration
+	// MailSender tells owners of taken emails that someone tried to register them.
+	MailSender MailSender
+}
+
+type MailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// Register registers new user in the system for the public registration endpoint.
+//
+// Unless hardened, it is RegisterNewUser. When hardened, the user id is not returned and
+// registration of a taken email succeeds as well, while the owner of the email is notified,
+// so the response doesn't reveal whether the email is registered. Users learn their id by signing in.
+func (a *Auth) Register(ctx context.Context, email, password string) (userID uuid.UUID, err error) {
+	const op = "auth.Register"
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+	)
+
+	if a.hardening == nil {
+		return a.RegisterNewUser(ctx, email, password)
+	}
+
+	defer a.pad(ctx, time.Now())
+
+	_, err = a.RegisterNewUser(ctx, email, password)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrUserExists):
+		a.notifyTakenEmail(ctx, log, email)
+	default:
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return uuid.Nil, nil
+}
+
+// notifyTakenEmail tells the owner of the email about the registration attempt in background,
+// so sending doesn't slow down the response.
+func (a *Auth) notifyTakenEmail(ctx context.Context, log *slog.Logger, email string) {
+	if a.hardening.MailSender == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		err := a.hardening.MailSender.Send(ctx, email,
+			"Registration attempt",
+			"Someone tried to create an account with your email address.\n\n"+
+				"If it was you, you already have an account and can sign in with it. "+
+				"Otherwise you can ignore this email.",
+		)
+		if err != nil {
+			log.Error("failed to notify about registration attempt", sl.Err(err))
+		}
+	}()
+}
+
+// pad sleeps until the hardening minimal duration passes since start, unless the context is done.
+// Slower calls are not padded.
+func (a *Auth) pad(ctx context.Context, start time.Time) {
+	if a.hardening == nil || a.hardening.MinDuration <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(time.Until(start.Add(a.hardening.MinDuration)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// compareDummyHash spends the time checking password of a user takes, when hardened,
+// so unknown emails are not answered faster than known ones.
+func (a *Auth) compareDummyHash(ctx context.Context, password string) {
+	if a.dummyHash == nil {
+		return
+	}
+
+	_ = comparePassword(ctx, a.dummyHash, []byte(password))
+}
+
+// newDummyHash returns hash of a random password with the cost of real password hashes.
+func newDummyHash() []byte {
+	password := make([]byte, 32)
+	_, _ = rand.Read(password)
+
+	// fails only for invalid cost or passwords longer than 72 bytes
+	hash, _ := passhash.Hash(password)
+
+	return hash
+}
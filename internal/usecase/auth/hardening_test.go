@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type memoryStorage struct {
+	mu    sync.Mutex
+	users map[string]*entity.User
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{users: make(map[string]*entity.User)}
+}
+
+func (m *memoryStorage) SaveUser(_ context.Context, email string, passHash []byte) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[email]; ok {
+		return uuid.Nil, repository.ErrUserExists
+	}
+
+	user := &entity.User{ID: uuid.New(), Email: email, PassHash: passHash}
+	m.users[email] = user
+
+	return user.ID, nil
+}
+
+func (m *memoryStorage) SaveDirectoryUser(ctx context.Context, email string, passHash []byte, _ []string) (uuid.UUID, error) {
+	return m.SaveUser(ctx, email, passHash)
+}
+
+func (m *memoryStorage) UpdateUserRoles(context.Context, uuid.UUID, []string) error { return nil }
+func (m *memoryStorage) UpdateUserEmail(context.Context, uuid.UUID, string) error   { return nil }
+func (m *memoryStorage) UpdateUserPhone(context.Context, uuid.UUID, string) error   { return nil }
+func (m *memoryStorage) DeleteUser(context.Context, uuid.UUID) error                { return nil }
+func (m *memoryStorage) UserByID(context.Context, uuid.UUID) (*entity.User, error) {
+	return nil, repository.ErrUserNotFound
+}
+func (m *memoryStorage) Record(context.Context, *entity.AuditEvent) error { return nil }
+
+func (m *memoryStorage) User(_ context.Context, email string) (*entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[email]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+type chanMailSender chan string
+
+func (c chanMailSender) Send(_ context.Context, to, _, _ string) error {
+	c <- to
+	return nil
+}
+
+func newAuth(t *testing.T, hardening *Hardening) (*Auth, *memoryStorage) {
+	t.Helper()
+
+	storage := newMemoryStorage()
+	a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, storage, nil, storage, nil, nil, hardening, "secret", time.Hour)
+
+	return a, storage
+}
+
+// spans records spans of the package tracer, the global provider can be set only once.
+var spans = tracetest.NewSpanRecorder()
+
+func init() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+}
+
+// passwordChecks returns how many password hashes fn compared.
+func passwordChecks(fn func()) int {
+	count := func() int {
+		n := 0
+		for _, span := range spans.Ended() {
+			if span.Name() == "auth.comparePassword" {
+				n++
+			}
+		}
+		return n
+	}
+
+	before := count()
+	fn()
+
+	return count() - before
+}
+
+func TestLogin_UnknownEmail(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		hardening *Hardening
+		// checks is how many password hashes login of an unknown email compares
+		checks int
+	}{
+		{"unhardened login skips password check", nil, 0},
+		{"hardened login checks dummy hash", &Hardening{}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, storage := newAuth(t, tt.hardening)
+
+			passHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+			require.NoError(t, err)
+			_, err = storage.SaveUser(ctx, "known@example.com", passHash)
+			require.NoError(t, err)
+
+			var unknownErr, wrongPasswordErr error
+
+			checks := passwordChecks(func() {
+				_, unknownErr = a.Login(ctx, "unknown@example.com", "password")
+			})
+			assert.Equal(t, tt.checks, checks)
+
+			checks = passwordChecks(func() {
+				_, wrongPasswordErr = a.Login(ctx, "known@example.com", "wrong password")
+			})
+			assert.Equal(t, 1, checks)
+
+			assert.ErrorIs(t, unknownErr, ErrInvalidCredentials)
+			assert.ErrorIs(t, wrongPasswordErr, ErrInvalidCredentials)
+			assert.Equal(t, wrongPasswordErr.Error(), unknownErr.Error(), "responses must not differ")
+		})
+	}
+}
+
+func TestPadding(t *testing.T) {
+	ctx := context.Background()
+	minDuration := 50 * time.Millisecond
+
+	a, storage := newAuth(t, &Hardening{MinDuration: minDuration})
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, err = storage.SaveUser(ctx, "known@example.com", passHash)
+	require.NoError(t, err)
+
+	for name, login := range map[string]func(){
+		"success":        func() { _, _ = a.Login(ctx, "known@example.com", "password") },
+		"wrong password": func() { _, _ = a.Login(ctx, "known@example.com", "wrong password") },
+		"unknown email":  func() { _, _ = a.Login(ctx, "unknown@example.com", "password") },
+	} {
+		start := time.Now()
+		login()
+		assert.GreaterOrEqual(t, time.Since(start), minDuration, name)
+	}
+
+	a.hardening.MinDuration = time.Hour
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	start := time.Now()
+	a.pad(cancelled, start)
+	assert.Less(t, time.Since(start), time.Minute, "padding must stop once the client is gone")
+}
+
+func TestRegister_Hardened(t *testing.T) {
+	ctx := context.Background()
+	mail := make(chanMailSender, 1)
+
+	a, _ := newAuth(t, &Hardening{MailSender: mail})
+
+	_, err := a.RegisterNewUser(ctx, "taken@example.com", "password")
+	require.NoError(t, err)
+
+	userID, newErr := a.Register(ctx, "new@example.com", "password")
+	assert.Equal(t, uuid.Nil, userID)
+
+	userID, takenErr := a.Register(ctx, "taken@example.com", "password")
+	assert.Equal(t, uuid.Nil, userID)
+
+	assert.NoError(t, newErr)
+	assert.NoError(t, takenErr, "taken email must look registered")
+
+	select {
+	case to := <-mail:
+		assert.Equal(t, "taken@example.com", to)
+	case <-time.After(time.Second):
+		t.Fatal("owner of the taken email is not notified")
+	}
+
+	// registrations through invitations still learn the email is taken
+	_, err = a.RegisterNewUser(ctx, "taken@example.com", "password")
+	assert.ErrorIs(t, err, ErrUserExists)
+}
+
+func TestRegister_Unhardened(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newAuth(t, nil)
+
+	userID, err := a.Register(ctx, "user@example.com", "password")
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, userID)
+
+	_, err = a.Register(ctx, "user@example.com", "password")
+	assert.ErrorIs(t, err, ErrUserExists)
+}
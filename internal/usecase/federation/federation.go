@@ -0,0 +1,199 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/otp"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrEmailRequired    = errors.New("email is required")
+	ErrEmailNotVerified = errors.New("email is not verified")
+)
+
+type Federation struct {
+	log             *slog.Logger
+	secret          string
+	userProvider    UserProvider
+	identityStorage IdentityStorage
+	gate            LoginGate
+	codeSender      CodeSender
+	tokenTTL        time.Duration
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (*entity.User, error)
+	UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+}
+
+type IdentityStorage interface {
+	Identity(ctx context.Context, provider, subject string) (*entity.Identity, error)
+	SaveIdentity(ctx context.Context, identity *entity.Identity) error
+	SaveUserWithIdentity(ctx context.Context, email string, passHash []byte, identity *entity.Identity) (userID uuid.UUID, err error)
+}
+
+// LoginGate decides whether the authenticated user may log in, see auth.Auth.Admit.
+type LoginGate interface {
+	Admit(ctx context.Context, user *entity.User, method string) error
+}
+
+// CodeSender sends one-time passcode the user completes risky logins with, see otp.OTP.SendCode.
+type CodeSender interface {
+	SendCode(ctx context.Context, email, channel string) error
+}
+
+// New returns new instance of Federation service
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	identityStorage IdentityStorage,
+	gate LoginGate,
+	codeSender CodeSender,
+	secret string,
+	tokenTTL time.Duration,
+) *Federation {
+	return &Federation{
+		log:             log,
+		secret:          secret,
+		userProvider:    userProvider,
+		identityStorage: identityStorage,
+		gate:            gate,
+		codeSender:      codeSender,
+		tokenTTL:        tokenTTL,
+	}
+}
+
+// Login logs in the user authenticated by an external identity provider and returns token.
+//
+// Known identities log in as the linked user. Unknown identities are linked to the user
+// with the same email, or a new user is provisioned with the email. Both require
+// the provider to have verified the email, otherwise returns ErrEmailNotVerified.
+// If the login is too risky, returns auth.ErrLoginDenied, or auth.ErrMFARequired once
+// a one-time passcode is sent to the email of the user, the login is completed with it then.
+func (f *Federation) Login(ctx context.Context, provider, subject, email string, emailVerified bool) (token string, err error) {
+	const op = "federation.Login"
+	log := f.log.With(
+		slog.String("op", op),
+		slog.String("provider", provider),
+	)
+
+	log.Info("attempting to login user with external identity")
+
+	user, err := f.user(ctx, log, provider, subject, email, emailVerified)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := f.gate.Admit(ctx, user, auth.MethodFederation); err != nil {
+		if errors.Is(err, auth.ErrMFARequired) {
+			// the code goes to the email of the user, which may differ from the email of the identity
+			if err := f.codeSender.SendCode(ctx, user.Email, otp.ChannelEmail); err != nil {
+				log.Error("failed to send one-time passcode", sl.Err(err))
+
+				return "", fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in successfully")
+
+	token, err = jwt.NewToken(user, f.secret, f.tokenTTL)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+func (f *Federation) user(ctx context.Context, log *slog.Logger, provider, subject, email string, emailVerified bool) (*entity.User, error) {
+	identity, err := f.identityStorage.Identity(ctx, provider, subject)
+	if err == nil {
+		return f.userProvider.UserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		log.Error("failed to get identity", sl.Err(err))
+
+		return nil, err
+	}
+
+	if email == "" {
+		return nil, ErrEmailRequired
+	}
+
+	// an unverified email could claim an account, or a user registering later, of someone else
+	if !emailVerified {
+		log.Warn("refusing identity with unverified email")
+
+		return nil, ErrEmailNotVerified
+	}
+
+	identity = &entity.Identity{
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}
+
+	user, err := f.userProvider.User(ctx, email)
+	switch {
+	case err == nil:
+		identity.UserID = user.ID
+		if err := f.identityStorage.SaveIdentity(ctx, identity); err != nil {
+			log.Error("failed to link identity", sl.Err(err))
+
+			return nil, err
+		}
+
+		log.Info("identity linked to existing user")
+
+		return user, nil
+	case errors.Is(err, repository.ErrUserNotFound):
+		return f.provision(ctx, log, identity)
+	default:
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, err
+	}
+}
+
+// provision creates user for the identity. The user has a random password,
+// so it can log in only through the provider until the password is set.
+func (f *Federation) provision(ctx context.Context, log *slog.Logger, identity *entity.Identity) (*entity.User, error) {
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+
+	passHash, err := passhash.Hash(password)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return nil, err
+	}
+
+	userID, err := f.identityStorage.SaveUserWithIdentity(ctx, identity.Email, passHash, identity)
+	if err != nil {
+		log.Error("failed to provision user", sl.Err(err))
+
+		return nil, err
+	}
+
+	log.Info("user provisioned", slog.String("user_id", userID.String()))
+
+	return f.userProvider.UserByID(ctx, userID)
+}
@@ -0,0 +1,153 @@
+package federation
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/otp"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStorage struct {
+	users      map[string]*entity.User
+	identities map[string]*entity.Identity
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		users:      make(map[string]*entity.User),
+		identities: make(map[string]*entity.Identity),
+	}
+}
+
+func (m *memoryStorage) User(_ context.Context, email string) (*entity.User, error) {
+	user, ok := m.users[email]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (m *memoryStorage) UserByID(_ context.Context, userID uuid.UUID) (*entity.User, error) {
+	for _, user := range m.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *memoryStorage) Identity(_ context.Context, provider, subject string) (*entity.Identity, error) {
+	identity, ok := m.identities[provider+"/"+subject]
+	if !ok {
+		return nil, repository.ErrIdentityNotFound
+	}
+
+	return identity, nil
+}
+
+func (m *memoryStorage) SaveIdentity(_ context.Context, identity *entity.Identity) error {
+	m.identities[identity.Provider+"/"+identity.Subject] = identity
+
+	return nil
+}
+
+func (m *memoryStorage) SaveUserWithIdentity(ctx context.Context, email string, _ []byte, identity *entity.Identity) (uuid.UUID, error) {
+	user := &entity.User{ID: uuid.New(), Email: email}
+	m.users[email] = user
+	identity.UserID = user.ID
+
+	return user.ID, m.SaveIdentity(ctx, identity)
+}
+
+// gate admits logins unless err is set and remembers the admitted ones.
+type gate struct {
+	err      error
+	admitted []string
+}
+
+func (g *gate) Admit(_ context.Context, user *entity.User, method string) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.admitted = append(g.admitted, user.Email+" "+method)
+
+	return nil
+}
+
+// codeSender remembers emails codes were sent to.
+type codeSender []string
+
+func (s *codeSender) SendCode(_ context.Context, email, channel string) error {
+	*s = append(*s, email+" "+channel)
+
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	storage := newMemoryStorage()
+	existing := &entity.User{ID: uuid.New(), Email: "existing@example.com"}
+	storage.users[existing.Email] = existing
+
+	gate := new(gate)
+	f := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, storage, gate, new(codeSender), "secret", time.Hour)
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		email         string
+		emailVerified bool
+		err           error
+	}{
+		{"link verified", existing.Email, true, nil},
+		{"provision verified", "new@example.com", true, nil},
+		{"link unverified", existing.Email, false, ErrEmailNotVerified},
+		{"provision unverified", "squatter@example.com", false, ErrEmailNotVerified},
+		{"no email", "", true, ErrEmailRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.Login(ctx, "idp", uuid.NewString(), tt.email, tt.emailVerified)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	assert.Contains(t, storage.users, "new@example.com")
+	assert.NotContains(t, storage.users, "squatter@example.com", "unverified email is not provisioned")
+	assert.Equal(t, []string{existing.Email + " " + auth.MethodFederation, "new@example.com " + auth.MethodFederation}, gate.admitted)
+}
+
+func TestLogin_Denied(t *testing.T) {
+	storage := newMemoryStorage()
+
+	f := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, storage, &gate{err: auth.ErrLoginDenied}, new(codeSender), "secret", time.Hour)
+
+	token, err := f.Login(context.Background(), "idp", uuid.NewString(), "user@example.com", true)
+	assert.ErrorIs(t, err, auth.ErrLoginDenied)
+	assert.Empty(t, token)
+}
+
+func TestLogin_MFARequired(t *testing.T) {
+	storage := newMemoryStorage()
+	existing := &entity.User{ID: uuid.New(), Email: "existing@example.com"}
+	storage.users[existing.Email] = existing
+	storage.identities["idp/subject"] = &entity.Identity{Provider: "idp", Subject: "subject", UserID: existing.ID}
+
+	sent := new(codeSender)
+	f := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, storage, &gate{err: auth.ErrMFARequired}, sent, "secret", time.Hour)
+
+	token, err := f.Login(context.Background(), "idp", "subject", "renamed@example.com", true)
+	assert.ErrorIs(t, err, auth.ErrMFARequired)
+	assert.Empty(t, token)
+	assert.Equal(t, []string{existing.Email + " " + otp.ChannelEmail}, []string(*sent), "code goes to the email of the user")
+}
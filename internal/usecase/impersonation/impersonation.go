@@ -0,0 +1,118 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrForbidden    = errors.New("user can't be impersonated")
+)
+
+type Impersonation struct {
+	log          *slog.Logger
+	secret       string
+	userProvider UserProvider
+	auditLog     AuditLog
+	tokenTTL     time.Duration
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, event *entity.AuditEvent) error
+}
+
+// New returns new instance of Impersonation service.
+func New(log *slog.Logger, userProvider UserProvider, auditLog AuditLog, secret string, tokenTTL time.Duration) *Impersonation {
+	return &Impersonation{
+		log:          log,
+		secret:       secret,
+		userProvider: userProvider,
+		auditLog:     auditLog,
+		tokenTTL:     tokenTTL,
+	}
+}
+
+// Impersonate returns short-lived access token of the user with act claim identifying the actor.
+// Every issuance is recorded in the audit log, the token is not issued if recording fails.
+//
+// Actors impersonate users of their own tenant, actors of the default tenant impersonate anyone.
+// If the user is not found in a tenant the actor may access, returns ErrUserNotFound.
+// If the user is the actor, an admin or a support engineer, returns ErrForbidden,
+// so impersonation can't be used to gain privileges.
+func (i *Impersonation) Impersonate(ctx context.Context, actorID, actorTenantID, userID uuid.UUID, reason string) (token string, expiresIn time.Duration, err error) {
+	const op = "impersonation.Impersonate"
+	log := i.log.With(
+		slog.String("op", op),
+		slog.String("actor_id", actorID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	user, err := i.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return "", 0, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// users of other tenants don't exist for the actor
+	if actorTenantID != tenancy.Default && actorTenantID != user.TenantID {
+		return "", 0, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	event := &entity.AuditEvent{
+		TenantID: user.TenantID,
+		Type:     entity.AuditImpersonation,
+		UserID:   user.ID,
+		ActorID:  actorID,
+		Outcome:  entity.OutcomeSuccess,
+		Details:  map[string]string{"reason": reason},
+	}
+
+	if user.ID == actorID || slices.Contains(user.Roles, entity.RoleAdmin) || slices.Contains(user.Roles, entity.RoleSupport) {
+		log.Warn("impersonation of privileged user refused")
+
+		event.Outcome = entity.OutcomeFailure
+		if err := i.auditLog.Record(ctx, event); err != nil {
+			log.Error("failed to record audit event", sl.Err(err))
+		}
+
+		return "", 0, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	token, err = jwt.NewToken(user, i.secret, i.tokenTTL, jwt.WithActor(actorID))
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := i.auditLog.Record(ctx, event); err != nil {
+		log.Error("failed to record audit event", sl.Err(err))
+
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("impersonation token issued")
+
+	return token, i.tokenTTL, nil
+}
@@ -0,0 +1,299 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrInvalidInvitation  = errors.New("invitation is invalid or expired")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// tokenType distinguishes invitation tokens from other tokens signed with the same secret.
+const tokenType = "invitation"
+
+type Invitations struct {
+	log               *slog.Logger
+	secret            string
+	auth              Auth
+	userProvider      UserProvider
+	userSaver         UserSaver
+	invitationStorage InvitationStorage
+	mailSender        MailSender
+	acceptURL         string
+	invitationTTL     time.Duration
+}
+
+// Auth registers and authenticates users, see auth.Auth.
+type Auth interface {
+	RegisterNewUser(ctx context.Context, email, password string) (userID uuid.UUID, err error)
+	Authenticate(ctx context.Context, email, password string) (*entity.User, error)
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (*entity.User, error)
+	UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+}
+
+type UserSaver interface {
+	UpdateUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error
+}
+
+type InvitationStorage interface {
+	SaveInvitation(ctx context.Context, invitation *entity.Invitation) (invitationID uuid.UUID, err error)
+	Invitation(ctx context.Context, invitationID uuid.UUID) (*entity.Invitation, error)
+	PendingInvitations(ctx context.Context) ([]*entity.Invitation, error)
+	RevokeInvitation(ctx context.Context, invitationID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, invitationID, userID uuid.UUID) error
+}
+
+type MailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New returns new instance of Invitations service.
+// AcceptURL is a link in the invitation email, the token is appended to it.
+func New(
+	log *slog.Logger,
+	auth Auth,
+	userProvider UserProvider,
+	userSaver UserSaver,
+	invitationStorage InvitationStorage,
+	mailSender MailSender,
+	secret string,
+	acceptURL string,
+	invitationTTL time.Duration,
+) *Invitations {
+	return &Invitations{
+		log:               log,
+		secret:            secret,
+		auth:              auth,
+		userProvider:      userProvider,
+		userSaver:         userSaver,
+		invitationStorage: invitationStorage,
+		mailSender:        mailSender,
+		acceptURL:         acceptURL,
+		invitationTTL:     invitationTTL,
+	}
+}
+
+// Create invites the email to the context tenant with the role
+// and sends the invitation token to the email.
+func (i *Invitations) Create(ctx context.Context, invitedBy uuid.UUID, email, role string) (*entity.Invitation, error) {
+	const op = "invitation.Create"
+	log := i.log.With(
+		slog.String("op", op),
+		slog.String("tenant_id", tenancy.FromContext(ctx).String()),
+	)
+
+	log.Info("creating invitation")
+
+	invitation := &entity.Invitation{
+		TenantID:  tenancy.FromContext(ctx),
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		ExpiresAt: time.Now().Add(i.invitationTTL),
+	}
+
+	invitationID, err := i.invitationStorage.SaveInvitation(ctx, invitation)
+	if err != nil {
+		log.Error("failed to save invitation", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := jwt.Sign(map[string]any{
+		"typ":           tokenType,
+		"invitation_id": invitationID.String(),
+	}, i.secret, i.invitationTTL)
+	if err != nil {
+		log.Error("failed to sign invitation token", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := i.mailSender.Send(ctx, email, "You are invited", i.message(token)); err != nil {
+		log.Error("failed to send invitation", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("invitation sent", slog.String("invitation_id", invitationID.String()))
+
+	return i.invitationStorage.Invitation(ctx, invitationID)
+}
+
+// Accept accepts the invitation and returns id of the user who joined the tenant.
+//
+// If the invited email has no user in the tenant, the user is registered with the password.
+// Otherwise the password must be the password of the existing user, who is given the role.
+// If the token is invalid, expired, revoked or already used, returns ErrInvalidInvitation.
+// If the password of the existing user is incorrect, returns ErrInvalidCredentials.
+func (i *Invitations) Accept(ctx context.Context, token, password string) (userID uuid.UUID, err error) {
+	const op = "invitation.Accept"
+	log := i.log.With(
+		slog.String("op", op),
+	)
+
+	invitation, err := i.pendingInvitation(ctx, token)
+	if err != nil {
+		log.Warn("invalid invitation", sl.Err(err))
+
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("invitation_id", invitation.ID.String()))
+	ctx = tenancy.WithTenant(ctx, invitation.TenantID)
+
+	user, err := i.member(ctx, log, invitation.Email, password)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the invitation is consumed last, so a failed registration doesn't burn it
+	if err := i.invitationStorage.AcceptInvitation(ctx, invitation.ID, user.ID); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+		}
+
+		log.Error("failed to accept invitation", sl.Err(err))
+
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !slices.Contains(user.Roles, invitation.Role) {
+		if err := i.userSaver.UpdateUserRoles(ctx, user.ID, append(user.Roles, invitation.Role)); err != nil {
+			log.Error("failed to grant role", sl.Err(err))
+
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("invitation accepted", slog.String("user_id", user.ID.String()))
+
+	return user.ID, nil
+}
+
+// Pending returns invitations of the context tenant that can still be accepted.
+func (i *Invitations) Pending(ctx context.Context) ([]*entity.Invitation, error) {
+	const op = "invitation.Pending"
+
+	invitations, err := i.invitationStorage.PendingInvitations(ctx)
+	if err != nil {
+		i.log.Error("failed to get invitations", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invitations, nil
+}
+
+// Revoke revokes pending invitation of the context tenant.
+//
+// If the pending invitation is not found, returns ErrInvitationNotFound.
+func (i *Invitations) Revoke(ctx context.Context, invitationID uuid.UUID) error {
+	const op = "invitation.Revoke"
+	log := i.log.With(
+		slog.String("op", op),
+		slog.String("invitation_id", invitationID.String()),
+	)
+
+	if err := i.invitationStorage.RevokeInvitation(ctx, invitationID); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvitationNotFound)
+		}
+
+		log.Error("failed to revoke invitation", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("invitation revoked")
+
+	return nil
+}
+
+func (i *Invitations) pendingInvitation(ctx context.Context, token string) (*entity.Invitation, error) {
+	claims, err := jwt.Parse(token, i.secret)
+	if err != nil || claims["typ"] != tokenType {
+		return nil, ErrInvalidInvitation
+	}
+
+	idClaim, _ := claims["invitation_id"].(string)
+	invitationID, err := uuid.Parse(idClaim)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitation, err := i.invitationStorage.Invitation(ctx, invitationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+
+	if !invitation.Pending(time.Now()) {
+		return nil, ErrInvalidInvitation
+	}
+
+	return invitation, nil
+}
+
+// member returns user of the invited email, registering it if needed.
+func (i *Invitations) member(ctx context.Context, log *slog.Logger, email, password string) (*entity.User, error) {
+	_, err := i.userProvider.User(ctx, email)
+	switch {
+	case err == nil:
+		user, err := i.auth.Authenticate(ctx, email, password)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				return nil, ErrInvalidCredentials
+			}
+			return nil, err
+		}
+
+		return user, nil
+	case errors.Is(err, repository.ErrUserNotFound):
+		userID, err := i.auth.RegisterNewUser(ctx, email, password)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.userProvider.UserByID(ctx, userID)
+	default:
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, err
+	}
+}
+
+func (i *Invitations) message(token string) string {
+	var b strings.Builder
+
+	b.WriteString("You have been invited to join an organization.\n\n")
+	if i.acceptURL != "" {
+		fmt.Fprintf(&b, "Accept the invitation: %s%s\n\n", i.acceptURL, token)
+	} else {
+		fmt.Fprintf(&b, "Your invitation token: %s\n\n", token)
+	}
+	fmt.Fprintf(&b, "The invitation expires in %s.\n", i.invitationTTL)
+
+	return b.String()
+}
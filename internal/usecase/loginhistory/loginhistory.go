@@ -0,0 +1,111 @@
+package loginhistory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/device"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type History struct {
+	log      *slog.Logger
+	storage  Storage
+	notifier Notifier
+}
+
+type Storage interface {
+	SaveLogin(ctx context.Context, login *entity.Login) (loginID int64, err error)
+	LoginSeen(ctx context.Context, userID uuid.UUID, fingerprint, network string) (seen, deviceSeen, networkSeen bool, err error)
+	Logins(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.Login, error)
+}
+
+// New returns new instance of login History service.
+func New(log *slog.Logger, storage Storage, notifier Notifier) *History {
+	return &History{
+		log:      log,
+		storage:  storage,
+		notifier: notifier,
+	}
+}
+
+// Record saves sign-in of the user from the client in the context.
+// If the user has signed in before, but never from the device or the network,
+// the user is notified in background.
+func (h *History) Record(ctx context.Context, user *entity.User) error {
+	const op = "loginhistory.Record"
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("user_id", user.ID.String()),
+	)
+
+	client := clientinfo.FromContext(ctx)
+	login := &entity.Login{
+		UserID:            user.ID,
+		IP:                client.IP,
+		Network:           device.Network(client.IP),
+		UserAgent:         client.UserAgent,
+		DeviceFingerprint: device.Fingerprint(client.UserAgent),
+	}
+
+	seen, deviceSeen, networkSeen, err := h.storage.LoginSeen(ctx, user.ID, login.DeviceFingerprint, login.Network)
+	if err != nil {
+		log.Error("failed to check login history", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	login.ID, err = h.storage.SaveLogin(ctx, login)
+	if err != nil {
+		log.Error("failed to save login", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the very first sign-in follows registration and is expected
+	if seen && (!deviceSeen || !networkSeen) {
+		log.Info("sign-in from new device or network",
+			slog.Bool("new_device", !deviceSeen),
+			slog.Bool("new_network", !networkSeen),
+		)
+
+		go h.notify(context.WithoutCancel(ctx), log, user, login)
+	}
+
+	return nil
+}
+
+// Logins returns latest sign-ins of the user, newest first.
+// Limit defaults to DefaultLimit and is capped at MaxLimit.
+func (h *History) Logins(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.Login, error) {
+	const op = "loginhistory.Logins"
+
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	logins, err := h.storage.Logins(ctx, userID, limit)
+	if err != nil {
+		h.log.Error("failed to get login history", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return logins, nil
+}
+
+func (h *History) notify(ctx context.Context, log *slog.Logger, user *entity.User, login *entity.Login) {
+	if err := h.notifier.NotifyNewLogin(ctx, user, login); err != nil {
+		log.Error("failed to notify user about new sign-in", sl.Err(err))
+	}
+}
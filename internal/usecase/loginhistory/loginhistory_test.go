@@ -0,0 +1,88 @@
+package loginhistory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	logins []*entity.Login
+}
+
+func (m *memoryStorage) SaveLogin(_ context.Context, login *entity.Login) (int64, error) {
+	m.logins = append(m.logins, login)
+	return int64(len(m.logins)), nil
+}
+
+func (m *memoryStorage) LoginSeen(_ context.Context, userID uuid.UUID, fingerprint, network string) (bool, bool, bool, error) {
+	var seen, deviceSeen, networkSeen bool
+	for _, login := range m.logins {
+		if login.UserID != userID {
+			continue
+		}
+		seen = true
+		deviceSeen = deviceSeen || login.DeviceFingerprint == fingerprint
+		networkSeen = networkSeen || login.Network == network
+	}
+	return seen, deviceSeen, networkSeen, nil
+}
+
+func (m *memoryStorage) Logins(context.Context, uuid.UUID, int) ([]*entity.Login, error) {
+	return m.logins, nil
+}
+
+type chanNotifier chan *entity.Login
+
+func (n chanNotifier) NotifyNewLogin(_ context.Context, _ *entity.User, login *entity.Login) error {
+	n <- login
+	return nil
+}
+
+func TestRecord_NotifiesAboutNewDeviceOrNetwork(t *testing.T) {
+	notifier := make(chanNotifier, 10)
+	history := New(slog.New(slog.NewTextHandler(io.Discard, nil)), new(memoryStorage), notifier)
+
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+
+	steps := []struct {
+		name   string
+		client clientinfo.Info
+		notify bool
+	}{
+		{"first sign-in", clientinfo.Info{IP: "192.0.2.1", UserAgent: "laptop"}, false},
+		{"same device and network", clientinfo.Info{IP: "192.0.2.99", UserAgent: "laptop"}, false},
+		{"new device", clientinfo.Info{IP: "192.0.2.1", UserAgent: "phone"}, true},
+		{"new network", clientinfo.Info{IP: "198.51.100.1", UserAgent: "laptop"}, true},
+		{"known device and network", clientinfo.Info{IP: "198.51.100.7", UserAgent: "phone"}, false},
+	}
+
+	for _, step := range steps {
+		ctx := clientinfo.WithInfo(context.Background(), step.client)
+		require.NoError(t, history.Record(ctx, user), step.name)
+
+		if step.notify {
+			select {
+			case login := <-notifier:
+				assert.Equal(t, step.client.IP, login.IP, step.name)
+			case <-time.After(time.Second):
+				t.Fatalf("%s: no notification", step.name)
+			}
+			continue
+		}
+
+		select {
+		case <-notifier:
+			t.Fatalf("%s: unexpected notification", step.name)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package loginhistory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+)
+
+const (
+	NotifierEmail = "email"
+	NotifierLog   = "log"
+	NotifierNone  = "none"
+)
+
+// Notifier tells the user about sign-in from a device or network the user hasn't used before.
+type Notifier interface {
+	NotifyNewLogin(ctx context.Context, user *entity.User, login *entity.Login) error
+}
+
+type MailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// EmailNotifier sends notifications to the email of the user.
+type EmailNotifier struct {
+	sender MailSender
+}
+
+func NewEmailNotifier(sender MailSender) *EmailNotifier {
+	return &EmailNotifier{
+		sender: sender,
+	}
+}
+
+func (n *EmailNotifier) NotifyNewLogin(ctx context.Context, user *entity.User, login *entity.Login) error {
+	return n.sender.Send(ctx, user.Email, "New sign-in to your account", message(login))
+}
+
+// LogNotifier writes notifications to the log, e.g. for development.
+type LogNotifier struct {
+	log *slog.Logger
+}
+
+func NewLogNotifier(log *slog.Logger) *LogNotifier {
+	return &LogNotifier{
+		log: log,
+	}
+}
+
+func (n *LogNotifier) NotifyNewLogin(_ context.Context, user *entity.User, login *entity.Login) error {
+	n.log.Info("new sign-in",
+		slog.String("user_id", user.ID.String()),
+		slog.String("ip", login.IP),
+		slog.String("user_agent", login.UserAgent),
+	)
+
+	return nil
+}
+
+// NopNotifier discards notifications.
+type NopNotifier struct{}
+
+func (NopNotifier) NotifyNewLogin(context.Context, *entity.User, *entity.Login) error {
+	return nil
+}
+
+func message(login *entity.Login) string {
+	var b strings.Builder
+
+	b.WriteString("Your account was just signed in to from a new device or network.\n\n")
+	fmt.Fprintf(&b, "Time: %s\n", time.Now().UTC().Format(time.RFC1123))
+	if login.IP != "" {
+		fmt.Fprintf(&b, "IP address: %s\n", login.IP)
+	}
+	if login.UserAgent != "" {
+		fmt.Fprintf(&b, "Device: %s\n", login.UserAgent)
+	}
+	b.WriteString("\nIf this was you, no action is needed. Otherwise change your password right away.\n")
+
+	return b.String()
+}
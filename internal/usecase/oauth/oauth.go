@@ -0,0 +1,629 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+const (
+	ResponseTypeCode        = "code"
+	CodeChallengeMethodS256 = "S256"
+	TokenTypeBearer         = "Bearer"
+)
+
+var (
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrInvalidClient           = errors.New("invalid client")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect uri")
+	ErrInvalidGrant            = errors.New("invalid grant")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrUnauthorizedClient      = errors.New("unauthorized client")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant type")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrInvalidToken            = errors.New("invalid token")
+	ErrInsufficientScope       = errors.New("insufficient scope")
+)
+
+type OAuth struct {
+	log             *slog.Logger
+	secret          string
+	issuer          string
+	signingKey      *jwk.Key
+	authenticator   Authenticator
+	userProvider    UserProvider
+	clientStorage   ClientStorage
+	grantStorage    GrantStorage
+	tokenTTL        time.Duration
+	codeTTL         time.Duration
+	refreshTokenTTL time.Duration
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, email, password string) (*entity.User, error)
+	Admit(ctx context.Context, user *entity.User, method string) error
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+}
+
+type ClientStorage interface {
+	SaveClient(ctx context.Context, client *entity.Client) (clientID uuid.UUID, err error)
+	Client(ctx context.Context, clientID uuid.UUID) (*entity.Client, error)
+}
+
+type GrantStorage interface {
+	SaveAuthorizationCode(ctx context.Context, code *entity.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash []byte) (*entity.AuthorizationCode, error)
+	SaveRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	ConsumeRefreshToken(ctx context.Context, tokenHash []byte) (*entity.RefreshToken, error)
+}
+
+// AuthorizationRequest holds parameters of the authorization endpoint.
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// Authorization is a validated authorization request the user is asked to consent to.
+type Authorization struct {
+	Client *entity.Client
+	// RedirectURI is where the user is sent back, the one registered for the client if the request omitted it.
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+
+	// redirectURIRequested tells whether the request named the redirect URI,
+	// the token request must repeat it then (RFC 6749 section 4.1.3).
+	redirectURIRequested bool
+}
+
+// TokenRequest holds parameters of the token endpoint.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	ExpiresIn    time.Duration
+	RefreshToken string
+	IDToken      string
+	Scopes       []string
+}
+
+// New returns new instance of OAuth service.
+// The issuer and the signing key are used for OpenID Connect ID tokens.
+func New(
+	log *slog.Logger,
+	authenticator Authenticator,
+	userProvider UserProvider,
+	clientStorage ClientStorage,
+	grantStorage GrantStorage,
+	secret string,
+	issuer string,
+	signingKey *jwk.Key,
+	tokenTTL time.Duration,
+	codeTTL time.Duration,
+	refreshTokenTTL time.Duration,
+) *OAuth {
+	return &OAuth{
+		log:             log,
+		secret:          secret,
+		issuer:          issuer,
+		signingKey:      signingKey,
+		authenticator:   authenticator,
+		userProvider:    userProvider,
+		clientStorage:   clientStorage,
+		grantStorage:    grantStorage,
+		tokenTTL:        tokenTTL,
+		codeTTL:         codeTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// RegisterClient registers new OAuth client of the context tenant and returns its id and secret.
+// Public clients get no secret, so the returned secret is empty.
+func (o *OAuth) RegisterClient(
+	ctx context.Context,
+	name string,
+	redirectURIs, scopes, grantTypes []string,
+	public bool,
+) (clientID uuid.UUID, secret string, err error) {
+	const op = "oauth.RegisterClient"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("name", name),
+	)
+
+	log.Info("registering client")
+
+	if err := validateClient(redirectURIs, grantTypes, public); err != nil {
+		return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	client := &entity.Client{
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		GrantTypes:   grantTypes,
+	}
+
+	if !public {
+		secret, err = randomToken()
+		if err != nil {
+			return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		client.SecretHash, err = passhash.Hash([]byte(secret))
+		if err != nil {
+			log.Error("failed to generate secret hash", sl.Err(err))
+
+			return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	clientID, err = o.clientStorage.SaveClient(ctx, client)
+	if err != nil {
+		log.Error("failed to save client", sl.Err(err))
+
+		return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("client registered", slog.String("client_id", clientID.String()))
+
+	return clientID, secret, nil
+}
+
+// Authorize validates authorization request.
+//
+// ErrInvalidClient and ErrInvalidRedirectURI mean that the redirect URI can't be trusted,
+// so the error must be shown to the user instead of being sent to the client.
+func (o *OAuth) Authorize(ctx context.Context, req *AuthorizationRequest) (*Authorization, error) {
+	const op = "oauth.Authorize"
+
+	client, err := o.client(ctx, req.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	redirectURI, err := resolveRedirectURI(client, req.RedirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	authz := &Authorization{
+		Client:               client,
+		RedirectURI:          redirectURI,
+		State:                req.State,
+		redirectURIRequested: req.RedirectURI != "",
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		return authz, fmt.Errorf("%s: %w", op, ErrUnsupportedResponseType)
+	}
+
+	if !slices.Contains(client.GrantTypes, entity.GrantTypeAuthorizationCode) {
+		return authz, fmt.Errorf("%s: %w", op, ErrUnauthorizedClient)
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return authz, fmt.Errorf("%s: %w: code_challenge with S256 method is required", op, ErrInvalidRequest)
+	}
+
+	authz.Scopes, err = resolveScopes(parseScope(req.Scope), client.Scopes)
+	if err != nil {
+		return authz, fmt.Errorf("%s: %w", op, err)
+	}
+
+	authz.CodeChallenge = req.CodeChallenge
+	authz.CodeChallengeMethod = req.CodeChallengeMethod
+	authz.Nonce = req.Nonce
+
+	return authz, nil
+}
+
+// Approve authenticates the user who consented to the authorization and returns authorization code.
+// The user is looked up in the tenant of the client.
+//
+// If credentials are invalid, returns auth.ErrInvalidCredentials.
+// If the login is too risky, returns auth.ErrMFARequired or auth.ErrLoginDenied.
+func (o *OAuth) Approve(ctx context.Context, authz *Authorization, email, password string) (code string, err error) {
+	const op = "oauth.Approve"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", authz.Client.ID.String()),
+	)
+
+	ctx = tenancy.WithTenant(ctx, authz.Client.TenantID)
+
+	user, err := o.authenticator.Authenticate(ctx, email, password)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := o.authenticator.Admit(ctx, user, auth.MethodPassword); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err = randomToken()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// omitted redirect URI is kept empty, so the token request doesn't have to name it
+	var redirectURI string
+	if authz.redirectURIRequested {
+		redirectURI = authz.RedirectURI
+	}
+
+	err = o.grantStorage.SaveAuthorizationCode(ctx, &entity.AuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            authz.Client.ID,
+		UserID:              user.ID,
+		RedirectURI:         redirectURI,
+		Scopes:              authz.Scopes,
+		CodeChallenge:       authz.CodeChallenge,
+		CodeChallengeMethod: authz.CodeChallengeMethod,
+		Nonce:               authz.Nonce,
+		AuthTime:            time.Now(),
+		ExpiresAt:           time.Now().Add(o.codeTTL),
+	})
+	if err != nil {
+		log.Error("failed to save authorization code", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("authorization approved")
+
+	return code, nil
+}
+
+// Exchange issues tokens for the grant in the token request.
+func (o *OAuth) Exchange(ctx context.Context, req *TokenRequest) (*Token, error) {
+	const op = "oauth.Exchange"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("grant_type", req.GrantType),
+	)
+
+	client, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		log.Warn("failed to authenticate client", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !slices.Contains(client.GrantTypes, req.GrantType) {
+		switch req.GrantType {
+		case entity.GrantTypeAuthorizationCode, entity.GrantTypeRefreshToken, entity.GrantTypeClientCredentials:
+			return nil, fmt.Errorf("%s: %w", op, ErrUnauthorizedClient)
+		default:
+			return nil, fmt.Errorf("%s: %w", op, ErrUnsupportedGrantType)
+		}
+	}
+
+	var token *Token
+	switch req.GrantType {
+	case entity.GrantTypeAuthorizationCode:
+		token, err = o.exchangeCode(ctx, client, req)
+	case entity.GrantTypeRefreshToken:
+		token, err = o.exchangeRefreshToken(ctx, client, req)
+	case entity.GrantTypeClientCredentials:
+		token, err = o.exchangeClientCredentials(client, req)
+	default:
+		err = ErrUnsupportedGrantType
+	}
+	if err != nil {
+		log.Warn("failed to exchange grant", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("token issued", slog.String("client_id", client.ID.String()))
+
+	return token, nil
+}
+
+func (o *OAuth) exchangeCode(ctx context.Context, client *entity.Client, req *TokenRequest) (*Token, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, fmt.Errorf("%w: code and code_verifier are required", ErrInvalidRequest)
+	}
+
+	code, err := o.grantStorage.ConsumeAuthorizationCode(ctx, hashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, repository.ErrAuthorizationCodeNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ID || time.Now().After(code.ExpiresAt) {
+		return nil, ErrInvalidGrant
+	}
+
+	if code.RedirectURI != "" && code.RedirectURI != req.RedirectURI {
+		return nil, fmt.Errorf("%w: redirect_uri mismatch", ErrInvalidGrant)
+	}
+
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge, code.CodeChallengeMethod) {
+		return nil, fmt.Errorf("%w: code_verifier mismatch", ErrInvalidGrant)
+	}
+
+	return o.issueTokens(ctx, client, code.UserID, code.Scopes, code.Nonce, code.AuthTime)
+}
+
+func (o *OAuth) exchangeRefreshToken(ctx context.Context, client *entity.Client, req *TokenRequest) (*Token, error) {
+	if req.RefreshToken == "" {
+		return nil, fmt.Errorf("%w: refresh_token is required", ErrInvalidRequest)
+	}
+
+	refreshToken, err := o.grantStorage.ConsumeRefreshToken(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	if refreshToken.ClientID != client.ID || time.Now().After(refreshToken.ExpiresAt) {
+		return nil, ErrInvalidGrant
+	}
+
+	scopes, err := resolveScopes(parseScope(req.Scope), refreshToken.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.issueTokens(ctx, client, refreshToken.UserID, scopes, "", refreshToken.AuthTime)
+}
+
+func (o *OAuth) exchangeClientCredentials(client *entity.Client, req *TokenRequest) (*Token, error) {
+	if client.Public() {
+		return nil, ErrUnauthorizedClient
+	}
+
+	scopes, err := resolveScopes(parseScope(req.Scope), client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := jwt.NewClientToken(client.ID.String(), o.secret, o.tokenTTL,
+		jwt.WithScopes(scopes),
+		jwt.WithClaims(map[string]any{"tenant_id": client.TenantID.String()}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Token{
+		AccessToken: accessToken,
+		TokenType:   TokenTypeBearer,
+		ExpiresIn:   o.tokenTTL,
+		Scopes:      scopes,
+	}, nil
+}
+
+func (o *OAuth) issueTokens(
+	ctx context.Context,
+	client *entity.Client,
+	userID uuid.UUID,
+	scopes []string,
+	nonce string,
+	authTime time.Time,
+) (*Token, error) {
+	user, err := o.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	// lookups by id aren't scoped to a tenant, the user must belong to the tenant of the client
+	if user.TenantID != client.TenantID {
+		return nil, ErrInvalidGrant
+	}
+
+	accessToken, err := jwt.NewToken(user, o.secret, o.tokenTTL,
+		jwt.WithScopes(scopes),
+		jwt.WithClientID(client.ID.String()),
+		jwt.WithoutRoles(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &Token{
+		AccessToken: accessToken,
+		TokenType:   TokenTypeBearer,
+		ExpiresIn:   o.tokenTTL,
+		Scopes:      scopes,
+	}
+
+	if slices.Contains(scopes, ScopeOpenID) {
+		token.IDToken, err = o.idToken(client, user, scopes, accessToken, nonce, authTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !slices.Contains(client.GrantTypes, entity.GrantTypeRefreshToken) {
+		return token, nil
+	}
+
+	token.RefreshToken, err = randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.grantStorage.SaveRefreshToken(ctx, &entity.RefreshToken{
+		TokenHash: hashToken(token.RefreshToken),
+		ClientID:  client.ID,
+		UserID:    user.ID,
+		Scopes:    scopes,
+		AuthTime:  authTime,
+		ExpiresAt: time.Now().Add(o.refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (o *OAuth) client(ctx context.Context, rawClientID string) (*entity.Client, error) {
+	clientID, err := uuid.Parse(rawClientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := o.clientStorage.Client(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrClientNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (o *OAuth) authenticateClient(ctx context.Context, clientID, secret string) (*entity.Client, error) {
+	client, err := o.client(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public() {
+		return client, nil
+	}
+
+	if err := passhash.Compare(client.SecretHash, []byte(secret)); err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func validateClient(redirectURIs, grantTypes []string, public bool) error {
+	if len(grantTypes) == 0 {
+		return fmt.Errorf("%w: at least one grant type is required", ErrInvalidRequest)
+	}
+
+	for _, grantType := range grantTypes {
+		switch grantType {
+		case entity.GrantTypeAuthorizationCode, entity.GrantTypeRefreshToken:
+		case entity.GrantTypeClientCredentials:
+			if public {
+				return fmt.Errorf("%w: public client can't use client_credentials", ErrInvalidRequest)
+			}
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedGrantType, grantType)
+		}
+	}
+
+	if slices.Contains(grantTypes, entity.GrantTypeAuthorizationCode) && len(redirectURIs) == 0 {
+		return fmt.Errorf("%w: authorization_code requires redirect uris", ErrInvalidRedirectURI)
+	}
+
+	for _, redirectURI := range redirectURIs {
+		u, err := url.Parse(redirectURI)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return fmt.Errorf("%w: %s", ErrInvalidRedirectURI, redirectURI)
+		}
+	}
+
+	return nil
+}
+
+// resolveRedirectURI returns redirect URI registered for the client.
+// Only exact matches are accepted, the URI may be omitted if the client has exactly one.
+func resolveRedirectURI(client *entity.Client, redirectURI string) (string, error) {
+	if redirectURI == "" {
+		if len(client.RedirectURIs) == 1 {
+			return client.RedirectURIs[0], nil
+		}
+		return "", ErrInvalidRedirectURI
+	}
+
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+
+	return redirectURI, nil
+}
+
+func parseScope(scope string) []string {
+	scopes := strings.Fields(scope)
+	slices.Sort(scopes)
+
+	return slices.Compact(scopes)
+}
+
+// resolveScopes checks that requested scopes are allowed.
+// If no scopes are requested, all allowed scopes are granted.
+func resolveScopes(requested, allowed []string) ([]string, error) {
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	for _, scope := range requested {
+		if !slices.Contains(allowed, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	return requested, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+
+	return sum[:]
+}
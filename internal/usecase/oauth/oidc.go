@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+const (
+	ScopeOpenID        = "openid"
+	ScopeEmail         = "email"
+	ScopeProfile       = "profile"
+	ScopeOfflineAccess = "offline_access"
+)
+
+// Issuer returns issuer identifier of the OpenID provider.
+func (o *OAuth) Issuer() string {
+	return o.issuer
+}
+
+// KeySet returns public keys used to verify ID tokens.
+func (o *OAuth) KeySet() jwk.JSONWebKeySet {
+	return o.signingKey.Set()
+}
+
+// UserInfo returns claims about the user the access token was issued to.
+// Claims are released according to the scopes of the token.
+func (o *OAuth) UserInfo(ctx context.Context, accessToken string) (map[string]any, error) {
+	const op = "oauth.UserInfo"
+	log := o.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := jwt.Parse(accessToken, o.secret)
+	if err != nil {
+		log.Warn("invalid access token", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	scope, _ := claims["scope"].(string)
+
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	scopes := strings.Fields(scope)
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInsufficientScope)
+	}
+
+	user, err := o.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info := userClaims(user, scopes)
+	info["sub"] = user.ID.String()
+
+	return info, nil
+}
+
+func (o *OAuth) idToken(
+	client *entity.Client,
+	user *entity.User,
+	scopes []string,
+	accessToken string,
+	nonce string,
+	authTime time.Time,
+) (string, error) {
+	return jwt.NewIDToken(user, o.signingKey, o.issuer, client.ID.String(), o.tokenTTL,
+		jwt.WithNonce(nonce),
+		jwt.WithAuthTime(authTime),
+		jwt.WithAccessTokenHash(accessToken),
+		jwt.WithClaims(userClaims(user, scopes)),
+	)
+}
+
+// userClaims returns standard claims (OpenID Connect Core, section 5.4) allowed by the scopes.
+func userClaims(user *entity.User, scopes []string) map[string]any {
+	claims := make(map[string]any)
+
+	if slices.Contains(scopes, ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = false
+	}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+
+	return claims
+}
@@ -0,0 +1,29 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// RFC 7636 limits code verifier to 43-128 characters.
+const (
+	minCodeVerifierLen = 43
+	maxCodeVerifierLen = 128
+)
+
+// verifyCodeChallenge checks PKCE code verifier against the challenge sent to the authorization endpoint.
+func verifyCodeChallenge(verifier, challenge, method string) bool {
+	if len(verifier) < minCodeVerifierLen || len(verifier) > maxCodeVerifierLen {
+		return false
+	}
+
+	if method != CodeChallengeMethodS256 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,59 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// example from RFC 7636, Appendix B
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    string
+		want      bool
+	}{
+		{
+			name:      "valid S256",
+			verifier:  verifier,
+			challenge: challenge,
+			method:    CodeChallengeMethodS256,
+			want:      true,
+		},
+		{
+			name:      "wrong verifier",
+			verifier:  strings.Repeat("a", minCodeVerifierLen),
+			challenge: challenge,
+			method:    CodeChallengeMethodS256,
+			want:      false,
+		},
+		{
+			name:      "plain method is not supported",
+			verifier:  verifier,
+			challenge: verifier,
+			method:    "plain",
+			want:      false,
+		},
+		{
+			name:      "too short verifier",
+			verifier:  "short",
+			challenge: challenge,
+			method:    CodeChallengeMethodS256,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, verifyCodeChallenge(tt.verifier, tt.challenge, tt.method))
+		})
+	}
+}
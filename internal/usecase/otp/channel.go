@@ -0,0 +1,71 @@
+package otp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sms"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Channel delivers one-time passcode to the user.
+type Channel interface {
+	// Available reports whether the channel can reach the user, e.g. the user has a phone.
+	Available(user *entity.User) bool
+	Send(ctx context.Context, user *entity.User, code string) error
+}
+
+type MailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// EmailChannel delivers codes to the email of the user.
+type EmailChannel struct {
+	sender MailSender
+}
+
+func NewEmailChannel(sender MailSender) *EmailChannel {
+	return &EmailChannel{
+		sender: sender,
+	}
+}
+
+func (c *EmailChannel) Available(*entity.User) bool {
+	return true
+}
+
+func (c *EmailChannel) Send(ctx context.Context, user *entity.User, code string) error {
+	return c.sender.Send(ctx, user.Email, "Your login code", message(code))
+}
+
+// SMSChannel delivers codes to the phone of the user.
+type SMSChannel struct {
+	provider sms.Provider
+}
+
+func NewSMSChannel(provider sms.Provider) *SMSChannel {
+	return &SMSChannel{
+		provider: provider,
+	}
+}
+
+func (c *SMSChannel) Available(user *entity.User) bool {
+	return user.Phone != ""
+}
+
+func (c *SMSChannel) Send(ctx context.Context, user *entity.User, code string) error {
+	if !c.Available(user) {
+		return ErrChannelUnavailable
+	}
+
+	return c.provider.SendSMS(ctx, user.Phone, message(code))
+}
+
+func message(code string) string {
+	return fmt.Sprintf("Your login code is %s. Do not share it with anyone.", code)
+}
@@ -0,0 +1,320 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+const codeDigits = 6
+
+var (
+	ErrInvalidCode        = errors.New("invalid code")
+	ErrUnknownChannel     = errors.New("unknown channel")
+	ErrChannelUnavailable = errors.New("channel is unavailable for user")
+)
+
+type OTP struct {
+	log          *slog.Logger
+	userProvider UserProvider
+	otpStorage   OTPStorage
+	channels     map[string]Channel
+	auditLog     AuditLog
+	auth         Authenticator
+	codeTTL      time.Duration
+	maxAttempts  int
+	dummyHash    []byte
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (*entity.User, error)
+}
+
+type OTPStorage interface {
+	SaveOTP(ctx context.Context, userID uuid.UUID, codeHash []byte, expiresAt time.Time) error
+	OTP(ctx context.Context, userID uuid.UUID) (*entity.OTP, error)
+	IncrementOTPAttempts(ctx context.Context, userID uuid.UUID) (attempts int, err error)
+	DeleteOTP(ctx context.Context, userID uuid.UUID) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, event *entity.AuditEvent) error
+}
+
+// Authenticator decides whether the verified user may log in and issues the token, see auth.Auth.
+type Authenticator interface {
+	Admit(ctx context.Context, user *entity.User, method string) error
+	NewToken(ctx context.Context, user *entity.User) (string, error)
+}
+
+// New returns new instance of OTP service.
+// Channels are keyed by their name, e.g. ChannelEmail or ChannelSMS.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	otpStorage OTPStorage,
+	channels map[string]Channel,
+	auditLog AuditLog,
+	authenticator Authenticator,
+	codeTTL time.Duration,
+	maxAttempts int,
+) *OTP {
+	return &OTP{
+		log:          log,
+		userProvider: userProvider,
+		otpStorage:   otpStorage,
+		channels:     channels,
+		auditLog:     auditLog,
+		auth:         authenticator,
+		codeTTL:      codeTTL,
+		maxAttempts:  maxAttempts,
+		dummyHash:    newDummyHash(),
+	}
+}
+
+// SendCode generates new one-time passcode for the user and sends it through given channel.
+//
+// If user doesn't exist or the channel can't reach the user, nothing is sent and
+// no error is returned, so the response doesn't reveal which emails are registered.
+// The code is saved only once it is sent, a failed delivery keeps the pending code.
+func (o *OTP) SendCode(ctx context.Context, email, channel string) error {
+	const op = "otp.SendCode"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("channel", channel),
+	)
+
+	log.Info("sending one-time passcode")
+
+	ch, ok := o.channels[channel]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrUnknownChannel)
+	}
+
+	user, err := o.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return nil
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !ch.Available(user) {
+		log.Warn("channel is unavailable for user")
+
+		return nil
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		log.Error("failed to generate code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	codeHash, err := passhash.Hash([]byte(code))
+	if err != nil {
+		log.Error("failed to generate code hash", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the code is sent before it's saved, so it may arrive a moment before it can be verified
+	if err := ch.Send(ctx, user, code); err != nil {
+		log.Error("failed to send code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = o.otpStorage.SaveOTP(ctx, user.ID, codeHash, time.Now().Add(o.codeTTL))
+	if err != nil {
+		log.Error("failed to save code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("one-time passcode sent")
+
+	return nil
+}
+
+// Verify checks one-time passcode of the user and returns token on success.
+//
+// Every verification consumes an attempt. Attempts are shared by the codes sent until one expires,
+// once they are exhausted no code is accepted until then. Expired codes are deleted.
+// Unknown emails, missing, expired and locked codes cost the same check as a wrong code
+// and return ErrInvalidCode as well, so the response doesn't reveal which emails are registered.
+// Verifications of codes of existing users are recorded in the audit log.
+// If the login is too risky, returns auth.ErrLoginDenied.
+func (o *OTP) Verify(ctx context.Context, email, code string) (token string, err error) {
+	const op = "otp.Verify"
+	log := o.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("verifying one-time passcode")
+
+	user, err := o.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			o.compareDummyHash(code)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	otp, err := o.otpStorage.OTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOTPNotFound) {
+			log.Warn("code not found", sl.Err(err))
+			o.compareDummyHash(code)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to get code", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(otp.ExpiresAt) {
+		log.Warn("code expired")
+		o.deleteCode(ctx, log, user.ID)
+		o.compareDummyHash(code)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	attempts, err := o.otpStorage.IncrementOTPAttempts(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOTPNotFound) {
+			o.compareDummyHash(code)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to increment attempts", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the code is kept, so new codes sent before it expires inherit the attempts
+	if attempts > o.maxAttempts {
+		log.Warn("too many attempts", slog.Int("attempts", attempts))
+		o.compareDummyHash(code)
+
+		if attempts == o.maxAttempts+1 {
+			o.record(ctx, log, user, entity.AuditLockout, entity.OutcomeFailure, map[string]string{
+				"method":   "otp",
+				"attempts": strconv.Itoa(attempts),
+			})
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	if err := passhash.Compare(otp.CodeHash, []byte(code)); err != nil {
+		log.Warn("invalid code", sl.Err(err))
+
+		o.record(ctx, log, user, entity.AuditLogin, entity.OutcomeFailure, map[string]string{
+			"method": "otp",
+			"reason": "invalid_code",
+		})
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	o.deleteCode(ctx, log, user.ID)
+
+	if err := o.auth.Admit(ctx, user, auth.MethodOTP); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in with one-time passcode")
+
+	// success is recorded once the login is admitted, denied logins aren't successful
+	o.record(ctx, log, user, entity.AuditLogin, entity.OutcomeSuccess, map[string]string{
+		"method": "otp",
+	})
+
+	token, err = o.auth.NewToken(ctx, user)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// record writes event to the audit log, failures are only logged.
+func (o *OTP) record(ctx context.Context, log *slog.Logger, user *entity.User, eventType, outcome string, details map[string]string) {
+	err := o.auditLog.Record(ctx, &entity.AuditEvent{
+		TenantID: user.TenantID,
+		Type:     eventType,
+		UserID:   user.ID,
+		Outcome:  outcome,
+		Details:  details,
+	})
+	if err != nil {
+		log.Error("failed to record audit event", sl.Err(err))
+	}
+}
+
+func (o *OTP) deleteCode(ctx context.Context, log *slog.Logger, userID uuid.UUID) {
+	if err := o.otpStorage.DeleteOTP(ctx, userID); err != nil {
+		log.Error("failed to delete code", sl.Err(err))
+	}
+}
+
+// compareDummyHash spends the time of a code check on paths that have no code to check.
+func (o *OTP) compareDummyHash(code string) {
+	_ = passhash.Compare(o.dummyHash, []byte(code))
+}
+
+// newDummyHash returns hash of a random code with the cost of real code hashes.
+func newDummyHash() []byte {
+	code := make([]byte, codeDigits)
+	_, _ = rand.Read(code)
+
+	// fails only for invalid cost or codes longer than 72 bytes
+	hash, _ := passhash.Hash(code)
+
+	return hash
+}
+
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for range codeDigits {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", codeDigits, n), nil
+}
@@ -0,0 +1,338 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/auth"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const maxAttempts = 3
+
+type memoryStorage struct {
+	mu     sync.Mutex
+	users  map[string]*entity.User
+	codes  map[uuid.UUID]*entity.OTP
+	events []*entity.AuditEvent
+}
+
+func newMemoryStorage(users ...*entity.User) *memoryStorage {
+	m := &memoryStorage{
+		users: make(map[string]*entity.User),
+		codes: make(map[uuid.UUID]*entity.OTP),
+	}
+	for _, user := range users {
+		m.users[user.Email] = user
+	}
+
+	return m
+}
+
+func (m *memoryStorage) User(_ context.Context, email string) (*entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[email]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (m *memoryStorage) SaveOTP(_ context.Context, userID uuid.UUID, codeHash []byte, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	otp := &entity.OTP{CodeHash: codeHash, ExpiresAt: expiresAt}
+	if prev, ok := m.codes[userID]; ok && time.Now().Before(prev.ExpiresAt) {
+		otp.Attempts = prev.Attempts
+	}
+	m.codes[userID] = otp
+
+	return nil
+}
+
+func (m *memoryStorage) OTP(_ context.Context, userID uuid.UUID) (*entity.OTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	otp, ok := m.codes[userID]
+	if !ok {
+		return nil, repository.ErrOTPNotFound
+	}
+
+	return otp, nil
+}
+
+func (m *memoryStorage) IncrementOTPAttempts(_ context.Context, userID uuid.UUID) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	otp, ok := m.codes[userID]
+	if !ok {
+		return 0, repository.ErrOTPNotFound
+	}
+	otp.Attempts++
+
+	return otp.Attempts, nil
+}
+
+func (m *memoryStorage) DeleteOTP(_ context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.codes, userID)
+
+	return nil
+}
+
+func (m *memoryStorage) Record(_ context.Context, event *entity.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+
+	return nil
+}
+
+func (m *memoryStorage) code(userID uuid.UUID) *entity.OTP {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.codes[userID]
+}
+
+// memoryChannel keeps the last code sent to each user, delivery fails while fail is set.
+type memoryChannel struct {
+	mu    sync.Mutex
+	codes map[uuid.UUID]string
+	fail  bool
+}
+
+func (c *memoryChannel) Available(user *entity.User) bool {
+	return user.Phone != ""
+}
+
+func (c *memoryChannel) Send(_ context.Context, user *entity.User, code string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fail {
+		return errors.New("provider is down")
+	}
+	c.codes[user.ID] = code
+
+	return nil
+}
+
+func (c *memoryChannel) code(userID uuid.UUID) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.codes[userID]
+}
+
+// gate admits logins unless err is set and issues tokens naming the user.
+type gate struct {
+	err error
+}
+
+func (g *gate) Admit(_ context.Context, _ *entity.User, method string) error {
+	if method != auth.MethodOTP {
+		return errors.New("unexpected method " + method)
+	}
+
+	return g.err
+}
+
+func (g *gate) NewToken(_ context.Context, user *entity.User) (string, error) {
+	return "token of " + user.ID.String(), nil
+}
+
+var (
+	user    = &entity.User{ID: uuid.New(), Email: "user@example.com", Phone: "+15550100"}
+	noPhone = &entity.User{ID: uuid.New(), Email: "nophone@example.com"}
+)
+
+func newOTP(codeTTL time.Duration) (*OTP, *memoryStorage, *memoryChannel, *gate) {
+	storage := newMemoryStorage(user, noPhone)
+	channel := &memoryChannel{codes: make(map[uuid.UUID]string)}
+	gate := new(gate)
+
+	o := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, storage,
+		map[string]Channel{ChannelSMS: channel}, storage, gate, codeTTL, maxAttempts)
+
+	return o, storage, channel, gate
+}
+
+func TestVerify(t *testing.T) {
+	o, storage, channel, _ := newOTP(time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+	code := channel.code(user.ID)
+	require.Len(t, code, codeDigits)
+
+	token, err := o.Verify(ctx, user.Email, code)
+	require.NoError(t, err)
+	assert.Equal(t, "token of "+user.ID.String(), token, "token is issued by auth")
+
+	last := storage.events[len(storage.events)-1]
+	assert.Equal(t, entity.AuditLogin, last.Type)
+	assert.Equal(t, entity.OutcomeSuccess, last.Outcome)
+
+	_, err = o.Verify(ctx, user.Email, code)
+	assert.ErrorIs(t, err, ErrInvalidCode, "code is single use")
+	assert.Nil(t, storage.code(user.ID))
+}
+
+func TestVerify_Denied(t *testing.T) {
+	o, storage, channel, gate := newOTP(time.Minute)
+	ctx := context.Background()
+
+	gate.err = auth.ErrLoginDenied
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+	token, err := o.Verify(ctx, user.Email, channel.code(user.ID))
+	assert.ErrorIs(t, err, auth.ErrLoginDenied)
+	assert.Empty(t, token)
+
+	for _, event := range storage.events {
+		assert.NotEqual(t, entity.OutcomeSuccess, event.Outcome, "denied login isn't recorded as success")
+	}
+}
+
+func TestVerify_TooManyAttempts(t *testing.T) {
+	o, storage, channel, _ := newOTP(time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+	code := channel.code(user.ID)
+
+	for range maxAttempts {
+		_, err := o.Verify(ctx, user.Email, "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	_, err := o.Verify(ctx, user.Email, code)
+	assert.ErrorIs(t, err, ErrInvalidCode, "valid code is rejected once attempts are exhausted")
+
+	events := len(storage.events)
+	last := storage.events[events-1]
+	assert.Equal(t, entity.AuditLockout, last.Type)
+
+	// new codes don't give more attempts
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+	_, err = o.Verify(ctx, user.Email, channel.code(user.ID))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+	assert.Len(t, storage.events, events, "lockout is recorded once")
+}
+
+func TestVerify_AttemptsKeptAcrossResends(t *testing.T) {
+	o, _, channel, _ := newOTP(time.Minute)
+	ctx := context.Background()
+
+	for range maxAttempts {
+		require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+		_, err := o.Verify(ctx, user.Email, "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+	_, err := o.Verify(ctx, user.Email, channel.code(user.ID))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestVerify_AttemptsResetAfterExpiry(t *testing.T) {
+	o, _, channel, _ := newOTP(10 * time.Millisecond)
+	ctx := context.Background()
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+	for range maxAttempts + 1 {
+		_, err := o.Verify(ctx, user.Email, "wrong")
+		assert.Error(t, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+	_, err := o.Verify(ctx, user.Email, channel.code(user.ID))
+	assert.NoError(t, err)
+}
+
+func TestVerify_Expired(t *testing.T) {
+	o, storage, channel, _ := newOTP(time.Millisecond)
+	ctx := context.Background()
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+	time.Sleep(10 * time.Millisecond)
+
+	_, err := o.Verify(ctx, user.Email, channel.code(user.ID))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+	assert.Nil(t, storage.code(user.ID), "expired code is deleted")
+}
+
+func TestVerify_Uniform(t *testing.T) {
+	o, _, channel, _ := newOTP(time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+	for range maxAttempts {
+		_, err := o.Verify(ctx, user.Email, "wrong")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	// unknown emails, users without a code and locked codes look like a wrong code
+	_, err := o.Verify(ctx, "unknown@example.com", "123456")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = o.Verify(ctx, noPhone.Email, "123456")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = o.Verify(ctx, user.Email, channel.code(user.ID))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestSendCode_Uniform(t *testing.T) {
+	o, storage, channel, _ := newOTP(time.Minute)
+	ctx := context.Background()
+
+	// unreachable and unknown users get the same response as registered ones
+	assert.NoError(t, o.SendCode(ctx, noPhone.Email, ChannelSMS))
+	assert.NoError(t, o.SendCode(ctx, "unknown@example.com", ChannelSMS))
+
+	assert.Nil(t, storage.code(noPhone.ID))
+	assert.Empty(t, channel.code(noPhone.ID))
+
+	assert.ErrorIs(t, o.SendCode(ctx, user.Email, "pigeon"), ErrUnknownChannel)
+}
+
+func TestSendCode_FailedDelivery(t *testing.T) {
+	o, _, channel, _ := newOTP(time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, o.SendCode(ctx, user.Email, ChannelSMS))
+	pending := channel.code(user.ID)
+
+	channel.fail = true
+	assert.Error(t, o.SendCode(ctx, user.Email, ChannelSMS))
+
+	_, err := o.Verify(ctx, user.Email, pending)
+	assert.NoError(t, err, "failed delivery keeps the pending code")
+}
@@ -0,0 +1,195 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+)
+
+// Broker publishes domain events to downstream services.
+// Publish must not return before the broker has accepted the event.
+type Broker interface {
+	Publish(ctx context.Context, event *entity.Event) error
+}
+
+// Brokers publishes events to every broker in turn.
+// The event is accepted once all brokers accepted it, brokers preceding a failed one receive it again.
+type Brokers []Broker
+
+func (b Brokers) Publish(ctx context.Context, event *entity.Event) error {
+	for _, broker := range b {
+		if err := broker.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type Storage interface {
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]*entity.Event, error)
+	MarkEventsPublished(ctx context.Context, ids []int64) error
+	PostponeEvents(ctx context.Context, ids []int64, at time.Time) error
+	DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+const (
+	// claimLease is how long claimed events are hidden from other relays,
+	// it should exceed the time publishing of a batch takes.
+	claimLease = time.Minute
+	// cleanupInterval is how often published events older than the retention are deleted.
+	cleanupInterval = time.Hour
+)
+
+// Relay publishes events from the outbox to the broker.
+//
+// Delivery is at least once: an event is marked as published only after the broker accepted it,
+// so events sent right before a crash are sent again. Events of one user are published
+// in the order they happened, a failed event holds back later events of its user
+// and is retried after retryDelay, while events of other users go on.
+type Relay struct {
+	log        *slog.Logger
+	storage    Storage
+	broker     Broker
+	batchSize  int
+	interval   time.Duration
+	retryDelay time.Duration
+	retention  time.Duration
+}
+
+// New returns new instance of Relay polling the outbox every interval.
+// Published events are kept for retention, zero retention keeps them forever.
+func New(log *slog.Logger, storage Storage, broker Broker, batchSize int, interval, retryDelay, retention time.Duration) *Relay {
+	return &Relay{
+		log:        log,
+		storage:    storage,
+		broker:     broker,
+		batchSize:  batchSize,
+		interval:   interval,
+		retryDelay: retryDelay,
+		retention:  retention,
+	}
+}
+
+// Run publishes events until stop is closed. The batch in flight is finished first,
+// stopping through ctx instead would fail marking its published events and they'd be sent again.
+func (r *Relay) Run(ctx context.Context, stop <-chan struct{}) {
+	const op = "outbox.Run"
+	log := r.log.With(slog.String("op", op))
+
+	log.Info("outbox relay is running", slog.Duration("interval", r.interval))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var cleanup <-chan time.Time
+	if r.retention > 0 {
+		cleanupTicker := time.NewTicker(cleanupInterval)
+		defer cleanupTicker.Stop()
+		cleanup = cleanupTicker.C
+	}
+
+	for {
+		// drain the outbox before waiting for the next tick
+		for {
+			n, err := r.Relay(ctx)
+			if err != nil {
+				log.Error("failed to relay events", sl.Err(err))
+				break
+			}
+			if n < r.batchSize || stopped(stop) {
+				break
+			}
+		}
+
+		select {
+		case <-stop:
+			log.Info("outbox relay stopped")
+			return
+		case <-cleanup:
+			if err := r.Cleanup(ctx); err != nil {
+				log.Error("failed to clean up outbox", sl.Err(err))
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Relay publishes one batch of events and returns how many events it claimed.
+// Events are claimed before publishing, so the outbox isn't locked while the broker is waited for.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	const op = "outbox.Relay"
+	log := r.log.With(slog.String("op", op))
+
+	events, err := r.storage.ClaimEvents(ctx, r.batchSize, claimLease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	published := make([]int64, 0, len(events))
+	var postponed []int64
+	failed := make(map[uuid.UUID]struct{})
+
+	for _, event := range events {
+		if _, ok := failed[event.AggregateID]; ok {
+			postponed = append(postponed, event.ID)
+			continue
+		}
+
+		if err := r.broker.Publish(ctx, event); err != nil {
+			log.Error("failed to publish event",
+				slog.Int64("event_id", event.ID),
+				slog.String("type", event.Type),
+				sl.Err(err),
+			)
+
+			failed[event.AggregateID] = struct{}{}
+			postponed = append(postponed, event.ID)
+			continue
+		}
+
+		published = append(published, event.ID)
+	}
+
+	if len(published) > 0 {
+		if err := r.storage.MarkEventsPublished(ctx, published); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if len(postponed) > 0 {
+		if err := r.storage.PostponeEvents(ctx, postponed, time.Now().Add(r.retryDelay)); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// Cleanup deletes events published longer than retention ago.
+func (r *Relay) Cleanup(ctx context.Context) error {
+	const op = "outbox.Cleanup"
+
+	n, err := r.storage.DeletePublishedEvents(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.log.Info("deleted published events", slog.String("op", op), slog.Int64("count", n))
+
+	return nil
+}
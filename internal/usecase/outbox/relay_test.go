@@ -0,0 +1,287 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/broker/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	events      []*entity.Event
+	published   map[int64]time.Time
+	nextAttempt map[int64]time.Time
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		published:   map[int64]time.Time{},
+		nextAttempt: map[int64]time.Time{},
+	}
+}
+
+func (m *memoryStorage) add(userID uuid.UUID, eventType string) {
+	m.events = append(m.events, &entity.Event{
+		ID:          int64(len(m.events) + 1),
+		AggregateID: userID,
+		Type:        eventType,
+	})
+}
+
+func (m *memoryStorage) ClaimEvents(_ context.Context, limit int, lease time.Duration) ([]*entity.Event, error) {
+	now := time.Now()
+	held := make(map[uuid.UUID]bool)
+
+	var batch []*entity.Event
+	for _, event := range m.events {
+		if _, ok := m.published[event.ID]; ok {
+			continue
+		}
+		if held[event.AggregateID] || m.nextAttempt[event.ID].After(now) {
+			held[event.AggregateID] = true
+			continue
+		}
+		if len(batch) < limit {
+			batch = append(batch, event)
+		}
+	}
+
+	for _, event := range batch {
+		m.nextAttempt[event.ID] = now.Add(lease)
+	}
+
+	return batch, nil
+}
+
+func (m *memoryStorage) MarkEventsPublished(_ context.Context, ids []int64) error {
+	for _, id := range ids {
+		m.published[id] = time.Now()
+	}
+	return nil
+}
+
+func (m *memoryStorage) PostponeEvents(_ context.Context, ids []int64, at time.Time) error {
+	for _, id := range ids {
+		m.nextAttempt[id] = at
+	}
+	return nil
+}
+
+func (m *memoryStorage) DeletePublishedEvents(_ context.Context, before time.Time) (int64, error) {
+	var n int64
+	m.events = slices.DeleteFunc(m.events, func(event *entity.Event) bool {
+		publishedAt, ok := m.published[event.ID]
+		if ok && publishedAt.Before(before) {
+			n++
+			return true
+		}
+		return false
+	})
+	return n, nil
+}
+
+// flakyBroker fails events of the user until it is healed.
+type flakyBroker struct {
+	*memory.Broker
+	failing uuid.UUID
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, event *entity.Event) error {
+	if event.AggregateID == b.failing {
+		return errors.New("broker is unavailable")
+	}
+
+	return b.Broker.Publish(ctx, event)
+}
+
+func newRelay(storage Storage, broker Broker, batchSize int) *Relay {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, broker, batchSize, 0, 0, 0)
+}
+
+func eventIDs(events []*entity.Event) []int64 {
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestRelay_PublishesInOrder(t *testing.T) {
+	storage := newMemoryStorage()
+	user := uuid.New()
+	storage.add(user, entity.EventUserRegistered)
+	storage.add(user, entity.EventUserEmailChanged)
+	storage.add(user, entity.EventUserDeleted)
+
+	broker := memory.New()
+	relay := newRelay(storage, broker, 2)
+
+	n, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.Equal(t, []int64{1, 2, 3}, eventIDs(broker.Events()))
+}
+
+func TestRelay_FailedEventHoldsBackItsUser(t *testing.T) {
+	storage := newMemoryStorage()
+	alice, bob := uuid.New(), uuid.New()
+	storage.add(alice, entity.EventUserRegistered)
+	storage.add(bob, entity.EventUserRegistered)
+	storage.add(alice, entity.EventUserEmailChanged)
+	storage.add(bob, entity.EventUserDeleted)
+
+	broker := &flakyBroker{Broker: memory.New(), failing: alice}
+	relay := newRelay(storage, broker, 10)
+
+	n, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []int64{2, 4}, eventIDs(broker.Events()))
+
+	// events of alice are kept and sent in order once the broker recovers
+	broker.failing = uuid.Nil
+
+	n, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, []int64{2, 4, 1, 3}, eventIDs(broker.Events()))
+}
+
+func TestRelay_UnacknowledgedEventsAreSentAgain(t *testing.T) {
+	storage := newMemoryStorage()
+	user := uuid.New()
+	storage.add(user, entity.EventUserRegistered)
+
+	broker := memory.New()
+	relay := newRelay(lostAckStorage{storage}, broker, 10)
+
+	// the broker got the event, but marking it as published was lost, e.g. the relay crashed
+	_, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+
+	// the event is claimed again once its lease runs out
+	_, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+	storage.nextAttempt[1] = time.Now()
+
+	relay = newRelay(storage, broker, 10)
+	_, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{1, 1}, eventIDs(broker.Events()))
+}
+
+// lostAckStorage publishes events but never marks them as published.
+type lostAckStorage struct {
+	*memoryStorage
+}
+
+func (s lostAckStorage) MarkEventsPublished(context.Context, []int64) error {
+	return nil
+}
+
+// commitStorage fails marking events as published with canceled context, as the database does.
+type commitStorage struct {
+	*memoryStorage
+}
+
+func (s commitStorage) MarkEventsPublished(ctx context.Context, ids []int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.memoryStorage.MarkEventsPublished(ctx, ids)
+}
+
+// blockingBroker holds the first event until released.
+type blockingBroker struct {
+	*memory.Broker
+	publishing chan struct{}
+	release    chan struct{}
+}
+
+func (b *blockingBroker) Publish(ctx context.Context, event *entity.Event) error {
+	if event.ID == 1 {
+		close(b.publishing)
+		<-b.release
+	}
+
+	return b.Broker.Publish(ctx, event)
+}
+
+func TestRelay_StopFinishesBatchInFlight(t *testing.T) {
+	storage := newMemoryStorage()
+	user := uuid.New()
+	storage.add(user, entity.EventUserRegistered)
+	storage.add(user, entity.EventUserDeleted)
+
+	broker := &blockingBroker{Broker: memory.New(), publishing: make(chan struct{}), release: make(chan struct{})}
+	relay := New(slog.New(slog.NewTextHandler(io.Discard, nil)), commitStorage{storage}, broker, 10, time.Hour, 0, 0)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(context.Background(), stop)
+	}()
+
+	<-broker.publishing
+	close(stop)
+	close(broker.release)
+	<-done
+
+	assert.Len(t, storage.published, 2, "events of the batch are marked as published")
+}
+
+func TestRelay_FailingUserDoesNotFillBatches(t *testing.T) {
+	storage := newMemoryStorage()
+	alice, bob := uuid.New(), uuid.New()
+	storage.add(alice, entity.EventUserRegistered)
+	storage.add(alice, entity.EventUserEmailChanged)
+	storage.add(alice, entity.EventUserDeleted)
+	storage.add(bob, entity.EventUserRegistered)
+
+	broker := &flakyBroker{Broker: memory.New(), failing: alice}
+	relay := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, broker, 2, 0, time.Hour, 0)
+
+	n, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Empty(t, broker.Events())
+
+	// events of alice wait for the retry, the next batch goes past them
+	n, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int64{4}, eventIDs(broker.Events()))
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	storage := newMemoryStorage()
+	user := uuid.New()
+	storage.add(user, entity.EventUserRegistered)
+	storage.add(user, entity.EventUserEmailChanged)
+	storage.add(user, entity.EventUserDeleted)
+
+	storage.published[1] = time.Now().Add(-2 * time.Hour)
+	storage.published[2] = time.Now()
+
+	relay := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, memory.New(), 10, 0, 0, time.Hour)
+	require.NoError(t, relay.Cleanup(context.Background()))
+
+	assert.Equal(t, []int64{2, 3}, eventIDs(storage.events), "only events published before the retention are deleted")
+}
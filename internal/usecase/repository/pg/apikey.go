@@ -0,0 +1,198 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveAPIKey saves API key of the user in the database and returns its id.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveAPIKey(ctx context.Context, key *entity.APIKey) (keyID uuid.UUID, err error) {
+	const op = "repository.pg.SaveAPIKey"
+
+	sql, args, err := r.qb.
+		Insert(TableAPIKeys).
+		Columns(
+			"user_id",
+			"name",
+			"prefix",
+			"key_hash",
+			"scopes",
+			"expires_at",
+		).
+		Values(
+			key.UserID,
+			key.Name,
+			key.Prefix,
+			key.KeyHash,
+			key.Scopes,
+			key.ExpiresAt,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&keyID)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrScan(op, err)
+	}
+
+	return keyID, nil
+}
+
+// APIKeyByPrefix returns API key by its lookup prefix from the database.
+//
+// If the key is not found, it returns repository.ErrAPIKeyNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) APIKeyByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	const op = "repository.pg.APIKeyByPrefix"
+
+	sql, args, err := r.apiKeySelect().
+		Where(sq.Eq{"prefix": prefix}).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	key := new(entity.APIKey)
+	err = scanAPIKey(r.pool.QueryRow(ctx, sql, args...), key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return key, nil
+}
+
+// APIKeys returns API keys of the user, newest first.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) APIKeys(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error) {
+	const op = "repository.pg.APIKeys"
+
+	sql, args, err := r.apiKeySelect().
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var keys []*entity.APIKey
+	for rows.Next() {
+		key := new(entity.APIKey)
+		if err := scanAPIKey(rows, key); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey deletes API key of the user.
+//
+// If the user has no such key, returns repository.ErrAPIKeyNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) DeleteAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	const op = "repository.pg.DeleteAPIKey"
+
+	sql, args, err := r.qb.
+		Delete(TableAPIKeys).
+		Where(sq.Eq{
+			"id":      keyID,
+			"user_id": userID,
+		}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey records that the key was used now from the ip.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) TouchAPIKey(ctx context.Context, keyID uuid.UUID, ip string) error {
+	const op = "repository.pg.TouchAPIKey"
+
+	sql, args, err := r.qb.
+		Update(TableAPIKeys).
+		Set("last_used_at", sq.Expr("now()")).
+		Set("last_used_ip", ip).
+		Where(sq.Eq{"id": keyID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) apiKeySelect() sq.SelectBuilder {
+	return r.qb.
+		Select(
+			"id",
+			"user_id",
+			"name",
+			"prefix",
+			"key_hash",
+			"scopes",
+			"expires_at",
+			"last_used_at",
+			"COALESCE(last_used_ip, '')",
+			"created_at",
+		).
+		From(TableAPIKeys)
+}
+
+func scanAPIKey(row pgx.Row, key *entity.APIKey) error {
+	return row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.CreatedAt,
+	)
+}
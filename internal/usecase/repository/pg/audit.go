@@ -0,0 +1,197 @@
+package pg
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveAuditEvent appends the event to the audit log and returns its id.
+// Unlike other records, the event is saved to its own tenant rather than the context one.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveAuditEvent(ctx context.Context, event *entity.AuditEvent) (eventID int64, err error) {
+	const op = "repository.pg.SaveAuditEvent"
+
+	details := event.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	sql, args, err := r.qb.
+		Insert(TableAuditLog).
+		Columns(
+			"tenant_id",
+			"type",
+			"user_id",
+			"actor_id",
+			"outcome",
+			"ip",
+			"user_agent",
+			"details",
+		).
+		Values(
+			event.TenantID,
+			event.Type,
+			nullUUID(event.UserID),
+			nullUUID(event.ActorID),
+			event.Outcome,
+			nullString(event.IP),
+			nullString(event.UserAgent),
+			details,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&eventID)
+	if err != nil {
+		return 0, pgerr.ErrScan(op, err)
+	}
+
+	return eventID, nil
+}
+
+// AuditEvents returns at most limit events of the context tenant matching the filter, newest first.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) AuditEvents(ctx context.Context, filter entity.AuditFilter, limit int) ([]*entity.AuditEvent, error) {
+	const op = "repository.pg.AuditEvents"
+
+	query := r.qb.
+		Select(
+			"id",
+			"tenant_id",
+			"type",
+			"user_id",
+			"actor_id",
+			"outcome",
+			"COALESCE(ip, '')",
+			"COALESCE(user_agent, '')",
+			"details",
+			"created_at",
+		).
+		From(TableAuditLog).
+		Where(auditFilter(ctx, filter)).
+		OrderBy("id DESC").
+		Limit(uint64(limit))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var events []*entity.AuditEvent
+	for rows.Next() {
+		event := new(entity.AuditEvent)
+		if err := scanAuditEvent(rows, event); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return events, nil
+}
+
+// CountAuditEvents returns number of events of the context tenant matching the filter.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) CountAuditEvents(ctx context.Context, filter entity.AuditFilter) (int, error) {
+	const op = "repository.pg.CountAuditEvents"
+
+	sql, args, err := r.qb.
+		Select("count(*)").
+		From(TableAuditLog).
+		Where(auditFilter(ctx, filter)).
+		ToSql()
+	if err != nil {
+		return 0, pgerr.ErrCreateQuery(op, err)
+	}
+
+	var count int
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, pgerr.ErrScan(op, err)
+	}
+
+	return count, nil
+}
+
+func auditFilter(ctx context.Context, filter entity.AuditFilter) sq.And {
+	where := sq.And{sq.Eq{"tenant_id": tenancy.FromContext(ctx)}}
+
+	if filter.UserID != uuid.Nil {
+		where = append(where, sq.Eq{"user_id": filter.UserID})
+	}
+	if filter.Type != "" {
+		where = append(where, sq.Eq{"type": filter.Type})
+	}
+	if filter.Outcome != "" {
+		where = append(where, sq.Eq{"outcome": filter.Outcome})
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, sq.GtOrEq{"created_at": filter.Since})
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, sq.Lt{"created_at": filter.Until})
+	}
+	if filter.AfterID != 0 {
+		where = append(where, sq.Lt{"id": filter.AfterID})
+	}
+
+	return where
+}
+
+func scanAuditEvent(row pgx.Row, event *entity.AuditEvent) error {
+	var userID, actorID *uuid.UUID
+
+	err := row.Scan(
+		&event.ID,
+		&event.TenantID,
+		&event.Type,
+		&userID,
+		&actorID,
+		&event.Outcome,
+		&event.IP,
+		&event.UserAgent,
+		&event.Details,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if userID != nil {
+		event.UserID = *userID
+	}
+	if actorID != nil {
+		event.ActorID = *actorID
+	}
+
+	return nil
+}
+
+// nullUUID stores nil uuid as NULL.
+func nullUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return &id
+}
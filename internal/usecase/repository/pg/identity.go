@@ -0,0 +1,136 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// Identity returns identity of the external provider by its subject within the context tenant.
+//
+// If the identity is not found, it returns repository.ErrIdentityNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) Identity(ctx context.Context, provider, subject string) (*entity.Identity, error) {
+	const op = "repository.pg.Identity"
+
+	sql, args, err := r.qb.
+		Select(
+			"provider",
+			"subject",
+			"user_id",
+			"email",
+			"created_at",
+		).
+		From(TableIdentities).
+		Where(
+			sq.Eq{
+				"tenant_id": tenancy.FromContext(ctx),
+				"provider":  provider,
+				"subject":   subject,
+			},
+		).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	identity := new(entity.Identity)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrIdentityNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return identity, nil
+}
+
+// SaveIdentity links identity of the external provider to an existing user.
+//
+// If the identity is already linked, returns repository.ErrIdentityExists.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveIdentity(ctx context.Context, identity *entity.Identity) error {
+	const op = "repository.pg.SaveIdentity"
+
+	return r.saveIdentity(ctx, r.pool, op, identity)
+}
+
+// SaveUserWithIdentity creates new user linked to identity of the external provider
+// in one transaction along with EventUserRegistered in the outbox and returns id of the user.
+//
+// If user with given email already exists, returns repository.ErrUserExists.
+// If the identity is already linked, returns repository.ErrIdentityExists.
+func (r *Repository) SaveUserWithIdentity(ctx context.Context, email string, passHash []byte, identity *entity.Identity) (userID uuid.UUID, err error) {
+	const op = "repository.pg.SaveUserWithIdentity"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateTx(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err = r.insertUser(ctx, tx, op, email, passHash, []string{})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	identity.UserID = userID
+	if err := r.saveIdentity(ctx, tx, op, identity); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, pgerr.ErrCommit(op, err)
+	}
+
+	return userID, nil
+}
+
+func (r *Repository) saveIdentity(ctx context.Context, db execer, op string, identity *entity.Identity) error {
+	sql, args, err := r.qb.
+		Insert(TableIdentities).
+		Columns(
+			"tenant_id",
+			"provider",
+			"subject",
+			"user_id",
+			"email",
+		).
+		Values(
+			tenancy.FromContext(ctx),
+			identity.Provider,
+			identity.Subject,
+			identity.UserID,
+			identity.Email,
+		).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := db.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrIdentityExists
+	}
+
+	return nil
+}
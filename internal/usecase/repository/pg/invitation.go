@@ -0,0 +1,226 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// pendingInvitation matches invitations that are neither accepted, revoked nor expired.
+var pendingInvitation = sq.And{
+	sq.Eq{"accepted_at": nil},
+	sq.Eq{"revoked_at": nil},
+	sq.Expr("expires_at > now()"),
+}
+
+// SaveInvitation saves invitation to the context tenant in the database and returns its id.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveInvitation(ctx context.Context, invitation *entity.Invitation) (invitationID uuid.UUID, err error) {
+	const op = "repository.pg.SaveInvitation"
+
+	sql, args, err := r.qb.
+		Insert(TableInvitations).
+		Columns(
+			"tenant_id",
+			"email",
+			"role",
+			"invited_by",
+			"expires_at",
+		).
+		Values(
+			tenancy.FromContext(ctx),
+			invitation.Email,
+			invitation.Role,
+			invitation.InvitedBy,
+			invitation.ExpiresAt,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&invitationID)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrScan(op, err)
+	}
+
+	return invitationID, nil
+}
+
+// Invitation returns invitation by id from the database.
+//
+// If the invitation is not found, it returns repository.ErrInvitationNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) Invitation(ctx context.Context, invitationID uuid.UUID) (*entity.Invitation, error) {
+	const op = "repository.pg.Invitation"
+
+	sql, args, err := r.invitationSelect().
+		Where(sq.Eq{"id": invitationID}).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	invitation := new(entity.Invitation)
+	err = scanInvitation(r.pool.QueryRow(ctx, sql, args...), invitation)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrInvitationNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return invitation, nil
+}
+
+// PendingInvitations returns invitations of the context tenant that can still be accepted,
+// newest first.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) PendingInvitations(ctx context.Context) ([]*entity.Invitation, error) {
+	const op = "repository.pg.PendingInvitations"
+
+	sql, args, err := r.invitationSelect().
+		Where(sq.Eq{"tenant_id": tenancy.FromContext(ctx)}).
+		Where(pendingInvitation).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var invitations []*entity.Invitation
+	for rows.Next() {
+		invitation := new(entity.Invitation)
+		if err := scanInvitation(rows, invitation); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		invitations = append(invitations, invitation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation revokes pending invitation of the context tenant.
+//
+// If the pending invitation is not found, returns repository.ErrInvitationNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) RevokeInvitation(ctx context.Context, invitationID uuid.UUID) error {
+	const op = "repository.pg.RevokeInvitation"
+
+	sql, args, err := r.qb.
+		Update(TableInvitations).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{
+			"id":        invitationID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		Where(pendingInvitation).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation marks pending invitation as accepted by the user.
+// Only one of concurrent calls succeeds.
+//
+// If the pending invitation is not found, returns repository.ErrInvitationNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) AcceptInvitation(ctx context.Context, invitationID, userID uuid.UUID) error {
+	const op = "repository.pg.AcceptInvitation"
+
+	sql, args, err := r.qb.
+		Update(TableInvitations).
+		Set("accepted_at", sq.Expr("now()")).
+		Set("accepted_by", userID).
+		Where(sq.Eq{"id": invitationID}).
+		Where(pendingInvitation).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) invitationSelect() sq.SelectBuilder {
+	return r.qb.
+		Select(
+			"id",
+			"tenant_id",
+			"email",
+			"role",
+			"invited_by",
+			"expires_at",
+			"accepted_at",
+			"revoked_at",
+			"created_at",
+		).
+		From(TableInvitations)
+}
+
+func scanInvitation(row pgx.Row, invitation *entity.Invitation) error {
+	// inviter may be deleted after sending the invitation
+	var invitedBy *uuid.UUID
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.TenantID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitedBy,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+		&invitation.RevokedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if invitedBy != nil {
+		invitation.InvitedBy = *invitedBy
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package pg
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveLogin appends sign-in of the user to the login history and returns its id.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveLogin(ctx context.Context, login *entity.Login) (loginID int64, err error) {
+	const op = "repository.pg.SaveLogin"
+
+	sql, args, err := r.qb.
+		Insert(TableLoginHistory).
+		Columns(
+			"user_id",
+			"ip",
+			"network",
+			"user_agent",
+			"device_fingerprint",
+		).
+		Values(
+			login.UserID,
+			login.IP,
+			login.Network,
+			login.UserAgent,
+			login.DeviceFingerprint,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&loginID)
+	if err != nil {
+		return 0, pgerr.ErrScan(op, err)
+	}
+
+	return loginID, nil
+}
+
+// LoginSeen reports whether the user has signed in before at all,
+// from the device and from the network.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) LoginSeen(ctx context.Context, userID uuid.UUID, fingerprint, network string) (seen, deviceSeen, networkSeen bool, err error) {
+	const op = "repository.pg.LoginSeen"
+
+	sql, args, err := r.qb.
+		Select("count(*) > 0").
+		Column(sq.Expr("COALESCE(bool_or(device_fingerprint = ?), false)", fingerprint)).
+		Column(sq.Expr("COALESCE(bool_or(network = ?), false)", network)).
+		From(TableLoginHistory).
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return false, false, false, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&seen, &deviceSeen, &networkSeen)
+	if err != nil {
+		return false, false, false, pgerr.ErrScan(op, err)
+	}
+
+	return seen, deviceSeen, networkSeen, nil
+}
+
+// Logins returns at most limit latest sign-ins of the user, newest first.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) Logins(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.Login, error) {
+	const op = "repository.pg.Logins"
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"user_id",
+			"ip",
+			"network",
+			"user_agent",
+			"device_fingerprint",
+			"created_at",
+		).
+		From(TableLoginHistory).
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var logins []*entity.Login
+	for rows.Next() {
+		login := new(entity.Login)
+		if err := scanLogin(rows, login); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return logins, nil
+}
+
+func scanLogin(row pgx.Row, login *entity.Login) error {
+	return row.Scan(
+		&login.ID,
+		&login.UserID,
+		&login.IP,
+		&login.Network,
+		&login.UserAgent,
+		&login.DeviceFingerprint,
+		&login.CreatedAt,
+	)
+}
@@ -0,0 +1,283 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveClient saves OAuth client of the context tenant in the database and returns its id.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveClient(ctx context.Context, client *entity.Client) (clientID uuid.UUID, err error) {
+	const op = "repository.pg.SaveClient"
+
+	sql, args, err := r.qb.
+		Insert(TableOAuthClients).
+		Columns(
+			"tenant_id",
+			"secret_hash",
+			"name",
+			"redirect_uris",
+			"scopes",
+			"grant_types",
+		).
+		Values(
+			tenancy.FromContext(ctx),
+			client.SecretHash,
+			client.Name,
+			client.RedirectURIs,
+			client.Scopes,
+			client.GrantTypes,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&clientID)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrScan(op, err)
+	}
+
+	return clientID, nil
+}
+
+// Client returns OAuth client by id from the database.
+// Clients of all tenants are looked up, the token endpoint is called without tenant.
+//
+// If the client is not found, it returns repository.ErrClientNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) Client(ctx context.Context, clientID uuid.UUID) (*entity.Client, error) {
+	const op = "repository.pg.Client"
+
+	sql, args, err := r.qb.
+		Select(
+			"id",
+			"tenant_id",
+			"secret_hash",
+			"name",
+			"redirect_uris",
+			"scopes",
+			"grant_types",
+			"created_at",
+		).
+		From(TableOAuthClients).
+		Where(
+			sq.Eq{"id": clientID},
+		).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	client := new(entity.Client)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&client.ID,
+		&client.TenantID,
+		&client.SecretHash,
+		&client.Name,
+		&client.RedirectURIs,
+		&client.Scopes,
+		&client.GrantTypes,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrClientNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return client, nil
+}
+
+// SaveAuthorizationCode saves authorization code in the database.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveAuthorizationCode(ctx context.Context, code *entity.AuthorizationCode) error {
+	const op = "repository.pg.SaveAuthorizationCode"
+
+	sql, args, err := r.qb.
+		Insert(TableOAuthAuthorizationCodes).
+		Columns(
+			"code_hash",
+			"client_id",
+			"user_id",
+			"redirect_uri",
+			"scopes",
+			"code_challenge",
+			"code_challenge_method",
+			"nonce",
+			"auth_time",
+			"expires_at",
+		).
+		Values(
+			code.CodeHash,
+			code.ClientID,
+			code.UserID,
+			code.RedirectURI,
+			code.Scopes,
+			code.CodeChallenge,
+			code.CodeChallengeMethod,
+			code.Nonce,
+			code.AuthTime,
+			code.ExpiresAt,
+		).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	_, err = r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode deletes authorization code from the database and returns it,
+// so every code can be exchanged only once.
+//
+// If the code is not found, it returns repository.ErrAuthorizationCodeNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) ConsumeAuthorizationCode(ctx context.Context, codeHash []byte) (*entity.AuthorizationCode, error) {
+	const op = "repository.pg.ConsumeAuthorizationCode"
+
+	sql, args, err := r.qb.
+		Delete(TableOAuthAuthorizationCodes).
+		Where(
+			sq.Eq{"code_hash": codeHash},
+		).
+		Suffix(`RETURNING
+			code_hash,
+			client_id,
+			user_id,
+			redirect_uri,
+			scopes,
+			code_challenge,
+			code_challenge_method,
+			nonce,
+			auth_time,
+			expires_at,
+			created_at`).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	code := new(entity.AuthorizationCode)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scopes,
+		&code.CodeChallenge,
+		&code.CodeChallengeMethod,
+		&code.Nonce,
+		&code.AuthTime,
+		&code.ExpiresAt,
+		&code.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrAuthorizationCodeNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return code, nil
+}
+
+// SaveRefreshToken saves refresh token in the database.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	const op = "repository.pg.SaveRefreshToken"
+
+	sql, args, err := r.qb.
+		Insert(TableOAuthRefreshTokens).
+		Columns(
+			"token_hash",
+			"client_id",
+			"user_id",
+			"scopes",
+			"auth_time",
+			"expires_at",
+		).
+		Values(
+			token.TokenHash,
+			token.ClientID,
+			token.UserID,
+			token.Scopes,
+			token.AuthTime,
+			token.ExpiresAt,
+		).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	_, err = r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// ConsumeRefreshToken deletes refresh token from the database and returns it,
+// so every refresh token can be used only once.
+//
+// If the token is not found, it returns repository.ErrRefreshTokenNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) ConsumeRefreshToken(ctx context.Context, tokenHash []byte) (*entity.RefreshToken, error) {
+	const op = "repository.pg.ConsumeRefreshToken"
+
+	sql, args, err := r.qb.
+		Delete(TableOAuthRefreshTokens).
+		Where(
+			sq.Eq{"token_hash": tokenHash},
+		).
+		Suffix(`RETURNING
+			token_hash,
+			client_id,
+			user_id,
+			scopes,
+			auth_time,
+			expires_at,
+			created_at`).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	token := new(entity.RefreshToken)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&token.TokenHash,
+		&token.ClientID,
+		&token.UserID,
+		&token.Scopes,
+		&token.AuthTime,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrRefreshTokenNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,147 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveOTP saves one-time passcode of the user in the database.
+//
+// A user has at most one active code, so the previous code (if any) is replaced.
+// Attempts of the previous code are kept unless it expired, so requesting
+// new codes doesn't give more attempts to guess them.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveOTP(ctx context.Context, userID uuid.UUID, codeHash []byte, expiresAt time.Time) error {
+	const op = "repository.pg.SaveOTP"
+
+	sql, args, err := r.qb.
+		Insert(TableOTPCodes).
+		Columns(
+			"user_id",
+			"code_hash",
+			"expires_at",
+		).
+		Values(
+			userID,
+			codeHash,
+			expiresAt,
+		).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE SET
+			code_hash = EXCLUDED.code_hash,
+			attempts = CASE WHEN ` + TableOTPCodes + `.expires_at > now() THEN ` + TableOTPCodes + `.attempts ELSE 0 END,
+			expires_at = EXCLUDED.expires_at,
+			created_at = now()`).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	_, err = r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// OTP returns active one-time passcode of the user from the database.
+//
+// If the code is not found, it returns repository.ErrOTPNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) OTP(ctx context.Context, userID uuid.UUID) (*entity.OTP, error) {
+	const op = "repository.pg.OTP"
+
+	sql, args, err := r.qb.
+		Select(
+			"user_id",
+			"code_hash",
+			"attempts",
+			"expires_at",
+			"created_at",
+		).
+		From(TableOTPCodes).
+		Where(
+			sq.Eq{"user_id": userID},
+		).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	otp := new(entity.OTP)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&otp.UserID,
+		&otp.CodeHash,
+		&otp.Attempts,
+		&otp.ExpiresAt,
+		&otp.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrOTPNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return otp, nil
+}
+
+// IncrementOTPAttempts increments the number of verification attempts of the
+// user's one-time passcode and returns the new value.
+//
+// If the code is not found, it returns repository.ErrOTPNotFound.
+func (r *Repository) IncrementOTPAttempts(ctx context.Context, userID uuid.UUID) (attempts int, err error) {
+	const op = "repository.pg.IncrementOTPAttempts"
+
+	sql, args, err := r.qb.
+		Update(TableOTPCodes).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Where(
+			sq.Eq{"user_id": userID},
+		).
+		Suffix("RETURNING attempts").
+		ToSql()
+	if err != nil {
+		return 0, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, repository.ErrOTPNotFound
+		}
+		return 0, pgerr.ErrScan(op, err)
+	}
+
+	return attempts, nil
+}
+
+// DeleteOTP deletes one-time passcode of the user from the database.
+func (r *Repository) DeleteOTP(ctx context.Context, userID uuid.UUID) error {
+	const op = "repository.pg.DeleteOTP"
+
+	sql, args, err := r.qb.
+		Delete(TableOTPCodes).
+		Where(
+			sq.Eq{"user_id": userID},
+		).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	_, err = r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package pg
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// outboxLockKey is a key of the advisory lock taken while events are claimed,
+// so concurrent relays don't claim later events of a user whose earlier event is being claimed.
+const outboxLockKey = 0x6f7574626f78
+
+// ClaimEvents returns at most limit oldest unpublished events due for an attempt, ordered by id.
+// Events are skipped while an earlier event of their user waits for an attempt, so events
+// of a user are published in order and a failing user doesn't hold back the others.
+// Claimed events are postponed by lease, so concurrent relays don't publish them,
+// and are claimed again after the lease if the relay crashes.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]*entity.Event, error) {
+	const op = "repository.pg.ClaimEvents"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, pgerr.ErrCreateTx(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", outboxLockKey); err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+
+	now := time.Now()
+
+	// subqueries keep question placeholders, the outer query numbers them all
+	held := sq.
+		Select("1").
+		From(TableOutbox + " earlier").
+		Where("earlier.aggregate_id = " + TableOutbox + ".aggregate_id").
+		Where("earlier.id < " + TableOutbox + ".id").
+		Where(sq.Eq{"earlier.published_at": nil}).
+		Where(sq.Gt{"earlier.next_attempt_at": now})
+
+	due := sq.
+		Select("id").
+		From(TableOutbox).
+		Where(sq.Eq{"published_at": nil}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		Where(sq.Expr("NOT EXISTS (?)", held)).
+		OrderBy("id").
+		Limit(uint64(limit))
+
+	sql, args, err := r.qb.
+		Update(TableOutbox).
+		Set("next_attempt_at", now.Add(lease)).
+		Where(sq.Expr("id IN (?)", due)).
+		Suffix("RETURNING id, aggregate_id, type, payload, created_at").
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*entity.Event, error) {
+		event := new(entity.Event)
+		err := row.Scan(
+			&event.ID,
+			&event.AggregateID,
+			&event.Type,
+			&event.Payload,
+			&event.CreatedAt,
+		)
+		return event, err
+	})
+	if err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, pgerr.ErrCommit(op, err)
+	}
+
+	// RETURNING doesn't keep the order of the subquery
+	slices.SortFunc(events, func(a, b *entity.Event) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return events, nil
+}
+
+// MarkEventsPublished marks events with the ids as published.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) MarkEventsPublished(ctx context.Context, ids []int64) error {
+	const op = "repository.pg.MarkEventsPublished"
+
+	sql, args, err := r.qb.
+		Update(TableOutbox).
+		Set("published_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": ids}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// PostponeEvents makes events with the ids due for an attempt at the time.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) PostponeEvents(ctx context.Context, ids []int64, at time.Time) error {
+	const op = "repository.pg.PostponeEvents"
+
+	sql, args, err := r.qb.
+		Update(TableOutbox).
+		Set("next_attempt_at", at).
+		Where(sq.Eq{"id": ids}).
+		Where(sq.Eq{"published_at": nil}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// DeletePublishedEvents deletes events published before the time and returns how many were deleted.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "repository.pg.DeletePublishedEvents"
+
+	sql, args, err := r.qb.
+		Delete(TableOutbox).
+		Where(sq.Lt{"published_at": before}).
+		ToSql()
+	if err != nil {
+		return 0, pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, pgerr.ErrExec(op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// saveEvent appends domain event about the user to the outbox within the transaction of the change.
+func (r *Repository) saveEvent(ctx context.Context, db execer, op, eventType string, userID uuid.UUID, payload map[string]any) error {
+	payload["type"] = eventType
+	payload["occurred_at"] = time.Now().UTC()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	sql, args, err := r.qb.
+		Insert(TableOutbox).
+		Columns(
+			"aggregate_id",
+			"type",
+			"payload",
+		).
+		Values(
+			userID,
+			eventType,
+			body,
+		).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := db.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
@@ -7,17 +7,30 @@ import (
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
 	"github.com/kurochkinivan/auth/internal/usecase/repository"
 	"github.com/kurochkinivan/auth/pkg/pgerr"
 )
 
+// Repository stores data in PostgreSQL.
+//
+// Users and identities are scoped to the tenant of the context, see tenancy.FromContext:
+// lookups by email or external subject see only users of that tenant and new users are created in it.
+// Lookups by id are not scoped, ids are unique across tenants.
 type Repository struct {
 	pool *pgxpool.Pool
 	qb   sq.StatementBuilderType
 }
 
+// execer is implemented by both pool and transaction,
+// so queries can be shared between transactional and plain methods.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
 func New(pool *pgxpool.Pool) *Repository {
 	return &Repository{
 		pool: pool,
@@ -25,26 +38,83 @@ func New(pool *pgxpool.Pool) *Repository {
 	}
 }
 
+// uniqueViolation is SQLSTATE of unique constraint violation.
+const uniqueViolation = "23505"
+
 const (
-	TableUsers = "users"
+	TableUsers                    = "users"
+	TableOTPCodes                 = "otp_codes"
+	TableOAuthClients             = "oauth_clients"
+	TableOAuthAuthorizationCodes  = "oauth_authorization_codes"
+	TableOAuthRefreshTokens       = "oauth_refresh_tokens"
+	TableIdentities               = "identities"
+	TableTenants                  = "tenants"
+	TableInvitations              = "invitations"
+	TableAPIKeys                  = "api_keys"
+	TableServiceAccounts          = "service_accounts"
+	TableServiceAccountAssertions = "service_account_assertions"
+	TableAuditLog                 = "audit_log"
+	TableLoginHistory             = "login_history"
+	TableOutbox                   = "outbox"
+	TableWebhooks                 = "webhooks"
+	TableWebhookDeliveries        = "webhook_deliveries"
 )
 
-// SaveUser saves user in the database.
+// SaveUser saves user in the database along with EventUserRegistered in the outbox.
 //
 // If user with given email already exists, returns error repository.ErrUserExists.
 // If an error occurs during query execution, returns an error.
 func (r *Repository) SaveUser(ctx context.Context, email string, passHash []byte) (userID uuid.UUID, err error) {
 	const op = "repository.pg.SaveUser"
 
+	return r.saveUser(ctx, op, email, passHash, []string{})
+}
+
+// SaveDirectoryUser saves user authenticated by the directory with its roles
+// along with EventUserRegistered in the outbox.
+//
+// If user with given email already exists, returns error repository.ErrUserExists.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveDirectoryUser(ctx context.Context, email string, passHash []byte, roles []string) (userID uuid.UUID, err error) {
+	const op = "repository.pg.SaveDirectoryUser"
+
+	return r.saveUser(ctx, op, email, passHash, roles)
+}
+
+func (r *Repository) saveUser(ctx context.Context, op, email string, passHash []byte, roles []string) (userID uuid.UUID, err error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateTx(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err = r.insertUser(ctx, tx, op, email, passHash, roles)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, pgerr.ErrCommit(op, err)
+	}
+
+	return userID, nil
+}
+
+// insertUser inserts user into the context tenant and EventUserRegistered into the outbox.
+func (r *Repository) insertUser(ctx context.Context, tx pgx.Tx, op, email string, passHash []byte, roles []string) (userID uuid.UUID, err error) {
 	sql, args, err := r.qb.
 		Insert(TableUsers).
 		Columns(
+			"tenant_id",
 			"email",
 			"password",
+			"roles",
 		).
 		Values(
+			tenancy.FromContext(ctx),
 			email,
 			passHash,
+			roles,
 		).
 		Suffix("ON CONFLICT DO NOTHING").
 		Suffix("RETURNING id").
@@ -53,7 +123,7 @@ func (r *Repository) SaveUser(ctx context.Context, email string, passHash []byte
 		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
 	}
 
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&userID)
+	err = tx.QueryRow(ctx, sql, args...).Scan(&userID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return uuid.Nil, repository.ErrUserExists
@@ -61,28 +131,241 @@ func (r *Repository) SaveUser(ctx context.Context, email string, passHash []byte
 		return uuid.Nil, pgerr.ErrScan(op, err)
 	}
 
+	err = r.saveEvent(ctx, tx, op, entity.EventUserRegistered, userID, map[string]any{
+		"user_id":   userID,
+		"tenant_id": tenancy.FromContext(ctx),
+		"email":     email,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	return userID, nil
 }
 
-// User returns a user by email from the database.
+// UpdateUserRoles replaces roles of the user.
+//
+// If the user is not found, returns repository.ErrUserNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) UpdateUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error {
+	const op = "repository.pg.UpdateUserRoles"
+
+	sql, args, err := r.qb.
+		Update(TableUsers).
+		Set("roles", roles).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateUserPhone changes phone of the user, empty phone removes it.
+//
+// If the user is not found, returns repository.ErrUserNotFound.
+// If the phone is taken by another user of the tenant, returns repository.ErrUserExists.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) UpdateUserPhone(ctx context.Context, userID uuid.UUID, phone string) error {
+	const op = "repository.pg.UpdateUserPhone"
+
+	var value any
+	if phone != "" {
+		value = phone
+	}
+
+	sql, args, err := r.qb.
+		Update(TableUsers).
+		Set("phone", value).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return repository.ErrUserExists
+		}
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateUserEmail changes email of the user and saves EventUserEmailChanged in the outbox.
+//
+// If the user is not found, returns repository.ErrUserNotFound.
+// If the email is taken by another user of the tenant, returns repository.ErrUserExists.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) UpdateUserEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	const op = "repository.pg.UpdateUserEmail"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return pgerr.ErrCreateTx(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql, args, err := r.qb.
+		Select("tenant_id", "email").
+		From(TableUsers).
+		Where(sq.Eq{"id": userID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	var tenantID uuid.UUID
+	var oldEmail string
+	err = tx.QueryRow(ctx, sql, args...).Scan(&tenantID, &oldEmail)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrUserNotFound
+		}
+		return pgerr.ErrScan(op, err)
+	}
+
+	sql, args, err = r.qb.
+		Update(TableUsers).
+		Set("email", email).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return repository.ErrUserExists
+		}
+		return pgerr.ErrExec(op, err)
+	}
+
+	err = r.saveEvent(ctx, tx, op, entity.EventUserEmailChanged, userID, map[string]any{
+		"user_id":   userID,
+		"tenant_id": tenantID,
+		"old_email": oldEmail,
+		"email":     email,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return pgerr.ErrCommit(op, err)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes user of the context tenant and saves EventUserDeleted in the outbox.
+//
+// If the user is not found, returns repository.ErrUserNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	const op = "repository.pg.DeleteUser"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return pgerr.ErrCreateTx(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql, args, err := r.qb.
+		Delete(TableUsers).
+		Where(sq.Eq{
+			"id":        userID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		Suffix("RETURNING email").
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	var email string
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrUserNotFound
+		}
+		return pgerr.ErrScan(op, err)
+	}
+
+	err = r.saveEvent(ctx, tx, op, entity.EventUserDeleted, userID, map[string]any{
+		"user_id":   userID,
+		"tenant_id": tenancy.FromContext(ctx),
+		"email":     email,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return pgerr.ErrCommit(op, err)
+	}
+
+	return nil
+}
+
+// User returns a user of the context tenant by email from the database.
 //
 // If the user is not found, it returns repository.ErrUserNotFound.
 // If an error occurs during query execution, it returns an error.
 func (r *Repository) User(ctx context.Context, email string) (*entity.User, error) {
 	const op = "repository.pg.User"
 
+	return r.user(ctx, op, sq.Eq{
+		"tenant_id": tenancy.FromContext(ctx),
+		"email":     email,
+	})
+}
+
+// UserByID returns a user by id from the database.
+//
+// If the user is not found, it returns repository.ErrUserNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) UserByID(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
+	const op = "repository.pg.UserByID"
+
+	return r.user(ctx, op, sq.Eq{"id": userID})
+}
+
+func (r *Repository) user(ctx context.Context, op string, where sq.Sqlizer) (*entity.User, error) {
 	sql, args, err := r.qb.
 		Select(
 			"id",
+			"tenant_id",
 			"email",
+			"COALESCE(phone, '')",
 			"password",
+			"roles",
 			"created_at",
 			"updated_at",
 		).
 		From(TableUsers).
-		Where(
-			sq.Eq{"email": email},
-		).
+		Where(where).
 		ToSql()
 	if err != nil {
 		return nil, pgerr.ErrCreateQuery(op, err)
@@ -91,8 +374,11 @@ func (r *Repository) User(ctx context.Context, email string) (*entity.User, erro
 	user := new(entity.User)
 	err = r.pool.QueryRow(ctx, sql, args...).Scan(
 		&user.ID,
+		&user.TenantID,
 		&user.Email,
+		&user.Phone,
 		&user.PassHash,
+		&user.Roles,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
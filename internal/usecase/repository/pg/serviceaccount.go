@@ -0,0 +1,263 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveServiceAccount saves service account of the context tenant in the database and returns its id.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveServiceAccount(ctx context.Context, account *entity.ServiceAccount) (accountID uuid.UUID, err error) {
+	const op = "repository.pg.SaveServiceAccount"
+
+	sql, args, err := r.qb.
+		Insert(TableServiceAccounts).
+		Columns(
+			"tenant_id",
+			"name",
+			"secret_hash",
+			"public_key",
+			"scopes",
+		).
+		Values(
+			tenancy.FromContext(ctx),
+			account.Name,
+			account.SecretHash,
+			nullString(account.PublicKey),
+			account.Scopes,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&accountID)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrScan(op, err)
+	}
+
+	return accountID, nil
+}
+
+// ServiceAccount returns service account of the context tenant by id from the database.
+//
+// If the service account is not found, it returns repository.ErrServiceAccountNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) ServiceAccount(ctx context.Context, accountID uuid.UUID) (*entity.ServiceAccount, error) {
+	const op = "repository.pg.ServiceAccount"
+
+	sql, args, err := r.serviceAccountSelect().
+		Where(sq.Eq{
+			"id":        accountID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	account := new(entity.ServiceAccount)
+	err = scanServiceAccount(r.pool.QueryRow(ctx, sql, args...), account)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrServiceAccountNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return account, nil
+}
+
+// ServiceAccounts returns service accounts of the context tenant, newest first.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) ServiceAccounts(ctx context.Context) ([]*entity.ServiceAccount, error) {
+	const op = "repository.pg.ServiceAccounts"
+
+	sql, args, err := r.serviceAccountSelect().
+		Where(sq.Eq{"tenant_id": tenancy.FromContext(ctx)}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var accounts []*entity.ServiceAccount
+	for rows.Next() {
+		account := new(entity.ServiceAccount)
+		if err := scanServiceAccount(rows, account); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return accounts, nil
+}
+
+// UpdateServiceAccountSecret replaces secret hash of the service account of the context tenant.
+//
+// If the service account is not found, returns repository.ErrServiceAccountNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) UpdateServiceAccountSecret(ctx context.Context, accountID uuid.UUID, secretHash []byte) error {
+	const op = "repository.pg.UpdateServiceAccountSecret"
+
+	sql, args, err := r.qb.
+		Update(TableServiceAccounts).
+		Set("secret_hash", secretHash).
+		Where(sq.Eq{
+			"id":        accountID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrServiceAccountNotFound
+	}
+
+	return nil
+}
+
+// DeleteServiceAccount deletes service account of the context tenant.
+//
+// If the service account is not found, returns repository.ErrServiceAccountNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) DeleteServiceAccount(ctx context.Context, accountID uuid.UUID) error {
+	const op = "repository.pg.DeleteServiceAccount"
+
+	sql, args, err := r.qb.
+		Delete(TableServiceAccounts).
+		Where(sq.Eq{
+			"id":        accountID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrServiceAccountNotFound
+	}
+
+	return nil
+}
+
+// SaveAssertionID saves id of JWT assertion of the service account until the assertion expires.
+// Expired ids of the service account are deleted along the way.
+//
+// If the id is already saved, returns repository.ErrAssertionUsed.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveAssertionID(ctx context.Context, accountID uuid.UUID, jti string, expiresAt time.Time) error {
+	const op = "repository.pg.SaveAssertionID"
+
+	sql, args, err := r.qb.
+		Delete(TableServiceAccountAssertions).
+		Where(sq.Eq{"service_account_id": accountID}).
+		Where("expires_at < now()").
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	sql, args, err = r.qb.
+		Insert(TableServiceAccountAssertions).
+		Columns(
+			"service_account_id",
+			"jti",
+			"expires_at",
+		).
+		Values(
+			accountID,
+			jti,
+			expiresAt,
+		).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrAssertionUsed
+	}
+
+	return nil
+}
+
+func (r *Repository) serviceAccountSelect() sq.SelectBuilder {
+	return r.qb.
+		Select(
+			"id",
+			"tenant_id",
+			"name",
+			"secret_hash",
+			"COALESCE(public_key, '')",
+			"scopes",
+			"created_at",
+		).
+		From(TableServiceAccounts)
+}
+
+func scanServiceAccount(row pgx.Row, account *entity.ServiceAccount) error {
+	return row.Scan(
+		&account.ID,
+		&account.TenantID,
+		&account.Name,
+		&account.SecretHash,
+		&account.PublicKey,
+		&account.Scopes,
+		&account.CreatedAt,
+	)
+}
+
+// nullString stores empty string as NULL.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
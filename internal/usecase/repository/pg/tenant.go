@@ -0,0 +1,204 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveTenant saves tenant in the database and returns its id.
+//
+// If tenant with given slug already exists, returns repository.ErrTenantExists.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveTenant(ctx context.Context, slug, name string) (tenantID uuid.UUID, err error) {
+	const op = "repository.pg.SaveTenant"
+
+	sql, args, err := r.qb.
+		Insert(TableTenants).
+		Columns(
+			"slug",
+			"name",
+		).
+		Values(
+			slug,
+			name,
+		).
+		Suffix("ON CONFLICT DO NOTHING").
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&tenantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, repository.ErrTenantExists
+		}
+		return uuid.Nil, pgerr.ErrScan(op, err)
+	}
+
+	return tenantID, nil
+}
+
+// Tenant returns tenant by id from the database.
+//
+// If the tenant is not found, it returns repository.ErrTenantNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) Tenant(ctx context.Context, tenantID uuid.UUID) (*entity.Tenant, error) {
+	const op = "repository.pg.Tenant"
+
+	return r.tenant(ctx, op, sq.Eq{"id": tenantID})
+}
+
+// TenantBySlug returns tenant by slug from the database.
+//
+// If the tenant is not found, it returns repository.ErrTenantNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) TenantBySlug(ctx context.Context, slug string) (*entity.Tenant, error) {
+	const op = "repository.pg.TenantBySlug"
+
+	return r.tenant(ctx, op, sq.Eq{"slug": slug})
+}
+
+// Tenants returns all tenants ordered by creation time.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) Tenants(ctx context.Context) ([]*entity.Tenant, error) {
+	const op = "repository.pg.Tenants"
+
+	sql, args, err := r.tenantSelect().
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var tenants []*entity.Tenant
+	for rows.Next() {
+		tenant := new(entity.Tenant)
+		if err := scanTenant(rows, tenant); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		tenants = append(tenants, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return tenants, nil
+}
+
+// UpdateTenant updates name and disabled flag of the tenant.
+//
+// If the tenant is not found, returns repository.ErrTenantNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) UpdateTenant(ctx context.Context, tenantID uuid.UUID, name string, disabled bool) error {
+	const op = "repository.pg.UpdateTenant"
+
+	sql, args, err := r.qb.
+		Update(TableTenants).
+		Set("name", name).
+		Set("disabled", disabled).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": tenantID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrTenantNotFound
+	}
+
+	return nil
+}
+
+// DeleteTenant deletes tenant with all its users.
+//
+// If the tenant is not found, returns repository.ErrTenantNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) DeleteTenant(ctx context.Context, tenantID uuid.UUID) error {
+	const op = "repository.pg.DeleteTenant"
+
+	sql, args, err := r.qb.
+		Delete(TableTenants).
+		Where(sq.Eq{"id": tenantID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrTenantNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) tenant(ctx context.Context, op string, where sq.Sqlizer) (*entity.Tenant, error) {
+	sql, args, err := r.tenantSelect().
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	tenant := new(entity.Tenant)
+	err = scanTenant(r.pool.QueryRow(ctx, sql, args...), tenant)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrTenantNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return tenant, nil
+}
+
+func (r *Repository) tenantSelect() sq.SelectBuilder {
+	return r.qb.
+		Select(
+			"id",
+			"slug",
+			"name",
+			"disabled",
+			"created_at",
+			"updated_at",
+		).
+		From(TableTenants)
+}
+
+func scanTenant(row pgx.Row, tenant *entity.Tenant) error {
+	return row.Scan(
+		&tenant.ID,
+		&tenant.Slug,
+		&tenant.Name,
+		&tenant.Disabled,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+}
@@ -0,0 +1,397 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/kurochkinivan/auth/pkg/pgerr"
+)
+
+// SaveWebhook saves webhook of the context tenant in the database and returns its id.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveWebhook(ctx context.Context, webhook *entity.Webhook) (webhookID uuid.UUID, err error) {
+	const op = "repository.pg.SaveWebhook"
+
+	sql, args, err := r.qb.
+		Insert(TableWebhooks).
+		Columns(
+			"tenant_id",
+			"url",
+			"event_types",
+			"secret",
+		).
+		Values(
+			tenancy.FromContext(ctx),
+			webhook.URL,
+			webhook.EventTypes,
+			webhook.Secret,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return uuid.Nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&webhookID)
+	if err != nil {
+		return uuid.Nil, pgerr.ErrScan(op, err)
+	}
+
+	return webhookID, nil
+}
+
+// Webhook returns webhook by id from the database.
+//
+// If the webhook is not found, it returns repository.ErrWebhookNotFound.
+// If an error occurs during query execution, it returns an error.
+func (r *Repository) Webhook(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error) {
+	const op = "repository.pg.Webhook"
+
+	sql, args, err := r.webhookSelect().
+		Where(sq.Eq{"id": webhookID}).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	webhook := new(entity.Webhook)
+	err = scanWebhook(r.pool.QueryRow(ctx, sql, args...), webhook)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrWebhookNotFound
+		}
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return webhook, nil
+}
+
+// Webhooks returns webhooks of the context tenant.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) Webhooks(ctx context.Context) ([]*entity.Webhook, error) {
+	const op = "repository.pg.Webhooks"
+
+	return r.webhooks(ctx, op, sq.Eq{"tenant_id": tenancy.FromContext(ctx)})
+}
+
+// SubscribedWebhooks returns webhooks of the tenant subscribed to the event type.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SubscribedWebhooks(ctx context.Context, tenantID uuid.UUID, eventType string) ([]*entity.Webhook, error) {
+	const op = "repository.pg.SubscribedWebhooks"
+
+	return r.webhooks(ctx, op, sq.And{
+		sq.Eq{"tenant_id": tenantID},
+		sq.Expr("? = ANY(event_types)", eventType),
+	})
+}
+
+func (r *Repository) webhooks(ctx context.Context, op string, where sq.Sqlizer) ([]*entity.Webhook, error) {
+	sql, args, err := r.webhookSelect().
+		Where(where).
+		OrderBy("created_at").
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []*entity.Webhook
+	for rows.Next() {
+		webhook := new(entity.Webhook)
+		if err := scanWebhook(rows, webhook); err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes webhook of the context tenant along with its deliveries.
+//
+// If the webhook is not found, returns repository.ErrWebhookNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	const op = "repository.pg.DeleteWebhook"
+
+	sql, args, err := r.qb.
+		Delete(TableWebhooks).
+		Where(sq.Eq{
+			"id":        webhookID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) webhookSelect() sq.SelectBuilder {
+	return r.qb.
+		Select(
+			"id",
+			"tenant_id",
+			"url",
+			"event_types",
+			"secret",
+			"created_at",
+		).
+		From(TableWebhooks)
+}
+
+func scanWebhook(row pgx.Row, webhook *entity.Webhook) error {
+	return row.Scan(
+		&webhook.ID,
+		&webhook.TenantID,
+		&webhook.URL,
+		&webhook.EventTypes,
+		&webhook.Secret,
+		&webhook.CreatedAt,
+	)
+}
+
+// SaveWebhookDeliveries saves pending deliveries of an event.
+// Deliveries of an event already saved for the webhook are skipped,
+// so an event published again is not delivered twice.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) SaveWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	const op = "repository.pg.SaveWebhookDeliveries"
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := r.qb.
+		Insert(TableWebhookDeliveries).
+		Columns(
+			"webhook_id",
+			"tenant_id",
+			"event_id",
+			"event_type",
+			"payload",
+		).
+		Suffix("ON CONFLICT (webhook_id, event_id) DO NOTHING")
+
+	for _, d := range deliveries {
+		query = query.Values(
+			d.WebhookID,
+			d.TenantID,
+			d.EventID,
+			d.EventType,
+			d.Payload,
+		)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// ClaimWebhookDeliveries returns at most limit pending deliveries due for an attempt
+// and counts the attempt. Claimed deliveries are postponed by lease, so concurrent
+// dispatchers don't send them, and are retried after the lease if the dispatcher crashes.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*entity.WebhookDelivery, error) {
+	const op = "repository.pg.ClaimWebhookDeliveries"
+
+	// the subquery keeps question placeholders, the outer query numbers them all
+	due := sq.
+		Select("id").
+		From(TableWebhookDeliveries).
+		Where(sq.Eq{"status": entity.DeliveryPending}).
+		Where(sq.LtOrEq{"next_attempt_at": time.Now()}).
+		OrderBy("next_attempt_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	sql, args, err := r.qb.
+		Update(TableWebhookDeliveries).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("next_attempt_at", time.Now().Add(lease)).
+		Where(sq.Expr("id IN (?)", due)).
+		Suffix("RETURNING " + deliveryColumns).
+		ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	return r.deliveries(ctx, op, sql, args)
+}
+
+// CompleteWebhookDelivery saves outcome of the last attempt of the delivery:
+// its status, when to attempt again if it is pending, and response of the receiver.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) CompleteWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	const op = "repository.pg.CompleteWebhookDelivery"
+
+	sql, args, err := r.qb.
+		Update(TableWebhookDeliveries).
+		Set("status", delivery.Status).
+		Set("next_attempt_at", delivery.NextAttemptAt).
+		Set("last_status_code", nullInt(delivery.LastStatusCode)).
+		Set("last_error", nullString(delivery.LastError)).
+		Set("delivered_at", delivery.DeliveredAt).
+		Where(sq.Eq{"id": delivery.ID}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	return nil
+}
+
+// WebhookDeliveries returns at most limit deliveries of the context tenant matching the filter, newest first.
+//
+// If an error occurs during query execution, returns an error.
+func (r *Repository) WebhookDeliveries(ctx context.Context, filter entity.DeliveryFilter, limit int) ([]*entity.WebhookDelivery, error) {
+	const op = "repository.pg.WebhookDeliveries"
+
+	query := r.qb.
+		Select(deliveryColumns).
+		From(TableWebhookDeliveries).
+		Where(sq.Eq{"tenant_id": tenancy.FromContext(ctx)}).
+		OrderBy("created_at DESC", "id").
+		Limit(uint64(limit))
+
+	if filter.WebhookID != uuid.Nil {
+		query = query.Where(sq.Eq{"webhook_id": filter.WebhookID})
+	}
+	if filter.Status != "" {
+		query = query.Where(sq.Eq{"status": filter.Status})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, pgerr.ErrCreateQuery(op, err)
+	}
+
+	return r.deliveries(ctx, op, sql, args)
+}
+
+// ReplayWebhookDelivery makes delivery of the context tenant pending again with a fresh attempt budget.
+//
+// If the delivery is not found, returns repository.ErrDeliveryNotFound.
+// If an error occurs during query execution, returns an error.
+func (r *Repository) ReplayWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error {
+	const op = "repository.pg.ReplayWebhookDelivery"
+
+	sql, args, err := r.qb.
+		Update(TableWebhookDeliveries).
+		Set("status", entity.DeliveryPending).
+		Set("attempts", 0).
+		Set("next_attempt_at", sq.Expr("now()")).
+		Set("delivered_at", nil).
+		Where(sq.Eq{
+			"id":        deliveryID,
+			"tenant_id": tenancy.FromContext(ctx),
+		}).
+		ToSql()
+	if err != nil {
+		return pgerr.ErrCreateQuery(op, err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return pgerr.ErrExec(op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+const deliveryColumns = "id, webhook_id, tenant_id, event_id, event_type, payload, status, attempts, " +
+	"next_attempt_at, COALESCE(last_status_code, 0), COALESCE(last_error, ''), delivered_at, created_at"
+
+func (r *Repository) deliveries(ctx context.Context, op, sql string, args []any) ([]*entity.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pgerr.ErrExec(op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		d := new(entity.WebhookDelivery)
+		err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.TenantID,
+			&d.EventID,
+			&d.EventType,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastStatusCode,
+			&d.LastError,
+			&d.DeliveredAt,
+			&d.CreatedAt,
+		)
+		if err != nil {
+			return nil, pgerr.ErrScan(op, err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pgerr.ErrScan(op, err)
+	}
+
+	return deliveries, nil
+}
+
+// nullInt stores zero as NULL.
+func nullInt(n int) *int {
+	if n == 0 {
+		return nil
+	}
+
+	return &n
+}
@@ -6,4 +6,25 @@ var (
 	ErrUserExists   = errors.New("user already exists")
 	ErrUserNotFound = errors.New("user not found")
 	ErrAppNotFound  = errors.New("app not found")
+	ErrOTPNotFound  = errors.New("otp not found")
+
+	ErrClientNotFound            = errors.New("client not found")
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found")
+	ErrRefreshTokenNotFound      = errors.New("refresh token not found")
+
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrIdentityExists   = errors.New("identity already exists")
+
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant already exists")
+
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrAssertionUsed          = errors.New("assertion already used")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
 )
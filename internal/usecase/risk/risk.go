@@ -0,0 +1,284 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/device"
+	"github.com/kurochkinivan/auth/internal/lib/geoip"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+)
+
+// Weights of signals in the score.
+const (
+	ScoreFailedAttempts     = 30
+	ScoreManyFailedAttempts = 60
+	ScoreNewDevice          = 20
+	ScoreNewNetwork         = 10
+	ScoreASNChange          = 25
+	ScoreImpossibleTravel   = 70
+)
+
+const (
+	// FailedAttemptsThreshold and ManyFailedAttemptsThreshold are numbers of failed
+	// sign-ins during the velocity window scored with ScoreFailedAttempts and ScoreManyFailedAttempts.
+	FailedAttemptsThreshold     = 3
+	ManyFailedAttemptsThreshold = 10
+
+	// minTravelKm ignores inaccuracy of city-level locations, neighbouring cities are never impossible travel.
+	minTravelKm = 100
+	// minTravelTime keeps speed of sign-ins following each other immediately finite.
+	minTravelTime = time.Minute
+
+	earthRadiusKm = 6371
+)
+
+type Engine struct {
+	log      *slog.Logger
+	storage  Storage
+	geo      Geo
+	auditLog AuditLog
+	policy   Policy
+}
+
+type Storage interface {
+	CountAuditEvents(ctx context.Context, filter entity.AuditFilter) (int, error)
+	LoginSeen(ctx context.Context, userID uuid.UUID, fingerprint, network string) (seen, deviceSeen, networkSeen bool, err error)
+	Logins(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.Login, error)
+}
+
+// Geo locates IP addresses, e.g. geoip.DB.
+type Geo interface {
+	Lookup(ip string) (*geoip.Location, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, event *entity.AuditEvent) error
+}
+
+// Policy turns score of a sign-in into a decision.
+type Policy struct {
+	// VelocityWindow is how far back failed sign-ins are counted.
+	VelocityWindow time.Duration
+	// MaxTravelSpeed in km/h, faster travel between consecutive sign-ins is impossible.
+	MaxTravelSpeed float64
+	// MFAScore and DenyScore are the lowest scores requiring MFA and denying the sign-in.
+	MFAScore  int
+	DenyScore int
+}
+
+// Decide returns decision on the score.
+func (p Policy) Decide(score int) string {
+	switch {
+	case score >= p.DenyScore:
+		return entity.RiskDeny
+	case score >= p.MFAScore:
+		return entity.RiskMFA
+	default:
+		return entity.RiskAllow
+	}
+}
+
+// New returns new instance of risk Engine.
+// Geo is optional, if nil, ASN change and impossible travel are not detected.
+func New(log *slog.Logger, storage Storage, geo Geo, auditLog AuditLog, policy Policy) *Engine {
+	return &Engine{
+		log:      log,
+		storage:  storage,
+		geo:      geo,
+		auditLog: auditLog,
+		policy:   policy,
+	}
+}
+
+// Assess scores sign-in of the user from the client in the context and decides on it.
+// The decision and its signals are recorded in the audit log.
+//
+// Must be called before the sign-in is added to login history.
+func (e *Engine) Assess(ctx context.Context, user *entity.User) (*entity.RiskAssessment, error) {
+	const op = "risk.Assess"
+	log := e.log.With(
+		slog.String("op", op),
+		slog.String("user_id", user.ID.String()),
+	)
+
+	signals, err := e.signals(ctx, log, user.ID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	score := Score(signals)
+	assessment := &entity.RiskAssessment{
+		Signals:  *signals,
+		Score:    score,
+		Decision: e.policy.Decide(score),
+	}
+
+	log.Info("sign-in assessed",
+		slog.Int("score", score),
+		slog.String("decision", assessment.Decision),
+	)
+
+	e.record(ctx, log, user, assessment)
+
+	return assessment, nil
+}
+
+// Score returns sum of weights of the signals.
+func Score(signals *entity.RiskSignals) int {
+	var score int
+
+	switch {
+	case signals.FailedAttempts >= ManyFailedAttemptsThreshold:
+		score += ScoreManyFailedAttempts
+	case signals.FailedAttempts >= FailedAttemptsThreshold:
+		score += ScoreFailedAttempts
+	}
+
+	if signals.NewDevice {
+		score += ScoreNewDevice
+	}
+	if signals.NewNetwork {
+		score += ScoreNewNetwork
+	}
+	if signals.ASNChanged {
+		score += ScoreASNChange
+	}
+	if signals.ImpossibleTravel {
+		score += ScoreImpossibleTravel
+	}
+
+	return score
+}
+
+func (e *Engine) signals(ctx context.Context, log *slog.Logger, userID uuid.UUID, now time.Time) (*entity.RiskSignals, error) {
+	client := clientinfo.FromContext(ctx)
+	signals := new(entity.RiskSignals)
+
+	failed, err := e.storage.CountAuditEvents(ctx, entity.AuditFilter{
+		UserID:  userID,
+		Type:    entity.AuditLogin,
+		Outcome: entity.OutcomeFailure,
+		Since:   now.Add(-e.policy.VelocityWindow),
+	})
+	if err != nil {
+		log.Error("failed to count failed sign-ins", sl.Err(err))
+
+		return nil, err
+	}
+	signals.FailedAttempts = failed
+
+	seen, deviceSeen, networkSeen, err := e.storage.LoginSeen(ctx, userID, device.Fingerprint(client.UserAgent), device.Network(client.IP))
+	if err != nil {
+		log.Error("failed to check login history", sl.Err(err))
+
+		return nil, err
+	}
+
+	// the very first sign-in has nothing to compare with
+	if !seen {
+		signals.FirstLogin = true
+
+		return signals, nil
+	}
+
+	signals.NewDevice = !deviceSeen
+	signals.NewNetwork = !networkSeen
+
+	if e.geo == nil {
+		return signals, nil
+	}
+
+	logins, err := e.storage.Logins(ctx, userID, 1)
+	if err != nil {
+		log.Error("failed to get previous sign-in", sl.Err(err))
+
+		return nil, err
+	}
+
+	current, err := e.geo.Lookup(client.IP)
+	if err != nil {
+		// unknown addresses are scored by device and network only
+		log.Warn("failed to locate client", sl.Err(err))
+
+		return signals, nil
+	}
+	signals.Country = current.Country
+	signals.ASN = current.ASN
+
+	if len(logins) == 0 || logins[0].IP == client.IP {
+		return signals, nil
+	}
+	previousLogin := logins[0]
+
+	previous, err := e.geo.Lookup(previousLogin.IP)
+	if err != nil {
+		log.Warn("failed to locate previous sign-in", sl.Err(err))
+
+		return signals, nil
+	}
+
+	signals.ASNChanged = current.ASN != 0 && previous.ASN != 0 && current.ASN != previous.ASN
+
+	if current.HasCoordinates() && previous.HasCoordinates() {
+		signals.TravelKm = Distance(previous, current)
+
+		elapsed := max(now.Sub(previousLogin.CreatedAt), minTravelTime)
+		signals.TravelSpeedKmh = signals.TravelKm / elapsed.Hours()
+
+		signals.ImpossibleTravel = signals.TravelKm > minTravelKm && signals.TravelSpeedKmh > e.policy.MaxTravelSpeed
+	}
+
+	return signals, nil
+}
+
+// Distance returns great-circle distance between the locations in kilometers.
+func Distance(from, to *geoip.Location) float64 {
+	lat1 := from.Latitude * math.Pi / 180
+	lat2 := to.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+func (e *Engine) record(ctx context.Context, log *slog.Logger, user *entity.User, assessment *entity.RiskAssessment) {
+	outcome := entity.OutcomeSuccess
+	if assessment.Decision != entity.RiskAllow {
+		outcome = entity.OutcomeFailure
+	}
+
+	signals := assessment.Signals
+	err := e.auditLog.Record(ctx, &entity.AuditEvent{
+		TenantID: user.TenantID,
+		Type:     entity.AuditRiskDecision,
+		UserID:   user.ID,
+		Outcome:  outcome,
+		Details: map[string]string{
+			"decision":          assessment.Decision,
+			"score":             strconv.Itoa(assessment.Score),
+			"failed_attempts":   strconv.Itoa(signals.FailedAttempts),
+			"first_login":       strconv.FormatBool(signals.FirstLogin),
+			"new_device":        strconv.FormatBool(signals.NewDevice),
+			"new_network":       strconv.FormatBool(signals.NewNetwork),
+			"asn_changed":       strconv.FormatBool(signals.ASNChanged),
+			"impossible_travel": strconv.FormatBool(signals.ImpossibleTravel),
+			"country":           signals.Country,
+			"asn":               strconv.FormatUint(uint64(signals.ASN), 10),
+			"travel_km":         strconv.FormatFloat(signals.TravelKm, 'f', 0, 64),
+			"travel_speed_kmh":  strconv.FormatFloat(signals.TravelSpeedKmh, 'f', 0, 64),
+		},
+	})
+	if err != nil {
+		log.Error("failed to record risk decision", sl.Err(err))
+	}
+}
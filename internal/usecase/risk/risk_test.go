@@ -0,0 +1,226 @@
+package risk
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/clientinfo"
+	"github.com/kurochkinivan/auth/internal/lib/device"
+	"github.com/kurochkinivan/auth/internal/lib/geoip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	failures int
+	logins   []*entity.Login
+	events   []*entity.AuditEvent
+}
+
+func (m *memoryStorage) CountAuditEvents(context.Context, entity.AuditFilter) (int, error) {
+	return m.failures, nil
+}
+
+func (m *memoryStorage) LoginSeen(_ context.Context, _ uuid.UUID, fingerprint, network string) (bool, bool, bool, error) {
+	var deviceSeen, networkSeen bool
+	for _, login := range m.logins {
+		deviceSeen = deviceSeen || login.DeviceFingerprint == fingerprint
+		networkSeen = networkSeen || login.Network == network
+	}
+	return len(m.logins) > 0, deviceSeen, networkSeen, nil
+}
+
+func (m *memoryStorage) Logins(context.Context, uuid.UUID, int) ([]*entity.Login, error) {
+	return m.logins[len(m.logins)-1:], nil
+}
+
+func (m *memoryStorage) Record(_ context.Context, event *entity.AuditEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memoryStorage) login(ip, userAgent string, at time.Time) {
+	m.logins = append(m.logins, &entity.Login{
+		IP:                ip,
+		Network:           device.Network(ip),
+		DeviceFingerprint: device.Fingerprint(userAgent),
+		CreatedAt:         at,
+	})
+}
+
+type mapGeo map[string]*geoip.Location
+
+func (g mapGeo) Lookup(ip string) (*geoip.Location, error) {
+	if location, ok := g[ip]; ok {
+		return location, nil
+	}
+	return nil, geoip.ErrInvalidIP
+}
+
+var (
+	berlin = &geoip.Location{Country: "DE", Latitude: 52.52, Longitude: 13.405, ASN: 3320}
+	// potsdam is in the same country and network operator as berlin
+	potsdam = &geoip.Location{Country: "DE", Latitude: 52.39, Longitude: 13.064, ASN: 3320}
+	tokyo   = &geoip.Location{Country: "JP", Latitude: 35.68, Longitude: 139.69, ASN: 2516}
+
+	geo = mapGeo{
+		"192.0.2.1":    berlin,
+		"192.0.2.77":   berlin,
+		"198.51.100.1": potsdam,
+		"203.0.113.1":  tokyo,
+	}
+)
+
+var policy = Policy{
+	VelocityWindow: 15 * time.Minute,
+	MaxTravelSpeed: 1000,
+	MFAScore:       40,
+	DenyScore:      80,
+}
+
+func TestAssess(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		prepare  func(*memoryStorage)
+		client   clientinfo.Info
+		signals  entity.RiskSignals
+		decision string
+	}{
+		{
+			name:     "first login",
+			prepare:  func(*memoryStorage) {},
+			client:   clientinfo.Info{IP: "192.0.2.1", UserAgent: "laptop"},
+			signals:  entity.RiskSignals{FirstLogin: true},
+			decision: entity.RiskAllow,
+		},
+		{
+			name: "known device and network",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-time.Hour))
+			},
+			client:   clientinfo.Info{IP: "192.0.2.77", UserAgent: "laptop"},
+			signals:  entity.RiskSignals{Country: "DE", ASN: 3320},
+			decision: entity.RiskAllow,
+		},
+		{
+			name: "new device nearby",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-time.Hour))
+			},
+			client:   clientinfo.Info{IP: "198.51.100.1", UserAgent: "phone"},
+			signals:  entity.RiskSignals{NewDevice: true, NewNetwork: true, Country: "DE", ASN: 3320},
+			decision: entity.RiskAllow,
+		},
+		{
+			name: "failed attempts from new device",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-time.Hour))
+				m.failures = 4
+			},
+			client:   clientinfo.Info{IP: "192.0.2.1", UserAgent: "phone"},
+			signals:  entity.RiskSignals{FailedAttempts: 4, NewDevice: true, Country: "DE", ASN: 3320},
+			decision: entity.RiskMFA,
+		},
+		{
+			name: "brute force",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-time.Hour))
+				m.failures = 25
+			},
+			client:   clientinfo.Info{IP: "192.0.2.1", UserAgent: "phone"},
+			signals:  entity.RiskSignals{FailedAttempts: 25, NewDevice: true, Country: "DE", ASN: 3320},
+			decision: entity.RiskDeny,
+		},
+		{
+			name: "impossible travel",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-time.Hour))
+			},
+			client: clientinfo.Info{IP: "203.0.113.1", UserAgent: "laptop"},
+			signals: entity.RiskSignals{
+				NewNetwork:       true,
+				ASNChanged:       true,
+				ImpossibleTravel: true,
+				Country:          "JP",
+				ASN:              2516,
+			},
+			decision: entity.RiskDeny,
+		},
+		{
+			name: "long flight",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-14*time.Hour))
+			},
+			client:   clientinfo.Info{IP: "203.0.113.1", UserAgent: "laptop"},
+			signals:  entity.RiskSignals{NewNetwork: true, ASNChanged: true, Country: "JP", ASN: 2516},
+			decision: entity.RiskAllow,
+		},
+		{
+			name: "unknown address",
+			prepare: func(m *memoryStorage) {
+				m.login("192.0.2.1", "laptop", now.Add(-time.Minute))
+			},
+			client:   clientinfo.Info{IP: "233.252.0.1", UserAgent: "laptop"},
+			signals:  entity.RiskSignals{NewNetwork: true},
+			decision: entity.RiskAllow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(memoryStorage)
+			tt.prepare(storage)
+
+			engine := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, geo, storage, policy)
+			user := &entity.User{ID: uuid.New(), TenantID: uuid.New()}
+
+			assessment, err := engine.Assess(clientinfo.WithInfo(context.Background(), tt.client), user)
+			require.NoError(t, err)
+
+			signals := assessment.Signals
+			signals.TravelKm, signals.TravelSpeedKmh = 0, 0
+			assert.Equal(t, tt.signals, signals)
+			assert.Equal(t, tt.decision, assessment.Decision, "score %d", assessment.Score)
+
+			require.Len(t, storage.events, 1)
+			event := storage.events[0]
+			assert.Equal(t, entity.AuditRiskDecision, event.Type)
+			assert.Equal(t, user.ID, event.UserID)
+			assert.Equal(t, user.TenantID, event.TenantID)
+			assert.Equal(t, tt.decision, event.Details["decision"])
+		})
+	}
+}
+
+func TestAssess_WithoutGeo(t *testing.T) {
+	storage := new(memoryStorage)
+	storage.login("192.0.2.1", "laptop", time.Now().Add(-time.Minute))
+
+	engine := New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, nil, storage, policy)
+	ctx := clientinfo.WithInfo(context.Background(), clientinfo.Info{IP: "203.0.113.1", UserAgent: "phone"})
+
+	assessment, err := engine.Assess(ctx, &entity.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.RiskSignals{NewDevice: true, NewNetwork: true}, assessment.Signals)
+	assert.Equal(t, ScoreNewDevice+ScoreNewNetwork, assessment.Score)
+}
+
+func TestDistance(t *testing.T) {
+	assert.InDelta(t, 8920, Distance(berlin, tokyo), 30)
+	assert.InDelta(t, 0, Distance(berlin, berlin), 0.001)
+}
+
+func TestPolicy_Decide(t *testing.T) {
+	assert.Equal(t, entity.RiskAllow, policy.Decide(39))
+	assert.Equal(t, entity.RiskMFA, policy.Decide(40))
+	assert.Equal(t, entity.RiskMFA, policy.Decide(79))
+	assert.Equal(t, entity.RiskDeny, policy.Decide(80))
+}
@@ -0,0 +1,308 @@
+package serviceaccount
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrInvalidClient          = errors.New("invalid client")
+	ErrInvalidScope           = errors.New("invalid scope")
+	ErrInvalidPublicKey       = errors.New("invalid public key")
+	ErrServiceAccountNotFound = errors.New("service account not found")
+)
+
+// assertionLifetime limits how far in the future JWT assertions may expire.
+const assertionLifetime = 5 * time.Minute
+
+type ServiceAccounts struct {
+	log      *slog.Logger
+	secret   string
+	issuer   string
+	storage  Storage
+	tokenTTL time.Duration
+}
+
+type Storage interface {
+	SaveServiceAccount(ctx context.Context, account *entity.ServiceAccount) (accountID uuid.UUID, err error)
+	ServiceAccount(ctx context.Context, accountID uuid.UUID) (*entity.ServiceAccount, error)
+	ServiceAccounts(ctx context.Context) ([]*entity.ServiceAccount, error)
+	UpdateServiceAccountSecret(ctx context.Context, accountID uuid.UUID, secretHash []byte) error
+	DeleteServiceAccount(ctx context.Context, accountID uuid.UUID) error
+	SaveAssertionID(ctx context.Context, accountID uuid.UUID, jti string, expiresAt time.Time) error
+}
+
+// New returns new instance of ServiceAccounts service.
+// Issuer is the audience JWT assertions must be addressed to.
+func New(log *slog.Logger, storage Storage, secret, issuer string, tokenTTL time.Duration) *ServiceAccounts {
+	return &ServiceAccounts{
+		log:      log,
+		secret:   secret,
+		issuer:   issuer,
+		storage:  storage,
+		tokenTTL: tokenTTL,
+	}
+}
+
+// Create creates service account of the context tenant.
+//
+// If publicKey is empty, the account authenticates with a secret, which is returned
+// and can't be shown again. Otherwise it authenticates with JWT assertions signed
+// with the private key and no secret is issued.
+// If publicKey is not a PEM encoded RSA or ECDSA key, returns ErrInvalidPublicKey.
+func (s *ServiceAccounts) Create(ctx context.Context, name string, scopes []string, publicKey string) (*entity.ServiceAccount, string, error) {
+	const op = "serviceaccount.Create"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("name", name),
+	)
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	account := &entity.ServiceAccount{
+		Name:      name,
+		PublicKey: publicKey,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	var secret string
+	if publicKey != "" {
+		if _, err := jwk.ParsePublicKey([]byte(publicKey)); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPublicKey)
+		}
+	} else {
+		var err error
+		secret, account.SecretHash, err = newSecret()
+		if err != nil {
+			log.Error("failed to generate secret", sl.Err(err))
+
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	accountID, err := s.storage.SaveServiceAccount(ctx, account)
+	if err != nil {
+		log.Error("failed to save service account", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("service account created", slog.String("service_account_id", accountID.String()))
+
+	account, err = s.storage.ServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return account, secret, nil
+}
+
+// ServiceAccounts returns service accounts of the context tenant.
+func (s *ServiceAccounts) ServiceAccounts(ctx context.Context) ([]*entity.ServiceAccount, error) {
+	const op = "serviceaccount.ServiceAccounts"
+
+	accounts, err := s.storage.ServiceAccounts(ctx)
+	if err != nil {
+		s.log.Error("failed to get service accounts", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return accounts, nil
+}
+
+// RotateSecret replaces secret of the service account of the context tenant and returns the new one.
+// The old secret stops working immediately, tokens issued with it stay valid until they expire.
+//
+// If the service account is not found, returns ErrServiceAccountNotFound.
+func (s *ServiceAccounts) RotateSecret(ctx context.Context, accountID uuid.UUID) (string, error) {
+	const op = "serviceaccount.RotateSecret"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("service_account_id", accountID.String()),
+	)
+
+	secret, secretHash, err := newSecret()
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.storage.UpdateServiceAccountSecret(ctx, accountID, secretHash); err != nil {
+		if errors.Is(err, repository.ErrServiceAccountNotFound) {
+			return "", fmt.Errorf("%s: %w", op, ErrServiceAccountNotFound)
+		}
+
+		log.Error("failed to update secret", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("service account secret rotated")
+
+	return secret, nil
+}
+
+// Delete deletes service account of the context tenant.
+//
+// If the service account is not found, returns ErrServiceAccountNotFound.
+func (s *ServiceAccounts) Delete(ctx context.Context, accountID uuid.UUID) error {
+	const op = "serviceaccount.Delete"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("service_account_id", accountID.String()),
+	)
+
+	if err := s.storage.DeleteServiceAccount(ctx, accountID); err != nil {
+		if errors.Is(err, repository.ErrServiceAccountNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrServiceAccountNotFound)
+		}
+
+		log.Error("failed to delete service account", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("service account deleted")
+
+	return nil
+}
+
+// Token authenticates the service account of the context tenant with either the secret
+// or the JWT assertion and returns short-lived access token restricted to the requested scopes.
+// If no scopes are requested, the token carries all scopes of the account.
+// Every assertion is accepted once.
+//
+// If the client is unknown or the credentials are invalid, returns ErrInvalidClient.
+// If a requested scope is not allowed for the account, returns ErrInvalidScope.
+func (s *ServiceAccounts) Token(ctx context.Context, clientID, clientSecret, assertion string, scopes []string) (token string, expiresIn time.Duration, err error) {
+	const op = "serviceaccount.Token"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	account, err := s.authenticate(ctx, clientID, clientSecret, assertion)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClient) {
+			log.Warn("invalid service account credentials")
+		} else {
+			log.Error("failed to authenticate service account", sl.Err(err))
+		}
+
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(account.Scopes, scope) {
+			return "", 0, fmt.Errorf("%s: %w: %s", op, ErrInvalidScope, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		scopes = account.Scopes
+	}
+
+	var opts []jwt.Option
+	if len(scopes) > 0 {
+		opts = append(opts, jwt.WithScopes(scopes))
+	}
+
+	token, err = jwt.NewServiceAccountToken(account, s.secret, s.tokenTTL, opts...)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("service account token issued", slog.String("scope", strings.Join(scopes, " ")))
+
+	return token, s.tokenTTL, nil
+}
+
+func (s *ServiceAccounts) authenticate(ctx context.Context, clientID, clientSecret, assertion string) (*entity.ServiceAccount, error) {
+	accountID, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	account, err := s.storage.ServiceAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrServiceAccountNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	switch {
+	case assertion != "":
+		if account.PublicKey == "" {
+			return nil, ErrInvalidClient
+		}
+
+		key, err := jwk.ParsePublicKey([]byte(account.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+
+		jti, expiresAt, err := jwt.ParseAssertion(assertion, key, clientID, s.issuer, assertionLifetime)
+		if err != nil {
+			return nil, ErrInvalidClient
+		}
+
+		// the id is saved only once the signature is checked, so others can't burn ids of the account
+		if err := s.storage.SaveAssertionID(ctx, account.ID, jti, expiresAt); err != nil {
+			if errors.Is(err, repository.ErrAssertionUsed) {
+				return nil, ErrInvalidClient
+			}
+			return nil, err
+		}
+	case clientSecret != "":
+		if len(account.SecretHash) == 0 {
+			return nil, ErrInvalidClient
+		}
+
+		if err := passhash.Compare(account.SecretHash, []byte(clientSecret)); err != nil {
+			return nil, ErrInvalidClient
+		}
+	default:
+		return nil, ErrInvalidClient
+	}
+
+	return account, nil
+}
+
+// newSecret returns random secret and its bcrypt hash.
+func newSecret() (secret string, secretHash []byte, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	secret = base64.RawURLEncoding.EncodeToString(b)
+
+	secretHash, err = passhash.Hash([]byte(secret))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, secretHash, nil
+}
@@ -0,0 +1,193 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant exists")
+	ErrTenantDisabled = errors.New("tenant is disabled")
+	ErrDefaultTenant  = errors.New("default tenant can't be deleted or disabled")
+)
+
+type Tenants struct {
+	log           *slog.Logger
+	tenantStorage TenantStorage
+}
+
+type TenantStorage interface {
+	SaveTenant(ctx context.Context, slug, name string) (tenantID uuid.UUID, err error)
+	Tenant(ctx context.Context, tenantID uuid.UUID) (*entity.Tenant, error)
+	TenantBySlug(ctx context.Context, slug string) (*entity.Tenant, error)
+	Tenants(ctx context.Context) ([]*entity.Tenant, error)
+	UpdateTenant(ctx context.Context, tenantID uuid.UUID, name string, disabled bool) error
+	DeleteTenant(ctx context.Context, tenantID uuid.UUID) error
+}
+
+// New returns new instance of Tenants service
+func New(log *slog.Logger, tenantStorage TenantStorage) *Tenants {
+	return &Tenants{
+		log:           log,
+		tenantStorage: tenantStorage,
+	}
+}
+
+// Resolve returns id of the active tenant with given slug.
+//
+// If the tenant doesn't exist, returns ErrTenantNotFound.
+// If the tenant is disabled, returns ErrTenantDisabled.
+func (t *Tenants) Resolve(ctx context.Context, slug string) (uuid.UUID, error) {
+	const op = "tenant.Resolve"
+
+	tenant, err := t.tenantStorage.TenantBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, repository.ErrTenantNotFound) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrTenantNotFound)
+		}
+
+		t.log.Error("failed to get tenant", slog.String("op", op), sl.Err(err))
+
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tenant.Disabled {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrTenantDisabled)
+	}
+
+	return tenant.ID, nil
+}
+
+// Create creates new tenant and returns it.
+//
+// If tenant with given slug already exists, returns ErrTenantExists.
+func (t *Tenants) Create(ctx context.Context, slug, name string) (*entity.Tenant, error) {
+	const op = "tenant.Create"
+	log := t.log.With(
+		slog.String("op", op),
+		slog.String("slug", slug),
+	)
+
+	log.Info("creating tenant")
+
+	tenantID, err := t.tenantStorage.SaveTenant(ctx, slug, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrTenantExists) {
+			log.Warn("tenant exists", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrTenantExists)
+		}
+
+		log.Error("failed to save tenant", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tenant created", slog.String("tenant_id", tenantID.String()))
+
+	return t.Tenant(ctx, tenantID)
+}
+
+// Tenant returns tenant by id.
+//
+// If the tenant doesn't exist, returns ErrTenantNotFound.
+func (t *Tenants) Tenant(ctx context.Context, tenantID uuid.UUID) (*entity.Tenant, error) {
+	const op = "tenant.Tenant"
+
+	tenant, err := t.tenantStorage.Tenant(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTenantNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTenantNotFound)
+		}
+
+		t.log.Error("failed to get tenant", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenant, nil
+}
+
+// Tenants returns all tenants.
+func (t *Tenants) Tenants(ctx context.Context) ([]*entity.Tenant, error) {
+	const op = "tenant.Tenants"
+
+	tenants, err := t.tenantStorage.Tenants(ctx)
+	if err != nil {
+		t.log.Error("failed to get tenants", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenants, nil
+}
+
+// Update changes name of the tenant and enables or disables it.
+// Users of disabled tenant can't log in or register.
+//
+// If the tenant doesn't exist, returns ErrTenantNotFound.
+// If the default tenant is being disabled, returns ErrDefaultTenant.
+func (t *Tenants) Update(ctx context.Context, tenantID uuid.UUID, name string, disabled bool) (*entity.Tenant, error) {
+	const op = "tenant.Update"
+	log := t.log.With(
+		slog.String("op", op),
+		slog.String("tenant_id", tenantID.String()),
+	)
+
+	if tenantID == tenancy.Default && disabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrDefaultTenant)
+	}
+
+	if err := t.tenantStorage.UpdateTenant(ctx, tenantID, name, disabled); err != nil {
+		if errors.Is(err, repository.ErrTenantNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTenantNotFound)
+		}
+
+		log.Error("failed to update tenant", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tenant updated", slog.Bool("disabled", disabled))
+
+	return t.Tenant(ctx, tenantID)
+}
+
+// Delete deletes the tenant with all its users.
+//
+// If the tenant doesn't exist, returns ErrTenantNotFound.
+// If the tenant is the default one, returns ErrDefaultTenant.
+func (t *Tenants) Delete(ctx context.Context, tenantID uuid.UUID) error {
+	const op = "tenant.Delete"
+	log := t.log.With(
+		slog.String("op", op),
+		slog.String("tenant_id", tenantID.String()),
+	)
+
+	if tenantID == tenancy.Default {
+		return fmt.Errorf("%s: %w", op, ErrDefaultTenant)
+	}
+
+	if err := t.tenantStorage.DeleteTenant(ctx, tenantID); err != nil {
+		if errors.Is(err, repository.ErrTenantNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrTenantNotFound)
+		}
+
+		log.Error("failed to delete tenant", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tenant deleted")
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+// maxErrorLength limits error of an attempt saved with the delivery.
+const maxErrorLength = 512
+
+// Dispatcher sends pending deliveries to webhooks.
+//
+// A delivery succeeds once the receiver responds with 2xx. Failed attempts are retried
+// with exponential backoff, after maxAttempts the delivery is dead until replayed.
+// Receivers must be idempotent: a delivery is sent again if its outcome is lost, e.g. on crash,
+// HeaderID identifies the delivery across attempts.
+type Dispatcher struct {
+	log            *slog.Logger
+	storage        Storage
+	client         *http.Client
+	batchSize      int
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	interval       time.Duration
+	lease          time.Duration
+}
+
+// NewDispatcher returns new instance of Dispatcher polling deliveries every interval.
+// Attempts are spaced by initialBackoff doubled after every failure, up to maxBackoff.
+func NewDispatcher(
+	log *slog.Logger,
+	storage Storage,
+	client *http.Client,
+	batchSize int,
+	maxAttempts int,
+	initialBackoff time.Duration,
+	maxBackoff time.Duration,
+	interval time.Duration,
+) *Dispatcher {
+	return &Dispatcher{
+		log:            log,
+		storage:        storage,
+		client:         client,
+		batchSize:      batchSize,
+		maxAttempts:    maxAttempts,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		interval:       interval,
+		// deliveries of a batch are sent concurrently, so the batch takes about one request
+		lease: max(2*client.Timeout, time.Minute),
+	}
+}
+
+// Run sends deliveries until the context is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	const op = "webhook.Dispatcher.Run"
+	log := d.log.With(slog.String("op", op))
+
+	log.Info("webhook dispatcher is running", slog.Duration("interval", d.interval))
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.Dispatch(ctx)
+			if err != nil {
+				log.Error("failed to dispatch webhook deliveries", sl.Err(err))
+				break
+			}
+			if n < d.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch sends one batch of due deliveries and returns its size.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	const op = "webhook.Dispatcher.Dispatch"
+
+	deliveries, err := d.storage.ClaimWebhookDeliveries(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhooks := make(map[uuid.UUID]*entity.Webhook)
+	var wg sync.WaitGroup
+
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = d.storage.Webhook(ctx, delivery.WebhookID)
+			if err != nil {
+				if !errors.Is(err, repository.ErrWebhookNotFound) {
+					d.log.Error("failed to get webhook", slog.String("op", op), sl.Err(err))
+				}
+				// deleted webhooks take their deliveries with them
+				continue
+			}
+
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, webhook, delivery)
+		}()
+	}
+
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver makes an attempt of the delivery and saves its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) {
+	const op = "webhook.Dispatcher.deliver"
+	log := d.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", webhook.ID.String()),
+		slog.String("delivery_id", delivery.ID.String()),
+		slog.Int("attempt", delivery.Attempts),
+	)
+
+	statusCode, err := d.send(ctx, webhook, delivery)
+
+	now := time.Now()
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = entity.DeliveryDelivered
+		delivery.DeliveredAt = &now
+
+		log.Info("webhook delivered")
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = entity.DeliveryDead
+		delivery.LastError = truncate(err.Error(), maxErrorLength)
+
+		log.Warn("webhook delivery is dead", sl.Err(err))
+	default:
+		delivery.Status = entity.DeliveryPending
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = truncate(err.Error(), maxErrorLength)
+
+		log.Warn("webhook delivery failed", slog.Time("next_attempt_at", delivery.NextAttemptAt), sl.Err(err))
+	}
+
+	// the outcome is saved even if the dispatcher is stopping, so the attempt is not repeated
+	if err := d.storage.CompleteWebhookDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Error("failed to save webhook delivery", sl.Err(err))
+	}
+}
+
+// send posts signed payload of the delivery to the webhook and returns status code of the response.
+func (d *Dispatcher) send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "auth-webhooks")
+	req.Header.Set(HeaderID, delivery.ID.String())
+	req.Header.Set(HeaderEvent, delivery.EventType)
+
+	now := time.Now()
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// let the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns delay before the attempt following the given one.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.initialBackoff
+	for range attempt - 1 {
+		delay *= 2
+		if delay >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+
+	return min(delay, d.maxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Headers of webhook requests.
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+const signatureVersion = "v1="
+
+// Sign returns signature of the payload sent at the timestamp:
+// "v1=" followed by hex encoded HMAC-SHA256 of "{unix timestamp}.{payload}" keyed by the secret.
+// Timestamp is signed along with the payload, so a captured request can't be replayed later.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks values of HeaderTimestamp and HeaderSignature of the request received at now.
+// Requests sent more than tolerance ago or in the future are rejected.
+//
+// If the signature doesn't match or the timestamp is out of tolerance, returns ErrInvalidSignature.
+func Verify(secret, timestamp, signature string, payload []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	sentAt := time.Unix(unix, 0)
+	if now.Sub(sentAt).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+
+	if !strings.HasPrefix(signature, signatureVersion) {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(Sign(secret, sentAt, payload)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	secret := "whsec_test"
+	payload := []byte(`{"type":"user.registered"}`)
+	sentAt := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+	signature := Sign(secret, sentAt, payload)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		payload   []byte
+		now       time.Time
+		valid     bool
+	}{
+		{"valid", secret, timestamp, signature, payload, sentAt.Add(time.Minute), true},
+		{"wrong secret", "whsec_other", timestamp, signature, payload, sentAt, false},
+		{"tampered payload", secret, timestamp, signature, []byte(`{"type":"user.deleted"}`), sentAt, false},
+		{"tampered timestamp", secret, strconv.FormatInt(sentAt.Unix()+1, 10), signature, payload, sentAt, false},
+		{"too old", secret, timestamp, signature, payload, sentAt.Add(10 * time.Minute), false},
+		{"from the future", secret, timestamp, signature, payload, sentAt.Add(-10 * time.Minute), false},
+		{"malformed timestamp", secret, "yesterday", signature, payload, sentAt, false},
+		{"unknown version", secret, timestamp, "v0=" + signature[len("v1="):], payload, sentAt, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.payload, 5*time.Minute, tt.now)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/sl"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrUnknownEventType = errors.New("unknown event type")
+	ErrUnknownStatus    = errors.New("unknown delivery status")
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// secretPrefix tells webhook secrets apart from other credentials.
+const secretPrefix = "whsec_"
+
+type Storage interface {
+	SaveWebhook(ctx context.Context, webhook *entity.Webhook) (webhookID uuid.UUID, err error)
+	Webhook(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error)
+	Webhooks(ctx context.Context) ([]*entity.Webhook, error)
+	SubscribedWebhooks(ctx context.Context, tenantID uuid.UUID, eventType string) ([]*entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	SaveWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*entity.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	WebhookDeliveries(ctx context.Context, filter entity.DeliveryFilter, limit int) ([]*entity.WebhookDelivery, error)
+	ReplayWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error
+}
+
+// Webhooks manages webhooks of tenants and delivers domain events to them, see Dispatcher.
+type Webhooks struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+// New returns new instance of Webhooks service.
+func New(log *slog.Logger, storage Storage) *Webhooks {
+	return &Webhooks{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Create registers webhook of the context tenant receiving events of the given types.
+// The returned webhook holds the secret its payloads are signed with.
+//
+// If the url is not an absolute http or https url, returns ErrInvalidURL.
+// If any of the event types is unknown, returns ErrUnknownEventType.
+func (w *Webhooks) Create(ctx context.Context, rawURL string, eventTypes []string) (*entity.Webhook, error) {
+	const op = "webhook.Create"
+	log := w.log.With(slog.String("op", op))
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidURL)
+	}
+
+	for _, eventType := range eventTypes {
+		if !slices.Contains(entity.EventTypes, eventType) {
+			return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownEventType, eventType)
+		}
+	}
+
+	eventTypes = slices.Clone(eventTypes)
+	slices.Sort(eventTypes)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhook := &entity.Webhook{
+		TenantID:   tenancy.FromContext(ctx),
+		URL:        rawURL,
+		EventTypes: slices.Compact(eventTypes),
+		Secret:     secretPrefix + base64.RawURLEncoding.EncodeToString(secret),
+		CreatedAt:  time.Now(),
+	}
+
+	webhook.ID, err = w.storage.SaveWebhook(ctx, webhook)
+	if err != nil {
+		log.Error("failed to save webhook", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook created", slog.String("webhook_id", webhook.ID.String()))
+
+	return webhook, nil
+}
+
+// Webhooks returns webhooks of the context tenant.
+func (w *Webhooks) Webhooks(ctx context.Context) ([]*entity.Webhook, error) {
+	const op = "webhook.Webhooks"
+
+	webhooks, err := w.storage.Webhooks(ctx)
+	if err != nil {
+		w.log.Error("failed to get webhooks", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// Delete deletes webhook of the context tenant, its pending deliveries are dropped.
+//
+// If the webhook is not found, returns ErrWebhookNotFound.
+func (w *Webhooks) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	const op = "webhook.Delete"
+	log := w.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", webhookID.String()),
+	)
+
+	if err := w.storage.DeleteWebhook(ctx, webhookID); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+
+		log.Error("failed to delete webhook", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook deleted")
+
+	return nil
+}
+
+// Deliveries returns latest deliveries of the context tenant matching the filter, newest first.
+// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+//
+// If the status filter is unknown, returns ErrUnknownStatus.
+func (w *Webhooks) Deliveries(ctx context.Context, filter entity.DeliveryFilter, pageSize int) ([]*entity.WebhookDelivery, error) {
+	const op = "webhook.Deliveries"
+
+	switch filter.Status {
+	case "", entity.DeliveryPending, entity.DeliveryDelivered, entity.DeliveryDead:
+	default:
+		return nil, fmt.Errorf("%s: %w", op, ErrUnknownStatus)
+	}
+
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	pageSize = min(pageSize, MaxPageSize)
+
+	deliveries, err := w.storage.WebhookDeliveries(ctx, filter, pageSize)
+	if err != nil {
+		w.log.Error("failed to get webhook deliveries", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// Replay sends the delivery of the context tenant again, e.g. once a dead receiver is fixed.
+//
+// If the delivery is not found, returns ErrDeliveryNotFound.
+func (w *Webhooks) Replay(ctx context.Context, deliveryID uuid.UUID) error {
+	const op = "webhook.Replay"
+	log := w.log.With(
+		slog.String("op", op),
+		slog.String("delivery_id", deliveryID.String()),
+	)
+
+	if err := w.storage.ReplayWebhookDelivery(ctx, deliveryID); err != nil {
+		if errors.Is(err, repository.ErrDeliveryNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrDeliveryNotFound)
+		}
+
+		log.Error("failed to replay webhook delivery", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook delivery replayed")
+
+	return nil
+}
+
+// Publish schedules delivery of the event to webhooks of its tenant subscribed to its type.
+// It is the outbox broker of webhooks, see outbox.Broker.
+func (w *Webhooks) Publish(ctx context.Context, event *entity.Event) error {
+	const op = "webhook.Publish"
+	log := w.log.With(
+		slog.String("op", op),
+		slog.Int64("event_id", event.ID),
+		slog.String("type", event.Type),
+	)
+
+	var payload struct {
+		TenantID uuid.UUID `json:"tenant_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		log.Error("failed to decode event payload", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhooks, err := w.storage.SubscribedWebhooks(ctx, payload.TenantID, event.Type)
+	if err != nil {
+		log.Error("failed to get subscribed webhooks", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries := make([]*entity.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &entity.WebhookDelivery{
+			WebhookID: webhook.ID,
+			TenantID:  webhook.TenantID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Payload:   event.Payload,
+		})
+	}
+
+	if err := w.storage.SaveWebhookDeliveries(ctx, deliveries); err != nil {
+		log.Error("failed to save webhook deliveries", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
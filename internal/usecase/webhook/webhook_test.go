@@ -0,0 +1,374 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage keeps webhooks and deliveries in memory, deliveries are due regardless of time.
+type memoryStorage struct {
+	mu         sync.Mutex
+	webhooks   []*entity.Webhook
+	deliveries []*entity.WebhookDelivery
+}
+
+func (m *memoryStorage) SaveWebhook(ctx context.Context, webhook *entity.Webhook) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := *webhook
+	w.ID = uuid.New()
+	w.TenantID = tenancy.FromContext(ctx)
+	m.webhooks = append(m.webhooks, &w)
+
+	return w.ID, nil
+}
+
+func (m *memoryStorage) Webhook(_ context.Context, webhookID uuid.UUID) (*entity.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.webhooks {
+		if w.ID == webhookID {
+			return w, nil
+		}
+	}
+
+	return nil, repository.ErrWebhookNotFound
+}
+
+func (m *memoryStorage) Webhooks(ctx context.Context) ([]*entity.Webhook, error) {
+	return m.SubscribedWebhooks(ctx, tenancy.FromContext(ctx), "")
+}
+
+func (m *memoryStorage) SubscribedWebhooks(_ context.Context, tenantID uuid.UUID, eventType string) ([]*entity.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var webhooks []*entity.Webhook
+	for _, w := range m.webhooks {
+		if w.TenantID != tenantID {
+			continue
+		}
+		for _, t := range w.EventTypes {
+			if eventType == "" || t == eventType {
+				webhooks = append(webhooks, w)
+				break
+			}
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (m *memoryStorage) DeleteWebhook(context.Context, uuid.UUID) error {
+	return nil
+}
+
+func (m *memoryStorage) SaveWebhookDeliveries(_ context.Context, deliveries []*entity.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+next:
+	for _, d := range deliveries {
+		for _, saved := range m.deliveries {
+			if saved.WebhookID == d.WebhookID && saved.EventID == d.EventID {
+				continue next
+			}
+		}
+
+		saved := *d
+		saved.ID = uuid.New()
+		saved.Status = entity.DeliveryPending
+		m.deliveries = append(m.deliveries, &saved)
+	}
+
+	return nil
+}
+
+func (m *memoryStorage) ClaimWebhookDeliveries(_ context.Context, limit int, _ time.Duration) ([]*entity.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var claimed []*entity.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == entity.DeliveryPending && len(claimed) < limit {
+			d.Attempts++
+			c := *d
+			claimed = append(claimed, &c)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (m *memoryStorage) CompleteWebhookDelivery(_ context.Context, delivery *entity.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, d := range m.deliveries {
+		if d.ID == delivery.ID {
+			c := *delivery
+			m.deliveries[i] = &c
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryStorage) WebhookDeliveries(context.Context, entity.DeliveryFilter, int) ([]*entity.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deliveries := make([]*entity.WebhookDelivery, 0, len(m.deliveries))
+	for _, d := range m.deliveries {
+		c := *d
+		deliveries = append(deliveries, &c)
+	}
+
+	return deliveries, nil
+}
+
+func (m *memoryStorage) ReplayWebhookDelivery(_ context.Context, deliveryID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.deliveries {
+		if d.ID == deliveryID {
+			d.Status = entity.DeliveryPending
+			d.Attempts = 0
+			return nil
+		}
+	}
+
+	return repository.ErrDeliveryNotFound
+}
+
+// receiver verifies requests and answers them with the given status codes in turn,
+// the last one is repeated.
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	payloads [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(r.Body)
+	require.NoError(rc.t, err)
+
+	err = Verify(rc.secret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), payload, time.Minute, time.Now())
+	assert.NoError(rc.t, err)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.requests = append(rc.requests, r)
+	rc.payloads = append(rc.payloads, payload)
+
+	code := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+
+	w.WriteHeader(code)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return len(rc.requests)
+}
+
+type fixture struct {
+	storage    *memoryStorage
+	webhooks   *Webhooks
+	dispatcher *Dispatcher
+	receiver   *receiver
+	webhook    *entity.Webhook
+	ctx        context.Context
+}
+
+func newFixture(t *testing.T, maxAttempts int, statuses ...int) *fixture {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	storage := new(memoryStorage)
+	webhooks := New(log, storage)
+
+	rc := &receiver{t: t, statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	ctx := tenancy.WithTenant(context.Background(), uuid.New())
+
+	webhook, err := webhooks.Create(ctx, server.URL+"/hooks", []string{entity.EventUserRegistered})
+	require.NoError(t, err)
+	rc.secret = webhook.Secret
+
+	dispatcher := NewDispatcher(log, storage, server.Client(), 10, maxAttempts, time.Second, 4*time.Second, time.Second)
+
+	return &fixture{
+		storage:    storage,
+		webhooks:   webhooks,
+		dispatcher: dispatcher,
+		receiver:   rc,
+		webhook:    webhook,
+		ctx:        ctx,
+	}
+}
+
+func (f *fixture) publish(t *testing.T, eventID int64, eventType string) {
+	t.Helper()
+
+	payload := `{"tenant_id":"` + tenancy.FromContext(f.ctx).String() + `","type":"` + eventType + `"}`
+	err := f.webhooks.Publish(context.Background(), &entity.Event{
+		ID:          eventID,
+		AggregateID: uuid.New(),
+		Type:        eventType,
+		Payload:     []byte(payload),
+	})
+	require.NoError(t, err)
+}
+
+func (f *fixture) delivery(t *testing.T) *entity.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := f.webhooks.Deliveries(f.ctx, entity.DeliveryFilter{}, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	return deliveries[0]
+}
+
+func TestDispatch_SignedDelivery(t *testing.T) {
+	f := newFixture(t, 3, http.StatusNoContent)
+
+	f.publish(t, 1, entity.EventUserRegistered)
+	// events published again by the outbox and events nobody subscribed to are not delivered
+	f.publish(t, 1, entity.EventUserRegistered)
+	f.publish(t, 2, entity.EventUserDeleted)
+
+	n, err := f.dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Equal(t, 1, f.receiver.count())
+	req := f.receiver.requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/hooks", req.URL.Path)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, entity.EventUserRegistered, req.Header.Get(HeaderEvent))
+	assert.JSONEq(t, `{"tenant_id":"`+f.webhook.TenantID.String()+`","type":"user.registered"}`, string(f.receiver.payloads[0]))
+
+	delivery := f.delivery(t)
+	assert.Equal(t, delivery.ID.String(), req.Header.Get(HeaderID))
+	assert.Equal(t, entity.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	n, err = f.dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestDispatch_RetriesWithBackoff(t *testing.T) {
+	f := newFixture(t, 5, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+
+	f.publish(t, 1, entity.EventUserRegistered)
+
+	backoffs := []time.Duration{time.Second, 2 * time.Second}
+	for i, backoff := range backoffs {
+		before := time.Now()
+
+		_, err := f.dispatcher.Dispatch(context.Background())
+		require.NoError(t, err)
+
+		delivery := f.delivery(t)
+		assert.Equal(t, entity.DeliveryPending, delivery.Status)
+		assert.Equal(t, i+1, delivery.Attempts)
+		assert.NotEmpty(t, delivery.LastError)
+		assert.WithinRange(t, delivery.NextAttemptAt, before.Add(backoff), time.Now().Add(backoff))
+	}
+
+	_, err := f.dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+
+	delivery := f.delivery(t)
+	assert.Equal(t, entity.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Empty(t, delivery.LastError)
+	assert.Equal(t, 3, f.receiver.count())
+}
+
+func TestDispatch_DeadLetterAndReplay(t *testing.T) {
+	f := newFixture(t, 2, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+
+	f.publish(t, 1, entity.EventUserRegistered)
+
+	for range 3 {
+		_, err := f.dispatcher.Dispatch(context.Background())
+		require.NoError(t, err)
+	}
+
+	delivery := f.delivery(t)
+	assert.Equal(t, entity.DeliveryDead, delivery.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+	assert.Equal(t, 2, f.receiver.count(), "dead deliveries are not attempted")
+
+	require.NoError(t, f.webhooks.Replay(f.ctx, delivery.ID))
+
+	_, err := f.dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+
+	delivery = f.delivery(t)
+	assert.Equal(t, entity.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, f.receiver.count())
+
+	assert.ErrorIs(t, f.webhooks.Replay(f.ctx, uuid.New()), ErrDeliveryNotFound)
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{initialBackoff: 10 * time.Second, maxBackoff: time.Minute}
+
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, want := range expected {
+		assert.Equal(t, want, d.backoff(i+1), "attempt %d", i+1)
+	}
+}
+
+func TestCreate_Validation(t *testing.T) {
+	webhooks := New(slog.New(slog.NewTextHandler(io.Discard, nil)), new(memoryStorage))
+	ctx := context.Background()
+
+	_, err := webhooks.Create(ctx, "ftp://example.com/hooks", []string{entity.EventUserRegistered})
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	_, err = webhooks.Create(ctx, "/hooks", []string{entity.EventUserRegistered})
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	_, err = webhooks.Create(ctx, "https://example.com/hooks", []string{"user.logged_in"})
+	assert.ErrorIs(t, err, ErrUnknownEventType)
+
+	w, err := webhooks.Create(ctx, "https://example.com/hooks", []string{entity.EventUserDeleted, entity.EventUserRegistered, entity.EventUserDeleted})
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.EventUserDeleted, entity.EventUserRegistered}, w.EventTypes)
+	assert.Regexp(t, `^whsec_[A-Za-z0-9_-]{43}$`, w.Secret)
+}
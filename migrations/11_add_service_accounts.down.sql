@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS service_account_assertions;
+DROP TABLE IF EXISTS service_accounts;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id UUID DEFAULT gen_random_uuid() NOT NULL,
+    tenant_id UUID NOT NULL REFERENCES tenants (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    secret_hash BYTEA,
+    public_key TEXT,
+    scopes TEXT[] DEFAULT '{}' NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_service_accounts_tenant_id ON service_accounts (tenant_id);
+
+-- ids of accepted JWT assertions, so an assertion can't be replayed before it expires
+CREATE TABLE IF NOT EXISTS service_account_assertions (
+    service_account_id UUID NOT NULL REFERENCES service_accounts (id) ON DELETE CASCADE,
+    jti TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (service_account_id, jti)
+);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    tenant_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    user_id UUID,
+    actor_id UUID,
+    outcome TEXT NOT NULL,
+    ip TEXT,
+    user_agent TEXT,
+    details JSONB DEFAULT '{}' NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id ON audit_log (tenant_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log (user_id, id DESC);
+
+-- events outlive users and tenants they refer to, so there are no foreign keys,
+-- and nobody can rewrite history
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS login_history (
+    id BIGINT GENERATED ALWAYS AS IDENTITY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    network TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    device_fingerprint TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_login_history_user_id ON login_history (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_login_history_device ON login_history (user_id, device_fingerprint);
+CREATE INDEX IF NOT EXISTS idx_login_history_network ON login_history (user_id, network);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS otp_codes;
+
+ALTER TABLE users
+    DROP COLUMN phone;
@@ -0,0 +1,11 @@
+ALTER TABLE users
+    ADD COLUMN phone TEXT UNIQUE;
+
+CREATE TABLE IF NOT EXISTS otp_codes (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash BYTEA NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (user_id)
+);
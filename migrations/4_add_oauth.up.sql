@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID DEFAULT gen_random_uuid() NOT NULL,
+    secret_hash BYTEA,
+    name TEXT NOT NULL,
+    redirect_uris TEXT[] DEFAULT '{}' NOT NULL,
+    scopes TEXT[] DEFAULT '{}' NOT NULL,
+    grant_types TEXT[] DEFAULT '{}' NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash BYTEA NOT NULL,
+    client_id UUID NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] DEFAULT '{}' NOT NULL,
+    code_challenge TEXT NOT NULL,
+    code_challenge_method TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_refresh_tokens (
+    token_hash BYTEA NOT NULL,
+    client_id UUID NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    scopes TEXT[] DEFAULT '{}' NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (token_hash)
+);
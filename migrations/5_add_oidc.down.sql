@@ -0,0 +1,8 @@
+ALTER TABLE oauth_refresh_tokens
+    DROP COLUMN auth_time;
+
+ALTER TABLE oauth_authorization_codes
+    DROP COLUMN auth_time;
+
+ALTER TABLE oauth_authorization_codes
+    DROP COLUMN nonce;
@@ -0,0 +1,8 @@
+ALTER TABLE oauth_authorization_codes
+    ADD COLUMN nonce TEXT DEFAULT '' NOT NULL;
+
+ALTER TABLE oauth_authorization_codes
+    ADD COLUMN auth_time TIMESTAMPTZ DEFAULT now() NOT NULL;
+
+ALTER TABLE oauth_refresh_tokens
+    ADD COLUMN auth_time TIMESTAMPTZ DEFAULT now() NOT NULL;
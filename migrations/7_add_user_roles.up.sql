@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN roles TEXT[] DEFAULT '{}' NOT NULL;
@@ -0,0 +1,31 @@
+DELETE FROM oauth_clients
+    WHERE tenant_id != '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE oauth_clients
+    DROP COLUMN tenant_id;
+
+DELETE FROM identities
+    WHERE tenant_id != '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE identities
+    DROP CONSTRAINT identities_pkey,
+    ADD PRIMARY KEY (provider, subject);
+
+ALTER TABLE identities
+    DROP COLUMN tenant_id;
+
+DELETE FROM users
+    WHERE tenant_id != '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE users
+    DROP CONSTRAINT users_tenant_id_email_key,
+    DROP CONSTRAINT users_tenant_id_phone_key,
+    ADD CONSTRAINT users_email_key UNIQUE (email),
+    ADD CONSTRAINT users_phone_key UNIQUE (phone);
+
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
+
+ALTER TABLE users
+    DROP COLUMN tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id UUID DEFAULT gen_random_uuid() NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    disabled BOOLEAN DEFAULT false NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT now() NOT NULL,
+    PRIMARY KEY (id)
+);
+
+-- existing users belong to the default tenant
+INSERT INTO tenants (id, slug, name)
+    VALUES ('00000000-0000-0000-0000-000000000000', 'default', 'Default');
+
+ALTER TABLE users
+    ADD COLUMN tenant_id UUID DEFAULT '00000000-0000-0000-0000-000000000000' NOT NULL
+        REFERENCES tenants (id) ON DELETE CASCADE;
+
+ALTER TABLE users
+    DROP CONSTRAINT users_email_key,
+    DROP CONSTRAINT users_phone_key,
+    ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email),
+    ADD CONSTRAINT users_tenant_id_phone_key UNIQUE (tenant_id, phone);
+
+DROP INDEX IF EXISTS idx_email;
+
+ALTER TABLE identities
+    ADD COLUMN tenant_id UUID DEFAULT '00000000-0000-0000-0000-000000000000' NOT NULL
+        REFERENCES tenants (id) ON DELETE CASCADE;
+
+ALTER TABLE identities
+    DROP CONSTRAINT identities_pkey,
+    ADD PRIMARY KEY (tenant_id, provider, subject);
+
+-- clients sign in users of their own tenant only
+ALTER TABLE oauth_clients
+    ADD COLUMN tenant_id UUID DEFAULT '00000000-0000-0000-0000-000000000000' NOT NULL
+        REFERENCES tenants (id) ON DELETE CASCADE;
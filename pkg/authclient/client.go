@@ -0,0 +1,389 @@
+// Package authclient is a Go client of the auth service. It dials the service,
+// registers and logs users in and keeps their access token fresh:
+//
+//	client, err := authclient.New("auth.example.com:443")
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	if err := client.Login(ctx, "user@example.com", password); err != nil {
+//		return err
+//	}
+//
+//	// calls to other services carry the current access token
+//	conn, err := grpc.NewClient("billing.example.com:443",
+//		grpc.WithTransportCredentials(credentials.NewTLS(nil)),
+//		grpc.WithPerRPCCredentials(client.Credentials()),
+//	)
+package authclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const defaultRefreshBefore = time.Minute
+
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// Client is a client of the auth service. It is safe for concurrent use.
+//
+// After logging in the client re-authenticates with the same credentials when the access
+// token is about to expire, as the service doesn't issue refresh tokens. The credentials
+// are kept in memory until Logout.
+//
+// Refreshing a session of Login replays the password login: every refresh is scored by
+// the risk engine, added to login history of the user and counted towards proof-of-work
+// thresholds of the client. A refresh the risk engine refuses fails like a login does,
+// e.g. when it requires a one-time code. Long-running processes should log in with
+// LoginWithAPIKey or LoginServiceAccount instead.
+type Client struct {
+	conn *grpc.ClientConn
+	auth authv1.AuthClient
+	opts options
+
+	mu        sync.Mutex
+	login     loginFunc
+	token     string
+	expiresAt time.Time
+	// session counts logins and logouts, so a refresh doesn't overwrite the session replaced meanwhile
+	session uint64
+	// refresh lets concurrent callers wait for a single login without holding mu
+	refresh singleflight.Group
+}
+
+// loginFunc authenticates with credentials of the session and returns new access token.
+type loginFunc func(ctx context.Context) (string, time.Time, error)
+
+type options struct {
+	tls           *tls.Config
+	insecure      bool
+	tenant        string
+	refreshBefore time.Duration
+	retry         RetryPolicy
+	solvePoW      bool
+	dialOpts      []grpc.DialOption
+}
+
+// Option configures Client.
+type Option func(opts *options)
+
+// WithTLS sets TLS configuration of the connection, e.g. with a client certificate
+// for servers requiring mutual TLS. By default servers are verified with system roots.
+func WithTLS(config *tls.Config) Option {
+	return func(opts *options) {
+		opts.tls = config
+	}
+}
+
+// WithInsecure disables TLS, e.g. for local development.
+func WithInsecure() Option {
+	return func(opts *options) {
+		opts.insecure = true
+	}
+}
+
+// WithTenant sends calls to the tenant of the slug instead of the default one.
+func WithTenant(slug string) Option {
+	return func(opts *options) {
+		opts.tenant = slug
+	}
+}
+
+// WithRefreshBefore sets how long before expiration the access token is refreshed, a minute by default.
+func WithRefreshBefore(d time.Duration) Option {
+	return func(opts *options) {
+		opts.refreshBefore = d
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy, zero policy disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(opts *options) {
+		opts.retry = policy
+	}
+}
+
+// WithoutPoW makes calls answered with a proof-of-work challenge fail instead of solving it.
+func WithoutPoW() Option {
+	return func(opts *options) {
+		opts.solvePoW = false
+	}
+}
+
+// WithDialOptions adds options of the connection, e.g. interceptors or stats handlers.
+func WithDialOptions(dialOpts ...grpc.DialOption) Option {
+	return func(opts *options) {
+		opts.dialOpts = append(opts.dialOpts, dialOpts...)
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		refreshBefore: defaultRefreshBefore,
+		retry:         DefaultRetryPolicy,
+		solvePoW:      true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// New returns client of the service at the target, e.g. auth.example.com:443.
+// The connection is established lazily on the first call.
+func New(target string, opts ...Option) (*Client, error) {
+	const op = "authclient.New"
+
+	o := newOptions(opts)
+
+	creds := credentials.NewTLS(o.tls)
+	if o.insecure {
+		creds = insecure.NewCredentials()
+	}
+
+	serviceConfig, err := o.retry.serviceConfig()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var interceptors []grpc.UnaryClientInterceptor
+	if o.tenant != "" {
+		interceptors = append(interceptors, tenantInterceptor(o.tenant))
+	}
+	if o.solvePoW {
+		interceptors = append(interceptors, powInterceptor())
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}
+
+	conn, err := grpc.NewClient(target, append(dialOpts, o.dialOpts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newClient(conn, authv1.NewAuthClient(conn), o), nil
+}
+
+func newClient(conn *grpc.ClientConn, auth authv1.AuthClient, opts options) *Client {
+	return &Client{
+		conn: conn,
+		auth: auth,
+		opts: opts,
+	}
+}
+
+// Auth returns client of all methods of the service. Calls requiring authentication
+// need grpc.PerRPCCredentials(c.Credentials()) option.
+func (c *Client) Auth() authv1.AuthClient {
+	return c.auth
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+// Register creates the user and returns its id. It doesn't log the user in.
+func (c *Client) Register(ctx context.Context, email, password string) (string, error) {
+	const op = "authclient.Register"
+
+	resp, err := c.auth.Register(ctx, &authv1.RegisterRequest{Email: email, Password: password})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.GetUserId(), nil
+}
+
+// Login logs the user in with email and password.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	const op = "authclient.Login"
+
+	err := c.startSession(ctx, func(ctx context.Context) (string, time.Time, error) {
+		resp, err := c.auth.Login(ctx, &authv1.LoginRequest{Email: email, Password: password})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		return resp.GetToken(), expiresAt(resp.GetToken()), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LoginWithAPIKey logs in with an API key, exchanging it for access tokens.
+func (c *Client) LoginWithAPIKey(ctx context.Context, key string) error {
+	const op = "authclient.LoginWithAPIKey"
+
+	err := c.startSession(ctx, func(ctx context.Context) (string, time.Time, error) {
+		resp, err := c.auth.ExchangeAPIKey(ctx, &authv1.ExchangeAPIKeyRequest{Key: key})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		return resp.GetToken(), expiresAt(resp.GetToken()), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LoginServiceAccount logs in as the service account with its client secret.
+// Scopes restrict the tokens, empty scopes request all scopes of the account.
+func (c *Client) LoginServiceAccount(ctx context.Context, clientID, clientSecret string, scopes []string) error {
+	const op = "authclient.LoginServiceAccount"
+
+	err := c.startSession(ctx, func(ctx context.Context) (string, time.Time, error) {
+		resp, err := c.auth.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+			ClientId:     clientID,
+			ClientSecret: clientSecret,
+			Scopes:       scopes,
+		})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		return resp.GetToken(), time.Now().Add(time.Duration(resp.GetExpiresIn()) * time.Second), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Logout forgets the access token and the credentials.
+func (c *Client) Logout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.login, c.token, c.expiresAt = nil, "", time.Time{}
+	c.session++
+}
+
+// Token returns access token of the session, logging in again if it expires
+// within the refresh window. If the login fails while the token is still valid,
+// the token is returned and the login is retried on the next call.
+// Concurrent callers share a single login, it is canceled with context of the caller that started it.
+//
+// If the client isn't logged in, returns ErrNotLoggedIn.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	const op = "authclient.Token"
+
+	c.mu.Lock()
+	login, token, expiresAt, session := c.login, c.token, c.expiresAt, c.session
+	c.mu.Unlock()
+
+	if login == nil {
+		return "", fmt.Errorf("%s: %w", op, ErrNotLoggedIn)
+	}
+
+	if c.fresh(expiresAt) {
+		return token, nil
+	}
+
+	refreshed, err, _ := c.refresh.Do(strconv.FormatUint(session, 10), func() (any, error) {
+		return c.refreshToken(ctx, login, session)
+	})
+	if err != nil {
+		if time.Now().Before(expiresAt) {
+			return token, nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return refreshed.(string), nil
+}
+
+// fresh reports whether the token expiring at the time needs no refresh,
+// tokens without expiration are never refreshed.
+func (c *Client) fresh(expiresAt time.Time) bool {
+	return expiresAt.IsZero() || time.Until(expiresAt) > c.opts.refreshBefore
+}
+
+// refreshToken logs in again and stores the token, unless the session was replaced meanwhile.
+func (c *Client) refreshToken(ctx context.Context, login loginFunc, session uint64) (string, error) {
+	// a refresh that finished before this one started may have stored a fresh token already
+	c.mu.Lock()
+	if c.session == session && c.fresh(c.expiresAt) {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+	c.mu.Unlock()
+
+	token, expiresAt, err := login(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == session {
+		c.token, c.expiresAt = token, expiresAt
+	}
+
+	return token, nil
+}
+
+// startSession logs in and replaces the session on success.
+func (c *Client) startSession(ctx context.Context, login loginFunc) error {
+	token, expiresAt, err := login(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.login, c.token, c.expiresAt = login, token, expiresAt
+	c.session++
+
+	return nil
+}
+
+// expiresAt returns expiration time of the token, or zero time if it is unknown.
+// The signature can't be verified by clients and doesn't need to be,
+// the time only schedules the refresh.
+func expiresAt(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+
+	return exp.Time
+}
@@ -0,0 +1,299 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/kurochkinivan/auth/internal/lib/pow"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const secret = "test-secret"
+
+// stubAuth issues tokens of the configured lifetime and counts logins.
+type stubAuth struct {
+	authv1.AuthClient
+
+	mu     sync.Mutex
+	ttl    time.Duration
+	fail   bool
+	logins int
+	// release, if set, holds logins until it is closed
+	release chan struct{}
+}
+
+func (s *stubAuth) Login(_ context.Context, req *authv1.LoginRequest, _ ...grpc.CallOption) (*authv1.LoginResponse, error) {
+	s.mu.Lock()
+	release := s.release
+	s.mu.Unlock()
+
+	if release != nil {
+		<-release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	if req.GetPassword() != "secret" {
+		return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+	}
+	s.logins++
+
+	token, err := jwt.NewToken(&entity.User{ID: uuid.New(), Email: req.GetEmail()}, secret, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authv1.LoginResponse{Token: token}, nil
+}
+
+func (s *stubAuth) ServiceAccountToken(_ context.Context, req *authv1.ServiceAccountTokenRequest, _ ...grpc.CallOption) (*authv1.ServiceAccountTokenResponse, error) {
+	return &authv1.ServiceAccountTokenResponse{Token: "token-of-" + req.GetClientId(), ExpiresIn: 3600}, nil
+}
+
+func (s *stubAuth) set(ttl time.Duration, fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ttl, s.fail = ttl, fail
+}
+
+func newTestClient(ttl time.Duration) (*Client, *stubAuth) {
+	auth := &stubAuth{ttl: ttl}
+	return newClient(nil, auth, newOptions([]Option{WithRefreshBefore(time.Minute)})), auth
+}
+
+func TestLogin(t *testing.T) {
+	client, auth := newTestClient(time.Hour)
+	ctx := context.Background()
+
+	_, err := client.Token(ctx)
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+
+	err = client.Login(ctx, "user@example.com", "wrong")
+	assert.Equal(t, codes.InvalidArgument, status.Code(errors.Unwrap(err)))
+
+	require.NoError(t, client.Login(ctx, "user@example.com", "secret"))
+
+	first, err := client.Token(ctx)
+	require.NoError(t, err)
+	second, err := client.Token(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second, "fresh token is reused")
+	assert.Equal(t, 1, auth.logins)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), client.expiresAt, 2*time.Second)
+
+	client.Logout()
+	_, err = client.Token(ctx)
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+}
+
+func TestToken_Refresh(t *testing.T) {
+	// the token expires within the refresh window right away
+	client, auth := newTestClient(30 * time.Second)
+	ctx := context.Background()
+
+	require.NoError(t, client.Login(ctx, "user@example.com", "secret"))
+	expiring := client.token
+
+	auth.set(time.Hour, false)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := client.Token(ctx)
+			assert.NoError(t, err)
+			tokens[i] = token
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, auth.logins, "concurrent callers share a single refresh")
+	for _, token := range tokens {
+		assert.NotEqual(t, expiring, token)
+		assert.Equal(t, tokens[0], token)
+	}
+}
+
+func TestToken_RefreshDoesNotBlockSession(t *testing.T) {
+	client, auth := newTestClient(30 * time.Second)
+	ctx := context.Background()
+
+	require.NoError(t, client.Login(ctx, "user@example.com", "secret"))
+
+	release := make(chan struct{})
+	auth.mu.Lock()
+	auth.release = release
+	auth.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = client.Token(ctx)
+	}()
+
+	// the session is available while the refresh waits for the server
+	logout := make(chan struct{})
+	go func() {
+		defer close(logout)
+		client.Logout()
+	}()
+
+	select {
+	case <-logout:
+	case <-time.After(time.Second):
+		t.Fatal("logout waits for the refresh")
+	}
+
+	close(release)
+	<-done
+
+	_, err := client.Token(ctx)
+	assert.ErrorIs(t, err, ErrNotLoggedIn, "refresh of the old session doesn't log in again")
+}
+
+func TestToken_RefreshFailure(t *testing.T) {
+	client, auth := newTestClient(30 * time.Second)
+	ctx := context.Background()
+
+	require.NoError(t, client.Login(ctx, "user@example.com", "secret"))
+	valid := client.token
+
+	auth.set(time.Hour, true)
+
+	token, err := client.Token(ctx)
+	require.NoError(t, err, "token is still valid")
+	assert.Equal(t, valid, token)
+
+	client.expiresAt = time.Now().Add(-time.Second)
+
+	_, err = client.Token(ctx)
+	assert.Equal(t, codes.Unavailable, status.Code(errors.Unwrap(err)))
+
+	auth.set(time.Hour, false)
+
+	token, err = client.Token(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, valid, token)
+}
+
+func TestLoginServiceAccount(t *testing.T) {
+	client, _ := newTestClient(time.Hour)
+
+	require.NoError(t, client.LoginServiceAccount(context.Background(), "billing", "client-secret", nil))
+
+	assert.Equal(t, "token-of-billing", client.token)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), client.expiresAt, 2*time.Second)
+}
+
+func TestCredentials(t *testing.T) {
+	client, _ := newTestClient(time.Hour)
+	creds := client.Credentials()
+	ctx := context.Background()
+
+	_, err := creds.GetRequestMetadata(ctx)
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+
+	require.NoError(t, client.Login(ctx, "user@example.com", "secret"))
+
+	md, err := creds.GetRequestMetadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer "+client.token, md["authorization"])
+	assert.True(t, creds.RequireTransportSecurity())
+
+	client.opts.insecure = true
+	assert.False(t, creds.RequireTransportSecurity())
+}
+
+func TestRetryPolicy(t *testing.T) {
+	config, err := DefaultRetryPolicy.serviceConfig()
+	require.NoError(t, err)
+
+	var parsed struct {
+		MethodConfig []struct {
+			Name        []map[string]string
+			RetryPolicy map[string]any
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(config), &parsed))
+	require.Len(t, parsed.MethodConfig, 1)
+	assert.Contains(t, parsed.MethodConfig[0].Name, map[string]string{"service": "auth.Auth", "method": "ValidateToken"})
+	assert.Contains(t, parsed.MethodConfig[0].Name, map[string]string{"service": "auth.Admin", "method": "ListTenants"})
+	for _, name := range parsed.MethodConfig[0].Name {
+		assert.NotEmpty(t, name["method"], "whole services aren't retried")
+		assert.NotContains(t, []string{"Register", "Login", "AcceptInvitation", "VerifyOTP"}, name["method"], "non-idempotent calls aren't retried")
+	}
+	assert.Equal(t, "0.1s", parsed.MethodConfig[0].RetryPolicy["initialBackoff"])
+
+	for name, policy := range map[string]RetryPolicy{"default": DefaultRetryPolicy, "disabled": {}} {
+		t.Run(name, func(t *testing.T) {
+			config, err := policy.serviceConfig()
+			require.NoError(t, err)
+
+			// gRPC rejects invalid service configs
+			conn, err := grpc.NewClient("localhost:0",
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithDefaultServiceConfig(config),
+			)
+			require.NoError(t, err)
+			require.NoError(t, conn.Close())
+		})
+	}
+}
+
+func TestPoWInterceptor(t *testing.T) {
+	issuer := pow.NewIssuer(secret, time.Minute)
+	challenge := issuer.Issue("client", 4)
+
+	calls := 0
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if solution := md.Get(powSolutionKey); len(solution) > 0 {
+			_, err := issuer.Verify(md.Get(powChallengeKey)[0], solution[0], "client")
+			return err
+		}
+
+		for _, opt := range opts {
+			if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+				*trailer.TrailerAddr = metadata.Pairs(powChallengeKey, challenge)
+			}
+		}
+
+		return status.Error(codes.ResourceExhausted, "proof of work required")
+	}
+
+	err := powInterceptor()(context.Background(), "/auth.Auth/Login", nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSolve_InvalidChallenge(t *testing.T) {
+	for _, challenge := range []string{"", "v1.1.4.nonce", "v2.1.4.nonce.mac", "v1.1.64.nonce.mac"} {
+		_, err := solve(context.Background(), challenge)
+		assert.ErrorIs(t, err, errInvalidChallenge, challenge)
+	}
+}
@@ -0,0 +1,33 @@
+package authclient
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// Credentials returns credentials attaching the current access token to calls,
+// for grpc.WithPerRPCCredentials of connections to services accepting the tokens,
+// or grpc.PerRPCCredentials of single calls.
+func (c *Client) Credentials() credentials.PerRPCCredentials {
+	return perRPCCredentials{client: c}
+}
+
+type perRPCCredentials struct {
+	client *Client
+}
+
+func (p perRPCCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := p.client.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity keeps tokens from being sent in plain text,
+// unless the client itself was configured as insecure.
+func (p perRPCCredentials) RequireTransportSecurity() bool {
+	return !p.client.opts.insecure
+}
@@ -0,0 +1,54 @@
+package authclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// maxDifficulty bounds challenges the client solves, see pow.MaxDifficulty.
+const maxDifficulty = 32
+
+var errInvalidChallenge = errors.New("invalid proof-of-work challenge")
+
+// solve finds solution of the proof-of-work challenge "v1.{expires}.{difficulty}.{nonce}.{mac}":
+// a string such that SHA-256 of "{challenge}:{solution}" starts with difficulty zero bits.
+// It mirrors pow.Solve of the server, so the client doesn't depend on its packages.
+// It gives up when the context is done.
+func solve(ctx context.Context, challenge string) (string, error) {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 5 || parts[0] != "v1" {
+		return "", errInvalidChallenge
+	}
+
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil || difficulty < 0 || difficulty > maxDifficulty {
+		return "", errInvalidChallenge
+	}
+
+	for n := uint64(0); ; n++ {
+		if n%(1<<14) == 0 && ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		solution := strconv.FormatUint(n, 36)
+		if leadingZeros(sha256.Sum256([]byte(challenge+":"+solution))) >= difficulty {
+			return solution, nil
+		}
+	}
+}
+
+func leadingZeros(sum [sha256.Size]byte) int {
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+
+	return zeros
+}
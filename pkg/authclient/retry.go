@@ -0,0 +1,132 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of proof-of-work challenges, see grpcapp.PoWChallengeKey,
+// and of the tenant, see tenancy.MetadataKey.
+const (
+	powChallengeKey = "x-pow-challenge"
+	powSolutionKey  = "x-pow-solution"
+	tenantKey       = "x-tenant"
+)
+
+// idempotentMethods are methods the retry policy applies to. They only read, so repeating
+// a call the server has processed before failing is harmless. Other calls, e.g. Register,
+// Login or AcceptInvitation, create users, sessions or history and aren't retried.
+var idempotentMethods = []struct{ service, method string }{
+	{"auth.Auth", "ValidateToken"},
+	{"auth.Auth", "ListAPIKeys"},
+	{"auth.Auth", "ListLoginHistory"},
+	{"auth.Admin", "GetTenant"},
+	{"auth.Admin", "ListTenants"},
+	{"auth.Admin", "ListInvitations"},
+	{"auth.Admin", "ListServiceAccounts"},
+	{"auth.Admin", "QueryAuditLog"},
+	{"auth.Admin", "ListWebhooks"},
+	{"auth.Admin", "ListWebhookDeliveries"},
+}
+
+// RetryPolicy retries idempotent calls failing with transient errors with exponential backoff.
+// It is applied by gRPC, see https://github.com/grpc/proposal/blob/master/A6-client-retries.md.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, gRPC limits it to 5.
+	// Policies of less than 2 attempts don't retry.
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// Codes are status codes of transient errors.
+	Codes []codes.Code
+}
+
+// DefaultRetryPolicy retries idempotent calls failing while the server is unavailable, e.g. while it restarts.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       4,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        2 * time.Second,
+	BackoffMultiplier: 2,
+	Codes:             []codes.Code{codes.Unavailable},
+}
+
+type retryPolicyConfig struct {
+	MaxAttempts          int          `json:"maxAttempts"`
+	InitialBackoff       string       `json:"initialBackoff"`
+	MaxBackoff           string       `json:"maxBackoff"`
+	BackoffMultiplier    float64      `json:"backoffMultiplier"`
+	RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+}
+
+type methodConfig struct {
+	Name        []map[string]string `json:"name"`
+	RetryPolicy *retryPolicyConfig  `json:"retryPolicy,omitempty"`
+}
+
+// serviceConfig returns gRPC service config of the policy in JSON.
+func (p RetryPolicy) serviceConfig() (string, error) {
+	method := methodConfig{}
+	for _, m := range idempotentMethods {
+		method.Name = append(method.Name, map[string]string{"service": m.service, "method": m.method})
+	}
+
+	if p.MaxAttempts >= 2 {
+		method.RetryPolicy = &retryPolicyConfig{
+			MaxAttempts:          p.MaxAttempts,
+			InitialBackoff:       seconds(p.InitialBackoff),
+			MaxBackoff:           seconds(p.MaxBackoff),
+			BackoffMultiplier:    p.BackoffMultiplier,
+			RetryableStatusCodes: p.Codes,
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{"methodConfig": []methodConfig{method}})
+	if err != nil {
+		return "", err
+	}
+
+	return string(config), nil
+}
+
+// seconds formats the duration as service config does, e.g. 0.1s.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// tenantInterceptor sends calls to the tenant of the slug.
+func tenantInterceptor(slug string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, tenantKey, slug), method, req, reply, cc, opts...)
+	}
+}
+
+// powInterceptor solves proof-of-work challenge the server answers with when it sees
+// too many requests from the client and repeats the call with the solution.
+func powInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+
+		challenge := trailer.Get(powChallengeKey)
+		if status.Code(err) != codes.ResourceExhausted || len(challenge) == 0 {
+			return err
+		}
+
+		solution, solveErr := solve(ctx, challenge[0])
+		if solveErr != nil {
+			return err
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, powChallengeKey, challenge[0], powSolutionKey, solution)
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,89 @@
+package tokenauth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor authenticates requests with bearer token of authorization metadata
+// and checks the requirement of the method, claims of the token are put into the context.
+// Requests without a valid token fail with codes.Unauthenticated, requests not meeting
+// the requirement fail with codes.PermissionDenied.
+func UnaryServerInterceptor(v *Verifier, policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, v, policy.requirement(info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor of streaming methods.
+func StreamServerInterceptor(v *Verifier, policy Policy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), v, policy.requirement(info.FullMethod))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides context of the stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize returns context with claims of the request token,
+// the error is a gRPC status ready to be returned to the client.
+func authorize(ctx context.Context, v *Verifier, requirement Requirement) (context.Context, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	claims, err := authenticate(ctx, v, authorization)
+	if err != nil {
+		if requirement.Public && errors.Is(err, ErrMissingToken) {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if err := requirement.Check(claims); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return WithClaims(ctx, claims), nil
+}
+
+// authenticate returns claims of the bearer token of the authorization header value.
+// Errors are ErrMissingToken or ErrInvalidToken, without details which could help forging tokens.
+func authenticate(ctx context.Context, v *Verifier, authorization string) (*Claims, error) {
+	token, err := bearerToken(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := v.Verify(ctx, token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,45 @@
+package tokenauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Middleware authenticates requests with bearer token of the Authorization header
+// and checks the requirement, claims of the token are put into the request context.
+// Requests without a valid token are answered with 401, requests not meeting
+// the requirement with 403, both carry WWW-Authenticate header of RFC 6750.
+func Middleware(v *Verifier, requirement Requirement) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticate(r.Context(), v, r.Header.Get("Authorization"))
+			switch {
+			case errors.Is(err, ErrMissingToken) && requirement.Public:
+				next.ServeHTTP(w, r)
+				return
+			case errors.Is(err, ErrMissingToken):
+				// no error code for requests without credentials, see RFC 6750 section 3.1
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			case err != nil:
+				challenge(w, "invalid_token", err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if err := requirement.Check(claims); err != nil {
+				challenge(w, "insufficient_scope", err)
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func challenge(w http.ResponseWriter, code string, err error) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q, error_description=%q", code, err.Error()))
+}
@@ -0,0 +1,148 @@
+package tokenauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var policy = Policy{
+	Methods: map[string]Requirement{
+		"/billing.Billing/Prices": {Public: true},
+		"/billing.Billing/Refund": {Roles: []string{"admin"}},
+	},
+	Default: Requirement{Scopes: []string{"billing"}},
+}
+
+func bearer(t *testing.T, opts ...jwt.Option) string {
+	t.Helper()
+
+	token, err := jwt.NewToken(user, secret, time.Hour, opts...)
+	require.NoError(t, err)
+
+	return "Bearer " + token
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(NewHMACVerifier(secret), policy)
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		code          codes.Code
+		authenticated bool
+	}{
+		{"valid", "/billing.Billing/Charge", bearer(t, jwt.WithScopes([]string{"billing"})), codes.OK, true},
+		{"missing token", "/billing.Billing/Charge", "", codes.Unauthenticated, false},
+		{"not bearer", "/billing.Billing/Charge", "Basic dXNlcjpwYXNz", codes.Unauthenticated, false},
+		{"invalid token", "/billing.Billing/Charge", "Bearer forged", codes.Unauthenticated, false},
+		{"insufficient scope", "/billing.Billing/Charge", bearer(t, jwt.WithScopes([]string{"reports"})), codes.PermissionDenied, false},
+		{"role", "/billing.Billing/Refund", bearer(t), codes.OK, true},
+		{"public", "/billing.Billing/Prices", "", codes.OK, false},
+		{"public with token", "/billing.Billing/Prices", bearer(t), codes.OK, true},
+		{"public with invalid token", "/billing.Billing/Prices", "Bearer forged", codes.Unauthenticated, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+
+			var claims *Claims
+			_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ any) (any, error) {
+				claims, _ = FromContext(ctx)
+				return "response", nil
+			})
+			assert.Equal(t, tt.code, status.Code(err))
+
+			if tt.authenticated {
+				require.NotNil(t, claims)
+				assert.Equal(t, user.ID, claims.UserID)
+			} else {
+				assert.Nil(t, claims)
+			}
+		})
+	}
+}
+
+type stream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(NewHMACVerifier(secret), policy)
+	info := &grpc.StreamServerInfo{FullMethod: "/billing.Billing/Refund"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", bearer(t)))
+	err := interceptor(nil, &stream{ctx: ctx}, info, func(_ any, ss grpc.ServerStream) error {
+		claims, ok := FromContext(ss.Context())
+		require.True(t, ok)
+		assert.Equal(t, []string{"admin"}, claims.Roles)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = interceptor(nil, &stream{ctx: context.Background()}, info, func(any, grpc.ServerStream) error {
+		t.Fatal("handler must not be called")
+		return nil
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(NewHMACVerifier(secret), Requirement{Scopes: []string{"billing"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := FromContext(r.Context())
+			require.True(t, ok)
+			_, _ = w.Write([]byte(claims.Email))
+		}),
+	)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{"valid", bearer(t, jwt.WithScopes([]string{"billing"})), http.StatusOK, ""},
+		{"missing token", "", http.StatusUnauthorized, "Bearer"},
+		{"invalid token", "Bearer forged", http.StatusUnauthorized, `Bearer error="invalid_token", error_description="invalid access token"`},
+		{"insufficient scope", bearer(t, jwt.WithScopes([]string{"reports"})), http.StatusForbidden, `Bearer error="insufficient_scope", error_description="insufficient scope"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.challenge, rec.Header().Get("WWW-Authenticate"))
+			if tt.status == http.StatusOK {
+				assert.Equal(t, user.Email, rec.Body.String())
+			}
+		})
+	}
+}
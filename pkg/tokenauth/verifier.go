@@ -0,0 +1,244 @@
+package tokenauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultCacheTTL        = time.Hour
+	defaultRefreshInterval = time.Minute
+
+	// accessTokenType is typ header of JWT access tokens, see RFC 9068 section 2.1.
+	accessTokenType = "at+jwt"
+)
+
+// Verifier verifies signatures and expiration of tokens and returns their claims.
+// It is safe for concurrent use.
+type Verifier struct {
+	keyfunc func(ctx context.Context, token *jwt.Token) (any, error)
+	methods []string
+	opts    options
+	// scopeRequired restricts tokens without a scope claim.
+	scopeRequired bool
+	// accessTokenRequired rejects tokens without typ header of access tokens.
+	accessTokenRequired bool
+}
+
+type options struct {
+	issuer          string
+	audience        string
+	leeway          time.Duration
+	client          *http.Client
+	cacheTTL        time.Duration
+	refreshInterval time.Duration
+}
+
+// Option configures Verifier.
+type Option func(opts *options)
+
+// WithIssuer requires iss claim of tokens to be the issuer.
+func WithIssuer(issuer string) Option {
+	return func(opts *options) {
+		opts.issuer = issuer
+	}
+}
+
+// WithAudience requires aud claim of tokens to contain the audience.
+func WithAudience(audience string) Option {
+	return func(opts *options) {
+		opts.audience = audience
+	}
+}
+
+// WithLeeway tolerates clock skew between the services when checking expiration.
+func WithLeeway(leeway time.Duration) Option {
+	return func(opts *options) {
+		opts.leeway = leeway
+	}
+}
+
+// WithHTTPClient sets client fetching the key set, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(opts *options) {
+		opts.client = client
+	}
+}
+
+// WithCacheTTL sets how long fetched keys are used before the key set is fetched again, an hour by default.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.cacheTTL = ttl
+	}
+}
+
+// WithRefreshInterval limits how often tokens signed with unknown keys make the key set
+// to be fetched again, a minute by default. Keys are rotated rarely, so this protects
+// the issuer from tokens with made up key ids.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(opts *options) {
+		opts.refreshInterval = interval
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		client:          http.DefaultClient,
+		cacheTTL:        defaultCacheTTL,
+		refreshInterval: defaultRefreshInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// NewHMACVerifier returns Verifier of tokens signed with HS256 and the secret
+// shared with the auth service.
+//
+// It is meant for trusted internal services only: holders of the secret can sign
+// tokens of any user, and the secret verifies every token the auth service signs with it.
+// Services outside of the trust boundary should use NewJWKSVerifier.
+func NewHMACVerifier(secret string, opts ...Option) *Verifier {
+	return &Verifier{
+		keyfunc: func(context.Context, *jwt.Token) (any, error) {
+			return []byte(secret), nil
+		},
+		methods: []string{jwt.SigningMethodHS256.Alg()},
+		opts:    newOptions(opts),
+	}
+}
+
+// NewJWKSVerifier returns Verifier of tokens signed with RS256 or ES256 by keys
+// published at the URL, e.g. https://auth.example.com/.well-known/jwks.json.
+// Keys are fetched lazily and cached, tokens signed with an unknown key make them to be fetched again.
+//
+// The issuer signs tokens for many audiences, e.g. ID tokens for every OAuth client,
+// so tokens must be issued by the issuer for the audience, it panics if either is empty.
+// Tokens must be access tokens of RFC 9068 with at+jwt typ header, so ID tokens
+// issued to a client whose id is the audience don't pass as access tokens.
+// Tokens without a scope claim are restricted and grant no scope.
+func NewJWKSVerifier(url, issuer, audience string, opts ...Option) *Verifier {
+	if issuer == "" || audience == "" {
+		panic("tokenauth: issuer and audience are required to verify tokens with a key set")
+	}
+
+	o := newOptions(append(opts, WithIssuer(issuer), WithAudience(audience)))
+	keys := newKeySet(url, o)
+
+	return &Verifier{
+		keyfunc: func(ctx context.Context, token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			if kid == "" {
+				return nil, errors.New("token has no key id")
+			}
+
+			return keys.key(ctx, kid)
+		},
+		methods:             []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()},
+		opts:                o,
+		scopeRequired:       true,
+		accessTokenRequired: true,
+	}
+}
+
+// Verify returns claims of the token.
+//
+// If the token is malformed, expired, signed with another key or, for key sets,
+// not an access token, returns ErrInvalidToken.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	const op = "tokenauth.Verify"
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.opts.leeway),
+	}
+	if v.opts.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.opts.issuer))
+	}
+	if v.opts.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(v.opts.audience))
+	}
+
+	mapClaims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, mapClaims, func(t *jwt.Token) (any, error) {
+		return v.keyfunc(ctx, t)
+	}, parserOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, errors.Join(ErrInvalidToken, err))
+	}
+
+	if v.accessTokenRequired && !isAccessToken(parsed) {
+		return nil, fmt.Errorf("%s: %w: token is not an access token", op, ErrInvalidToken)
+	}
+
+	claims, err := claimsOf(mapClaims)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	claims.scopeRequired = v.scopeRequired
+
+	return claims, nil
+}
+
+// isAccessToken reports whether typ header of the token is at+jwt, the media type
+// prefix is optional and the comparison is case-insensitive, see RFC 9068 section 4.
+func isAccessToken(token *jwt.Token) bool {
+	typ, _ := token.Header["typ"].(string)
+	typ = strings.TrimPrefix(strings.ToLower(typ), "application/")
+
+	return typ == accessTokenType
+}
+
+func claimsOf(mapClaims jwt.MapClaims) (*Claims, error) {
+	claims := new(Claims)
+
+	claims.Subject, _ = mapClaims["sub"].(string)
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	claims.UserID, _ = uuid.Parse(claims.Subject)
+
+	claims.TenantID = uuidClaim(mapClaims, "tenant_id")
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.ClientID, _ = mapClaims["client_id"].(string)
+
+	if scope, _ := mapClaims["scope"].(string); scope != "" {
+		claims.Scopes = strings.Fields(scope)
+	}
+
+	roles, _ := mapClaims["roles"].([]any)
+	for _, role := range roles {
+		if role, ok := role.(string); ok {
+			claims.Roles = append(claims.Roles, role)
+		}
+	}
+
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+
+	claims.APIKeyID = uuidClaim(mapClaims, "api_key_id")
+	claims.ServiceAccount, _ = mapClaims["service_account"].(bool)
+
+	if act, ok := mapClaims["act"].(map[string]any); ok {
+		claims.ActorID = uuidClaim(act, "sub")
+	}
+
+	return claims, nil
+}
+
+func uuidClaim(claims map[string]any, name string) uuid.UUID {
+	value, _ := claims[name].(string)
+	id, _ := uuid.Parse(value)
+	return id
+}
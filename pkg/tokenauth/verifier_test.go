@@ -0,0 +1,298 @@
+package tokenauth
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/jwk"
+	"github.com/kurochkinivan/auth/internal/lib/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "test-secret"
+
+var user = &entity.User{
+	ID:       uuid.New(),
+	TenantID: uuid.New(),
+	Email:    "user@example.com",
+	Roles:    []string{"admin"},
+}
+
+func TestVerify_HMAC(t *testing.T) {
+	actorID := uuid.New()
+	token, err := jwt.NewToken(user, secret, time.Hour, jwt.WithScopes([]string{"billing", "reports"}), jwt.WithActor(actorID))
+	require.NoError(t, err)
+
+	claims, err := NewHMACVerifier(secret).Verify(context.Background(), token)
+	require.NoError(t, err)
+
+	assert.Equal(t, user.ID.String(), claims.Subject)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, user.TenantID, claims.TenantID)
+	assert.Equal(t, user.Email, claims.Email)
+	assert.Equal(t, []string{"billing", "reports"}, claims.Scopes)
+	assert.Equal(t, []string{"admin"}, claims.Roles)
+	assert.Equal(t, actorID, claims.ActorID)
+	assert.False(t, claims.ServiceAccount)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt, 2*time.Second)
+}
+
+func TestVerify_ServiceAccount(t *testing.T) {
+	account := &entity.ServiceAccount{ID: uuid.New(), TenantID: uuid.New()}
+	token, err := jwt.NewServiceAccountToken(account, secret, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := NewHMACVerifier(secret).Verify(context.Background(), token)
+	require.NoError(t, err)
+
+	assert.True(t, claims.ServiceAccount)
+	assert.Equal(t, account.ID, claims.UserID)
+	assert.True(t, claims.Restricted(), "machine tokens grant only explicit scopes")
+	assert.False(t, claims.HasScope("billing"))
+}
+
+func TestVerify_Restricted(t *testing.T) {
+	userToken, err := jwt.NewToken(user, secret, time.Hour)
+	require.NoError(t, err)
+
+	delegated, err := jwt.NewToken(user, secret, time.Hour, jwt.WithClientID("client"))
+	require.NoError(t, err)
+
+	clientToken, err := jwt.NewClientToken("client", secret, time.Hour)
+	require.NoError(t, err)
+
+	scoped, err := jwt.NewClientToken("client", secret, time.Hour, jwt.WithScopes([]string{"billing"}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		billing  bool
+		reports  bool
+		restrict bool
+	}{
+		{"user", userToken, true, true, false},
+		{"delegated without scopes", delegated, false, false, true},
+		{"client without scopes", clientToken, false, false, true},
+		{"client with scopes", scoped, true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := NewHMACVerifier(secret).Verify(context.Background(), tt.token)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.restrict, claims.Restricted())
+			assert.Equal(t, tt.billing, claims.HasScope("billing"))
+			assert.Equal(t, tt.reports, claims.HasScope("reports"))
+		})
+	}
+}
+
+func TestVerify_Invalid(t *testing.T) {
+	expired, err := jwt.NewToken(user, secret, -time.Minute)
+	require.NoError(t, err)
+
+	otherSecret, err := jwt.NewToken(user, "another-secret", time.Hour)
+	require.NoError(t, err)
+
+	key, err := jwk.Generate()
+	require.NoError(t, err)
+	// asymmetric tokens must not be accepted as HMAC ones
+	idToken, err := jwt.NewIDToken(user, key, "https://auth.example.com", "client", time.Hour)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"expired":      expired,
+		"other secret": otherSecret,
+		"other method": idToken,
+		"malformed":    "not.a.token",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewHMACVerifier(secret).Verify(context.Background(), token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	t.Run("leeway", func(t *testing.T) {
+		_, err := NewHMACVerifier(secret, WithLeeway(5*time.Minute)).Verify(context.Background(), expired)
+		assert.NoError(t, err)
+	})
+}
+
+// issuer publishes the key set and counts how many times it was fetched.
+type issuer struct {
+	mu      sync.Mutex
+	keys    []*jwk.Key
+	fetches atomic.Int32
+}
+
+func (i *issuer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	i.fetches.Add(1)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var set jwk.JSONWebKeySet
+	for _, key := range i.keys {
+		set.Keys = append(set.Keys, key.Public())
+	}
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+func (i *issuer) rotate(t *testing.T) *jwk.Key {
+	t.Helper()
+
+	key, err := jwk.Generate()
+	require.NoError(t, err)
+
+	i.mu.Lock()
+	i.keys = append(i.keys, key)
+	i.mu.Unlock()
+
+	return key
+}
+
+// accessToken returns JWT access token of the user (RFC 9068) signed with the key.
+func accessToken(t *testing.T, key *jwk.Key, issuer, audience string, claims map[string]any) (string, error) {
+	t.Helper()
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodRS256, gojwt.MapClaims{
+		"iss": issuer,
+		"sub": user.ID.String(),
+		"aud": audience,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = key.ID
+	token.Header["typ"] = "at+jwt"
+
+	maps.Copy(token.Claims.(gojwt.MapClaims), claims)
+
+	return token.SignedString(key.Private)
+}
+
+func TestVerify_JWKS(t *testing.T) {
+	iss := new(issuer)
+	server := httptest.NewServer(iss)
+	t.Cleanup(server.Close)
+
+	first := iss.rotate(t)
+
+	verifier := NewJWKSVerifier(server.URL, "https://auth.example.com", "billing", WithRefreshInterval(0))
+
+	token, err := accessToken(t, first, "https://auth.example.com", "billing", nil)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			claims, err := verifier.Verify(context.Background(), token)
+			if assert.NoError(t, err) {
+				assert.Equal(t, user.ID, claims.UserID)
+				assert.True(t, claims.Restricted(), "tokens without scope claim grant no scope")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), iss.fetches.Load(), "keys are cached")
+
+	t.Run("rotated key", func(t *testing.T) {
+		second := iss.rotate(t)
+
+		token, err := accessToken(t, second, "https://auth.example.com", "billing", nil)
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), iss.fetches.Load())
+	})
+
+	t.Run("scoped", func(t *testing.T) {
+		token, err := accessToken(t, first, "https://auth.example.com", "billing", map[string]any{"scope": "billing"})
+		require.NoError(t, err)
+
+		claims, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		assert.True(t, claims.HasScope("billing"))
+		assert.False(t, claims.HasScope("reports"))
+	})
+
+	t.Run("other issuer", func(t *testing.T) {
+		token, err := accessToken(t, first, "https://evil.example.com", "billing", nil)
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("id token", func(t *testing.T) {
+		// the client whose id is the audience gets ID tokens signed with the same keys
+		token, err := jwt.NewIDToken(user, first, "https://auth.example.com", "billing", time.Hour, jwt.WithScopes([]string{"billing"}))
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("other audience", func(t *testing.T) {
+		token, err := accessToken(t, first, "https://auth.example.com", "reports", nil)
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestVerify_JWKSRefreshInterval(t *testing.T) {
+	iss := new(issuer)
+	server := httptest.NewServer(iss)
+	t.Cleanup(server.Close)
+
+	iss.rotate(t)
+	verifier := NewJWKSVerifier(server.URL, "https://auth.example.com", "billing")
+
+	unknown, err := jwk.Generate()
+	require.NoError(t, err)
+
+	token, err := accessToken(t, unknown, "https://auth.example.com", "billing", nil)
+	require.NoError(t, err)
+
+	for range 3 {
+		_, err = verifier.Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+	assert.Equal(t, int32(1), iss.fetches.Load(), "unknown keys don't make the set to be fetched on every request")
+}
+
+func TestNewJWKSVerifier_RequiresIssuerAndAudience(t *testing.T) {
+	assert.Panics(t, func() { NewJWKSVerifier("https://auth.example.com/.well-known/jwks.json", "", "billing") })
+	assert.Panics(t, func() {
+		NewJWKSVerifier("https://auth.example.com/.well-known/jwks.json", "https://auth.example.com", "")
+	})
+}
+
+func TestRequirement_Check(t *testing.T) {
+	restricted := &Claims{Scopes: []string{"billing"}, Roles: []string{"support"}}
+	unrestricted := &Claims{Roles: []string{"admin"}}
+
+	assert.NoError(t, Requirement{Scopes: []string{"billing"}}.Check(restricted))
+	assert.ErrorIs(t, Requirement{Scopes: []string{"billing", "reports"}}.Check(restricted), ErrInsufficientScope)
+	assert.NoError(t, Requirement{Scopes: []string{"reports"}}.Check(unrestricted), "unrestricted tokens grant any scope")
+
+	assert.NoError(t, Requirement{Roles: []string{"admin", "support"}}.Check(restricted))
+	assert.ErrorIs(t, Requirement{Roles: []string{"admin"}}.Check(restricted), ErrForbiddenRole)
+}
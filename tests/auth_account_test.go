@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func registerAndLogin(t *testing.T, ctx context.Context, st *suite.Suite, email, password string) context.Context {
+	t.Helper()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &authv1.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respLogin.GetToken())
+}
+
+func TestChangeEmail_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	newEmail := gofakeit.Email()
+	password := randomFakePassword()
+
+	userCtx := registerAndLogin(t, ctx, st, email, password)
+
+	_, err := st.AuthClient.ChangeEmail(userCtx, &authv1.ChangeEmailRequest{
+		Password: password,
+		Email:    newEmail,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &authv1.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.Login(ctx, &authv1.LoginRequest{
+		Email:    newEmail,
+		Password: password,
+	})
+	assert.NoError(t, err)
+}
+
+func TestChangeEmail_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	password := randomFakePassword()
+	takenEmail := gofakeit.Email()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    takenEmail,
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	userCtx := registerAndLogin(t, ctx, st, gofakeit.Email(), password)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		password string
+		email    string
+		code     codes.Code
+	}{
+		{"unauthenticated", ctx, password, gofakeit.Email(), codes.Unauthenticated},
+		{"wrong password", userCtx, randomFakePassword(), gofakeit.Email(), codes.InvalidArgument},
+		{"invalid email", userCtx, password, "not-an-email", codes.InvalidArgument},
+		{"email taken", userCtx, password, takenEmail, codes.AlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ChangeEmail(tt.ctx, &authv1.ChangeEmailRequest{
+				Password: tt.password,
+				Email:    tt.email,
+			})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestDeleteAccount_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	userCtx := registerAndLogin(t, ctx, st, email, password)
+
+	_, err := st.AuthClient.DeleteAccount(userCtx, &authv1.DeleteAccountRequest{
+		Password: randomFakePassword(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.DeleteAccount(userCtx, &authv1.DeleteAccountRequest{
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &authv1.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
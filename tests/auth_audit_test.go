@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestQueryAuditLog_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	respRegister, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &authv1.LoginRequest{
+		Email:    email,
+		Password: randomFakePassword(),
+	})
+	require.Error(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &authv1.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, uuid.NewString()))
+
+	var events []*authv1.AuditEvent
+	pageToken := ""
+	for {
+		resp, err := st.AdminClient.QueryAuditLog(adminCtx, &authv1.QueryAuditLogRequest{
+			UserId:    respRegister.GetUserId(),
+			PageSize:  2,
+			PageToken: pageToken,
+		})
+		require.NoError(t, err)
+
+		events = append(events, resp.GetEvents()...)
+
+		if resp.GetNextPageToken() == "" {
+			break
+		}
+		pageToken = resp.GetNextPageToken()
+	}
+
+	require.Len(t, events, 3)
+
+	// newest first
+	assert.Equal(t, "login", events[0].GetType())
+	assert.Equal(t, "success", events[0].GetOutcome())
+	assert.Equal(t, "login", events[1].GetType())
+	assert.Equal(t, "failure", events[1].GetOutcome())
+	assert.Equal(t, "invalid_password", events[1].GetDetails()["reason"])
+	assert.Equal(t, "register", events[2].GetType())
+
+	for _, event := range events {
+		assert.Equal(t, respRegister.GetUserId(), event.GetUserId())
+		assert.NotEmpty(t, event.GetIp())
+		assert.NotEmpty(t, event.GetUserAgent())
+	}
+}
+
+func TestQueryAuditLog_Denied(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AdminClient.QueryAuditLog(ctx, &authv1.QueryAuditLogRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, uuid.NewString()))
+
+	_, err = st.AdminClient.QueryAuditLog(adminCtx, &authv1.QueryAuditLogRequest{
+		PageToken: "not a token",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
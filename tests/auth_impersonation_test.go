@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestImpersonate_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respRegister, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	supportID := uuid.NewString()
+	supportCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+roleToken(t, st, supportID, "support"))
+
+	respImpersonate, err := st.AdminClient.Impersonate(supportCtx, &authv1.ImpersonateRequest{
+		UserId: respRegister.GetUserId(),
+		Reason: "ticket 42",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(st.Cfg.Impersonation.TokenTTL.Seconds()), respImpersonate.GetExpiresIn())
+
+	respValidate, err := st.AuthClient.ValidateToken(ctx, &authv1.ValidateTokenRequest{
+		Token: respImpersonate.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, respRegister.GetUserId(), respValidate.GetUserId())
+	assert.Equal(t, supportID, respValidate.GetActorId())
+
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respImpersonate.GetToken())
+	_, err = st.AuthClient.CreateAPIKey(userCtx, &authv1.CreateAPIKeyRequest{
+		Name: "persistence",
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestImpersonate_Denied(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respRegister, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		token  string
+		userID string
+		reason string
+		code   codes.Code
+	}{
+		{
+			name:   "admin without support role",
+			token:  adminToken(t, st, uuid.NewString()),
+			userID: respRegister.GetUserId(),
+			reason: "ticket 42",
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "unknown user",
+			token:  roleToken(t, st, uuid.NewString(), "support"),
+			userID: uuid.NewString(),
+			reason: "ticket 42",
+			code:   codes.NotFound,
+		},
+		{
+			name:   "no reason",
+			token:  roleToken(t, st, uuid.NewString(), "support"),
+			userID: respRegister.GetUserId(),
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+
+			_, err := st.AdminClient.Impersonate(callCtx, &authv1.ImpersonateRequest{
+				UserId: tt.userID,
+				Reason: tt.reason,
+			})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
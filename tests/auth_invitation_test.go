@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestInvitation_CreateListRevoke(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respRegister, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, respRegister.GetUserId()))
+
+	respCreate, err := st.AdminClient.CreateInvitation(adminCtx, &authv1.CreateInvitationRequest{
+		Email: gofakeit.Email(),
+		Role:  "support",
+	})
+	require.NoError(t, err)
+
+	invitationID := respCreate.GetInvitation().GetId()
+	assert.Equal(t, respRegister.GetUserId(), respCreate.GetInvitation().GetInvitedBy())
+
+	respList, err := st.AdminClient.ListInvitations(adminCtx, &authv1.ListInvitationsRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, invitationIDs(respList.GetInvitations()), invitationID)
+
+	_, err = st.AdminClient.RevokeInvitation(adminCtx, &authv1.RevokeInvitationRequest{Id: invitationID})
+	require.NoError(t, err)
+
+	respList, err = st.AdminClient.ListInvitations(adminCtx, &authv1.ListInvitationsRequest{})
+	require.NoError(t, err)
+	assert.NotContains(t, invitationIDs(respList.GetInvitations()), invitationID)
+
+	_, err = st.AdminClient.RevokeInvitation(adminCtx, &authv1.RevokeInvitationRequest{Id: invitationID})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCreateInvitation_UnknownRole(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, uuid.NewString()))
+
+	for _, role := range []string{"", "developer", "Admin"} {
+		_, err := st.AdminClient.CreateInvitation(adminCtx, &authv1.CreateInvitationRequest{
+			Email: gofakeit.Email(),
+			Role:  role,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), role)
+	}
+}
+
+func TestAcceptInvitation_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		wantCode codes.Code
+	}{
+		{
+			name:     "forged token",
+			token:    "not-a-token",
+			password: randomFakePassword(),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "empty token",
+			token:    "",
+			password: randomFakePassword(),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "empty password",
+			token:    "not-a-token",
+			password: "",
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.AcceptInvitation(ctx, &authv1.AcceptInvitationRequest{
+				Token:    tt.token,
+				Password: tt.password,
+			})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func invitationIDs(invitations []*authv1.Invitation) []string {
+	ids := make([]string, 0, len(invitations))
+	for _, inv := range invitations {
+		ids = append(ids, inv.GetId())
+	}
+
+	return ids
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestListLoginHistory_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	var token string
+	for range 2 {
+		respLogin, err := st.AuthClient.Login(ctx, &authv1.LoginRequest{
+			Email:    email,
+			Password: password,
+		})
+		require.NoError(t, err)
+
+		token = respLogin.GetToken()
+	}
+
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	respHistory, err := st.AuthClient.ListLoginHistory(userCtx, &authv1.ListLoginHistoryRequest{})
+	require.NoError(t, err)
+	require.Len(t, respHistory.GetLogins(), 2)
+
+	for _, login := range respHistory.GetLogins() {
+		assert.NotEmpty(t, login.GetIp())
+		assert.Contains(t, login.GetUserAgent(), "grpc-go")
+		assert.NotEmpty(t, login.GetDeviceFingerprint())
+	}
+
+	respHistory, err = st.AuthClient.ListLoginHistory(userCtx, &authv1.ListLoginHistoryRequest{
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	assert.Len(t, respHistory.GetLogins(), 1)
+}
+
+func TestListLoginHistory_Unauthenticated(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.ListLoginHistory(ctx, &authv1.ListLoginHistoryRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifyOTP_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+		Email:    email,
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		email    string
+		code     string
+		wantCode codes.Code
+	}{
+		{
+			name:     "code was not requested",
+			email:    email,
+			code:     "123456",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "unknown user",
+			email:    gofakeit.Email(),
+			code:     "123456",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "malformed code",
+			email:    email,
+			code:     "12ab",
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.VerifyOTP(ctx, &authv1.VerifyOTPRequest{
+				Email: tt.email,
+				Code:  tt.code,
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestSendOTP_UnknownChannel(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.SendOTP(ctx, &authv1.SendOTPRequest{
+		Email:   gofakeit.Email(),
+		Channel: "pigeon",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// smsCode waits for the last code the SMS file provider of the server delivered to the phone.
+// The file path is relative to the working directory of the server, the repository root.
+func smsCode(t *testing.T, st *suite.Suite, phone string) string {
+	t.Helper()
+
+	re := regexp.MustCompile(`code is (\d+)`)
+
+	var code string
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(filepath.Join("..", st.Cfg.OTP.SMSFile))
+		if err != nil {
+			return false
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) == 3 && fields[1] == phone {
+				if m := re.FindStringSubmatch(fields[2]); m != nil {
+					code = m[1]
+				}
+			}
+		}
+
+		return code != ""
+	}, 5*time.Second, 100*time.Millisecond)
+
+	return code
+}
+
+func randomPhone() string {
+	return fmt.Sprintf("+1555%07d", gofakeit.Number(0, 9999999))
+}
+
+// registerWithPhone registers the user and sets the phone receiving SMS codes.
+func registerWithPhone(t *testing.T, ctx context.Context, st *suite.Suite) (email, phone string) {
+	t.Helper()
+
+	email, phone = gofakeit.Email(), randomPhone()
+	password := randomFakePassword()
+
+	userCtx := registerAndLogin(t, ctx, st, email, password)
+
+	_, err := st.AuthClient.ChangePhone(userCtx, &authv1.ChangePhoneRequest{
+		Password: password,
+		Phone:    phone,
+	})
+	require.NoError(t, err)
+
+	return email, phone
+}
+
+func TestOTP_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email, phone := registerWithPhone(t, ctx, st)
+
+	_, err := st.AuthClient.SendOTP(ctx, &authv1.SendOTPRequest{
+		Email:   email,
+		Channel: "sms",
+	})
+	require.NoError(t, err)
+
+	respVerify, err := st.AuthClient.VerifyOTP(ctx, &authv1.VerifyOTPRequest{
+		Email: email,
+		Code:  smsCode(t, st, phone),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respVerify.GetToken())
+}
+
+func TestOTP_TooManyAttempts(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email, phone := registerWithPhone(t, ctx, st)
+
+	_, err := st.AuthClient.SendOTP(ctx, &authv1.SendOTPRequest{
+		Email:   email,
+		Channel: "sms",
+	})
+	require.NoError(t, err)
+	code := smsCode(t, st, phone)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for range st.Cfg.OTP.MaxAttempts {
+		_, err := st.AuthClient.VerifyOTP(ctx, &authv1.VerifyOTPRequest{Email: email, Code: wrong})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, err = st.AuthClient.VerifyOTP(ctx, &authv1.VerifyOTPRequest{Email: email, Code: code})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "valid code is rejected once attempts are exhausted")
+
+	_, err = st.AuthClient.SendOTP(ctx, &authv1.SendOTPRequest{
+		Email:   email,
+		Channel: "sms",
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.VerifyOTP(ctx, &authv1.VerifyOTPRequest{Email: email, Code: smsCode(t, st, phone)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "new code doesn't lift the lockout")
+}
+
+func TestSendOTP_Uniform(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	registerAndLogin(t, ctx, st, email, randomFakePassword())
+
+	// a user without phone gets the same response as an unknown one
+	for _, email := range []string{email, gofakeit.Email()} {
+		_, err := st.AuthClient.SendOTP(ctx, &authv1.SendOTPRequest{
+			Email:   email,
+			Channel: "sms",
+		})
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServiceAccount_Secret(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, uuid.NewString()))
+
+	respCreate, err := st.AdminClient.CreateServiceAccount(adminCtx, &authv1.CreateServiceAccountRequest{
+		Name:   "billing",
+		Scopes: []string{"read", "write"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respCreate.GetClientSecret())
+	assert.True(t, respCreate.GetServiceAccount().GetHasSecret())
+
+	clientID := respCreate.GetServiceAccount().GetId()
+
+	respToken, err := st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: respCreate.GetClientSecret(),
+		Scopes:       []string{"read"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(st.Cfg.ServiceAccount.TokenTTL.Seconds()), respToken.GetExpiresIn())
+
+	respValidate, err := st.AuthClient.ValidateToken(ctx, &authv1.ValidateTokenRequest{
+		Token: respToken.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, respValidate.GetServiceAccount())
+	assert.Equal(t, clientID, respValidate.GetUserId())
+	assert.Equal(t, []string{"read"}, respValidate.GetScopes())
+
+	_, err = st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: respCreate.GetClientSecret(),
+		Scopes:       []string{"admin"},
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respRotate, err := st.AdminClient.RotateServiceAccountSecret(adminCtx, &authv1.RotateServiceAccountSecretRequest{
+		Id: clientID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: respCreate.GetClientSecret(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: respRotate.GetClientSecret(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.DeleteServiceAccount(adminCtx, &authv1.DeleteServiceAccountRequest{
+		Id: clientID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: respRotate.GetClientSecret(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServiceAccount_Assertion(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, uuid.NewString()))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	respCreate, err := st.AdminClient.CreateServiceAccount(adminCtx, &authv1.CreateServiceAccountRequest{
+		Name:      "reports",
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respCreate.GetClientSecret())
+
+	clientID := respCreate.GetServiceAccount().GetId()
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": clientID,
+		"sub": clientID,
+		"aud": st.Cfg.OIDC.Issuer,
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"jti": uuid.NewString(),
+	}).SignedString(key)
+	require.NoError(t, err)
+
+	respToken, err := st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:  clientID,
+		Assertion: assertion,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respToken.GetToken())
+
+	_, err = st.AuthClient.ServiceAccountToken(ctx, &authv1.ServiceAccountTokenRequest{
+		ClientId:  clientID,
+		Assertion: assertion,
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "assertion can't be replayed")
+
+	// service accounts are not users
+	tokenCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respToken.GetToken())
+	_, err = st.AuthClient.ListAPIKeys(tokenCtx, &authv1.ListAPIKeysRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respList, err := st.AdminClient.ListServiceAccounts(adminCtx, &authv1.ListServiceAccountsRequest{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respList.GetServiceAccounts())
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenant_SameEmailInDifferentTenants(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, gofakeit.UUID()))
+
+	respTenant, err := st.AdminClient.CreateTenant(adminCtx, &authv1.CreateTenantRequest{
+		Slug: strings.ToLower(gofakeit.LetterN(12)),
+		Name: gofakeit.Company(),
+	})
+	require.NoError(t, err)
+
+	tenantCtx := metadata.AppendToOutgoingContext(ctx, tenancy.MetadataKey, respTenant.GetTenant().GetSlug())
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	for _, ctx := range []context.Context{ctx, tenantCtx} {
+		_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{
+			Email:    email,
+			Password: password,
+		})
+		require.NoError(t, err)
+	}
+
+	respLogin, err := st.AuthClient.Login(tenantCtx, &authv1.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	tokenParsed, err := jwt.Parse(respLogin.GetToken(), func(token *jwt.Token) (any, error) {
+		return []byte(st.Cfg.Secret), nil
+	})
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, respTenant.GetTenant().GetId(), claims["tenant_id"])
+}
+
+func TestTenant_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		_, err := st.AuthClient.Login(metadata.AppendToOutgoingContext(ctx, tenancy.MetadataKey, "unknown-tenant"), &authv1.LoginRequest{
+			Email:    gofakeit.Email(),
+			Password: randomFakePassword(),
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("admin rpc without token", func(t *testing.T) {
+		_, err := st.AdminClient.ListTenants(ctx, &authv1.ListTenantsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+// adminToken mints access token of an admin of the default tenant.
+func adminToken(t *testing.T, st *suite.Suite, userID string) string {
+	t.Helper()
+
+	return roleToken(t, st, userID, "admin")
+}
+
+// roleToken returns access token of the user of the default tenant with the role.
+func roleToken(t *testing.T, st *suite.Suite, userID, role string) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":       userID,
+		"tenant_id": tenancy.Default.String(),
+		"roles":     []string{role},
+		"exp":       time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(st.Cfg.Secret))
+	require.NoError(t, err)
+
+	return token
+}
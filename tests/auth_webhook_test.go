@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/webhook"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestWebhook_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	serviceCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, gofakeit.UUID()))
+
+	respTenant, err := st.AdminClient.CreateTenant(serviceCtx, &authv1.CreateTenantRequest{
+		Slug: strings.ToLower(gofakeit.LetterN(12)),
+		Name: gofakeit.Company(),
+	})
+	require.NoError(t, err)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tenantAdminToken(t, st, respTenant.GetTenant().GetId()))
+
+	type request struct {
+		header  http.Header
+		payload []byte
+	}
+	requests := make(chan request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header, payload: payload}
+	}))
+	defer receiver.Close()
+
+	respWebhook, err := st.AdminClient.CreateWebhook(adminCtx, &authv1.CreateWebhookRequest{
+		Url:        receiver.URL,
+		EventTypes: []string{"user.registered"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respWebhook.GetSecret())
+
+	email := gofakeit.Email()
+	respRegister, err := st.AuthClient.Register(
+		metadata.AppendToOutgoingContext(ctx, tenancy.MetadataKey, respTenant.GetTenant().GetSlug()),
+		&authv1.RegisterRequest{
+			Email:    email,
+			Password: randomFakePassword(),
+		},
+	)
+	require.NoError(t, err)
+
+	var req request
+	select {
+	case req = <-requests:
+	case <-time.After(15 * time.Second):
+		t.Fatal("webhook is not delivered")
+	}
+
+	err = webhook.Verify(
+		respWebhook.GetSecret(),
+		req.header.Get(webhook.HeaderTimestamp),
+		req.header.Get(webhook.HeaderSignature),
+		req.payload,
+		time.Minute,
+		time.Now(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "user.registered", req.header.Get(webhook.HeaderEvent))
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(req.payload, &payload))
+	assert.Equal(t, respRegister.GetUserId(), payload["user_id"])
+	assert.Equal(t, email, payload["email"])
+
+	require.Eventually(t, func() bool {
+		resp, err := st.AdminClient.ListWebhookDeliveries(adminCtx, &authv1.ListWebhookDeliveriesRequest{
+			WebhookId: respWebhook.GetWebhook().GetId(),
+		})
+		if err != nil || len(resp.GetDeliveries()) != 1 {
+			return false
+		}
+
+		return resp.GetDeliveries()[0].GetStatus() == "delivered"
+	}, 5*time.Second, 100*time.Millisecond)
+
+	respDeliveries, err := st.AdminClient.ListWebhookDeliveries(adminCtx, &authv1.ListWebhookDeliveriesRequest{
+		WebhookId: respWebhook.GetWebhook().GetId(),
+	})
+	require.NoError(t, err)
+	delivery := respDeliveries.GetDeliveries()[0]
+	assert.Equal(t, req.header.Get(webhook.HeaderID), delivery.GetId())
+
+	_, err = st.AdminClient.ReplayWebhookDelivery(adminCtx, &authv1.ReplayWebhookDeliveryRequest{
+		Id: delivery.GetId(),
+	})
+	require.NoError(t, err)
+
+	select {
+	case replayed := <-requests:
+		assert.Equal(t, delivery.GetId(), replayed.header.Get(webhook.HeaderID))
+		assert.JSONEq(t, string(req.payload), string(replayed.payload))
+	case <-time.After(15 * time.Second):
+		t.Fatal("replayed webhook is not delivered")
+	}
+
+	_, err = st.AdminClient.DeleteWebhook(adminCtx, &authv1.DeleteWebhookRequest{
+		Id: respWebhook.GetWebhook().GetId(),
+	})
+	require.NoError(t, err)
+
+	respWebhooks, err := st.AdminClient.ListWebhooks(adminCtx, &authv1.ListWebhooksRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, respWebhooks.GetWebhooks())
+}
+
+func TestWebhook_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, gofakeit.UUID()))
+
+	tests := []struct {
+		name       string
+		url        string
+		eventTypes []string
+	}{
+		{"invalid url", "not a url", []string{"user.registered"}},
+		{"unsupported scheme", "ftp://example.com/hooks", []string{"user.registered"}},
+		{"no event types", "https://example.com/hooks", nil},
+		{"unknown event type", "https://example.com/hooks", []string{"user.logged_in"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AdminClient.CreateWebhook(adminCtx, &authv1.CreateWebhookRequest{
+				Url:        tt.url,
+				EventTypes: tt.eventTypes,
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+
+	t.Run("replay unknown delivery", func(t *testing.T) {
+		_, err := st.AdminClient.ReplayWebhookDelivery(adminCtx, &authv1.ReplayWebhookDeliveryRequest{
+			Id: gofakeit.UUID(),
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+roleToken(t, st, gofakeit.UUID(), "user"))
+
+		_, err := st.AdminClient.ListWebhooks(userCtx, &authv1.ListWebhooksRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+// tenantAdminToken mints access token of an admin of the tenant.
+func tenantAdminToken(t *testing.T, st *suite.Suite, tenantID string) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":       gofakeit.UUID(),
+		"tenant_id": tenantID,
+		"roles":     []string{"admin"},
+		"exp":       time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(st.Cfg.Secret))
+	require.NoError(t, err)
+
+	return token
+}
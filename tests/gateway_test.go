@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/kurochkinivan/auth/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postJSON(t *testing.T, st *suite.Suite, path string, body any) (*http.Response, map[string]any) {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	url := "http://" + net.JoinHostPort(st.Cfg.HTTP.Host, st.Cfg.HTTP.Port) + path
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	return resp, result
+}
+
+func TestGateway_RegisterLogin(t *testing.T) {
+	_, st := suite.New(t)
+	if !st.Cfg.Gateway.Enabled {
+		t.Skip("gateway is disabled")
+	}
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	resp, body := postJSON(t, st, "/v1/register", map[string]string{"email": email, "password": password})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.NotEmpty(t, resp.Header.Get("X-Request-Id"))
+
+	resp, body = postJSON(t, st, "/v1/login", map[string]string{"email": email, "password": password})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.NotEmpty(t, body["token"])
+
+	resp, body = postJSON(t, st, "/v1/login", map[string]string{"email": email, "password": password + "x"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "InvalidArgument", body["code"])
+	assert.Equal(t, "invalid credentials", body["message"])
+	assert.Equal(t, resp.Header.Get("X-Request-Id"), body["request_id"])
+}
@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/kurochkinivan/auth/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealth_Serving(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	for _, service := range []string{"", "auth.Auth", "auth.Admin"} {
+		resp, err := st.Health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err, service)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus(), service)
+	}
+}
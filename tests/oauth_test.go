@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/kurochkinivan/auth/internal/entity"
+	"github.com/kurochkinivan/auth/internal/lib/passhash"
+	"github.com/kurochkinivan/auth/internal/lib/tenancy"
+	"github.com/kurochkinivan/auth/internal/usecase/oauth"
+	"github.com/kurochkinivan/auth/internal/usecase/repository/pg"
+	"github.com/kurochkinivan/auth/tests/suite"
+	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
+	pgclient "github.com/kurochkinivan/pgClient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const oauthRedirectURI = "http://localhost:3000/callback"
+
+// repository connects to the database of the server, for fixtures the API can't create,
+// e.g. OAuth clients registered by the operator with cmd/client.
+func repository(t *testing.T, ctx context.Context, st *suite.Suite) *pg.Repository {
+	t.Helper()
+
+	cfg := st.Cfg.PostgreSQL
+	pool, err := pgclient.NewClient(ctx, cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DB)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pg.New(pool)
+}
+
+// registerOAuthClient registers confidential client of the authorization code grant.
+func registerOAuthClient(t *testing.T, ctx context.Context, st *suite.Suite, repo *pg.Repository) (clientID, secret string) {
+	t.Helper()
+
+	service := oauth.New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, repo, repo, repo,
+		st.Cfg.Secret, st.Cfg.OIDC.Issuer, nil, st.Cfg.TokenTTL, st.Cfg.OAuth.CodeTTL, st.Cfg.OAuth.RefreshTokenTTL)
+
+	id, secret, err := service.RegisterClient(ctx, gofakeit.AppName(), []string{oauthRedirectURI},
+		[]string{"openid", "profile"}, []string{entity.GrantTypeAuthorizationCode}, false)
+	require.NoError(t, err)
+
+	return id.String(), secret
+}
+
+// authorizationCodeToken runs the authorization code flow with PKCE
+// for the user consenting with the password and returns the token response.
+func authorizationCodeToken(t *testing.T, st *suite.Suite, clientID, secret, scope, email, password string) map[string]any {
+	t.Helper()
+
+	return redirectedAuthorizationCodeToken(t, st, clientID, secret, scope, oauthRedirectURI, email, password)
+}
+
+// redirectedAuthorizationCodeToken is authorizationCodeToken with given redirect URI,
+// empty one is omitted from both requests.
+func redirectedAuthorizationCodeToken(t *testing.T, st *suite.Suite, clientID, secret, scope, redirectURI, email, password string) map[string]any {
+	t.Helper()
+
+	base := "http://" + net.JoinHostPort(st.Cfg.HTTP.Host, st.Cfg.HTTP.Port)
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	verifier := gofakeit.LetterN(64)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"scope":                 {scope},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"action":                {"approve"},
+		"email":                 {email},
+		"password":              {password},
+	}
+	if redirectURI != "" {
+		params.Set("redirect_uri", redirectURI)
+	}
+
+	resp, err := client.PostForm(base+"/oauth/authorize", params)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code, location.String())
+
+	params = url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"code_verifier": {verifier},
+	}
+	if redirectURI != "" {
+		params.Set("redirect_uri", redirectURI)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, base+"/oauth/token", strings.NewReader(params.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var token map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	require.Equal(t, http.StatusOK, resp.StatusCode, token)
+
+	return token
+}
+
+// TestOAuth_RedirectURIOmitted checks that the token request needn't name
+// the redirect URI the authorization request omitted.
+func TestOAuth_RedirectURIOmitted(t *testing.T) {
+	ctx, st := suite.New(t)
+	repo := repository(t, ctx, st)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	clientID, secret := registerOAuthClient(t, ctx, st, repo)
+	token := redirectedAuthorizationCodeToken(t, st, clientID, secret, "profile", "", email, password)
+
+	accessToken, _ := token["access_token"].(string)
+	assert.NotEmpty(t, accessToken)
+}
+
+// TestOAuth_ClientSignsInUsersOfItsTenant checks that a client of the default tenant
+// can't get tokens of users of other tenants, even if the request names their tenant.
+func TestOAuth_ClientSignsInUsersOfItsTenant(t *testing.T) {
+	ctx, st := suite.New(t)
+	repo := repository(t, ctx, st)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken(t, st, gofakeit.UUID()))
+
+	respTenant, err := st.AdminClient.CreateTenant(adminCtx, &authv1.CreateTenantRequest{
+		Slug: strings.ToLower(gofakeit.LetterN(12)),
+		Name: gofakeit.Company(),
+	})
+	require.NoError(t, err)
+	slug := respTenant.GetTenant().GetSlug()
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err = st.AuthClient.Register(metadata.AppendToOutgoingContext(ctx, tenancy.MetadataKey, slug), &authv1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	clientID, _ := registerOAuthClient(t, ctx, st, repo)
+
+	challenge := sha256.Sum256([]byte(gofakeit.LetterN(64)))
+	resp, err := http.PostForm("http://"+net.JoinHostPort(st.Cfg.HTTP.Host, st.Cfg.HTTP.Port)+"/oauth/authorize?tenant="+slug, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {oauthRedirectURI},
+		"scope":                 {"profile"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"action":                {"approve"},
+		"email":                 {email},
+		"password":              {password},
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestOAuth_TokenOfAdminIsNotAdminToken(t *testing.T) {
+	ctx, st := suite.New(t)
+	repo := repository(t, ctx, st)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	passHash, err := passhash.Hash([]byte(password))
+	require.NoError(t, err)
+	_, err = repo.SaveDirectoryUser(ctx, email, passHash, []string{entity.RoleAdmin})
+	require.NoError(t, err)
+
+	clientID, secret := registerOAuthClient(t, ctx, st, repo)
+	token := authorizationCodeToken(t, st, clientID, secret, "profile", email, password)
+
+	accessToken, _ := token["access_token"].(string)
+	require.NotEmpty(t, accessToken)
+
+	respValidate, err := st.AuthClient.ValidateToken(ctx, &authv1.ValidateTokenRequest{Token: accessToken})
+	require.NoError(t, err)
+	assert.Empty(t, respValidate.GetRoles(), "delegated token doesn't carry roles of the user")
+
+	clientCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+accessToken)
+	_, err = st.AdminClient.ListInvitations(clientCtx, &authv1.ListInvitationsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respLogin, err := st.AuthClient.Login(ctx, &authv1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respLogin.GetToken())
+	_, err = st.AdminClient.ListInvitations(adminCtx, &authv1.ListInvitationsRequest{})
+	assert.NoError(t, err, "the user is still an admin with own token")
+}
+
+// TestOIDC_Interop verifies ID tokens as relying parties do: with an off-the-shelf
+// library discovering the provider by its issuer and fetching the published keys.
+func TestOIDC_Interop(t *testing.T) {
+	ctx, st := suite.New(t)
+	repo := repository(t, ctx, st)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	clientID, secret := registerOAuthClient(t, ctx, st, repo)
+	token := authorizationCodeToken(t, st, clientID, secret, "openid profile", email, password)
+
+	rawIDToken, _ := token["id_token"].(string)
+	require.NotEmpty(t, rawIDToken)
+
+	provider, err := oidc.NewProvider(ctx, st.Cfg.OIDC.Issuer)
+	require.NoError(t, err, "discovery document matches the issuer")
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: clientID}).Verify(ctx, rawIDToken)
+	require.NoError(t, err)
+	assert.Equal(t, st.Cfg.OIDC.Issuer, idToken.Issuer)
+
+	accessToken, _ := token["access_token"].(string)
+	assert.NoError(t, idToken.VerifyAccessToken(accessToken))
+}
+
+func TestOAuth_TokenCantManageAPIKeys(t *testing.T) {
+	ctx, st := suite.New(t)
+	repo := repository(t, ctx, st)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &authv1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	clientID, secret := registerOAuthClient(t, ctx, st, repo)
+	token := authorizationCodeToken(t, st, clientID, secret, "profile", email, password)
+
+	accessToken, _ := token["access_token"].(string)
+	clientCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+accessToken)
+
+	_, err = st.AuthClient.CreateAPIKey(clientCtx, &authv1.CreateAPIKeyRequest{Name: "ci"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.ListLoginHistory(clientCtx, &authv1.ListLoginHistoryRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -6,15 +6,20 @@ import (
 	"testing"
 
 	"github.com/kurochkinivan/auth/internal/config"
+	"github.com/kurochkinivan/auth/internal/lib/tlsutil"
 	authv1 "github.com/kurochkinivan/auth_proto/gen/go/auth"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Suite struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient authv1.AuthClient
+	Cfg         *config.Config
+	AuthClient  authv1.AuthClient
+	AdminClient authv1.AdminClient
+	Health      healthpb.HealthClient
 }
 
 func New(t *testing.T) (context.Context, *Suite) {
@@ -30,9 +35,19 @@ func New(t *testing.T) (context.Context, *Suite) {
 		cancelCtx()
 	})
 
+	creds := insecure.NewCredentials()
+	if cfg.GRPC.TLS.Enabled {
+		// trusts the server as the gateway does
+		tlsConfig, err := tlsutil.ClientConfig(cfg.Gateway.TLS.CAPath, cfg.Gateway.TLS.CertPath, cfg.Gateway.TLS.KeyPath, cfg.Gateway.TLS.ServerName)
+		if err != nil {
+			t.Fatalf("failed to load tls config: %v", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
 	cc, err := grpc.NewClient(
 		net.JoinHostPort(cfg.GRPC.Host, cfg.GRPC.Port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithContextDialer(
 			func(context.Context, string) (net.Conn, error) {
 				return (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(cfg.GRPC.Host, cfg.GRPC.Port))
@@ -44,8 +59,10 @@ func New(t *testing.T) (context.Context, *Suite) {
 	}
 
 	return ctx, &Suite{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: authv1.NewAuthClient(cc),
+		T:           t,
+		Cfg:         cfg,
+		AuthClient:  authv1.NewAuthClient(cc),
+		AdminClient: authv1.NewAdminClient(cc),
+		Health:      healthpb.NewHealthClient(cc),
 	}
 }